@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 
 	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
@@ -24,6 +23,8 @@ A Belvedere application is anything in a Docker container which accepts HTTP2 co
 			newAppsListCmd(),
 			newAppsCreateCmd(),
 			newAppsUpdateCmd(),
+			newAppsDiffCmd(),
+			newAppsConfigCmd(),
 			newAppsDeleteCmd(),
 		},
 	}
@@ -54,6 +55,8 @@ Prints a table of provisioned applications in the current project.`,
 func newAppsCreateCmd() *cli.Command {
 	var (
 		mf  cli.ModifyFlags
+		cf  cli.ConfigFlags
+		pf  cli.PreviewFlags
 		lrf cli.LongRunningFlags
 	)
 
@@ -68,27 +71,33 @@ The resources which run an application are provisioned inside a GCP region (e.g.
 property cannot be changed once the application is created.
 
 If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
-instead.`,
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render').
+
+With --preview, Deployment Manager validates the changes and plans them without making them. The
+planned intent for each resource (e.g. CREATE_OR_ACQUIRE, UPDATE, DELETE) is printed along with any
+validation errors, and then the preview is cancelled.`,
 			Args: cobra.RangeArgs(2, 3),
 		},
 		Flags: func(fs *pflag.FlagSet) {
 			mf.Register(fs)
+			pf.Register(fs)
 			lrf.Register(fs)
+			cf.Register(fs)
 		},
 		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
 			region := args.String(0)
 			name := args.String(1)
-			b, err := args.File(2)
+			config, resolved, err := cf.Parse(ctx, args, 2, variables(project.Name(), name, "", region))
 			if err != nil {
 				return err
 			}
 
-			config, err := cfg.Parse(bytes.NewReader(b))
-			if err != nil {
+			if err := printResolved(out, resolved, mf.DryRun); err != nil {
 				return err
 			}
 
-			return project.Apps().Create(ctx, region, name, config, mf.DryRun, lrf.Interval)
+			return project.Apps().Create(ctx, region, name, config, mf.DryRun, pf.Preview, lrf.Interval)
 		},
 	}
 }
@@ -96,6 +105,8 @@ instead.`,
 func newAppsUpdateCmd() *cli.Command {
 	var (
 		mf  cli.ModifyFlags
+		cf  cli.ConfigFlags
+		pf  cli.PreviewFlags
 		lrf cli.LongRunningFlags
 	)
 
@@ -106,27 +117,118 @@ func newAppsUpdateCmd() *cli.Command {
 			Short:   `Update an application`,
 			Long: `Update an application.
 
+Only the app-level settings of the configuration (identityAwareProxy, cdnPolicy, wafRules,
+rateLimits, allowIPs, denyIPs, sessionAffinity, drainingTimeout, and iamRoles) are applied. The release-level settings (e.g.
+machineType, numReplicas, and container) only take effect when a release is created; if they differ
+from those of any of the application's releases, a warning is logged.
+
 If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
-instead.`,
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render').
+
+With --preview, Deployment Manager validates the changes and plans them without making them. The
+planned intent for each resource (e.g. CREATE_OR_ACQUIRE, UPDATE, DELETE) is printed along with any
+validation errors, and then the preview is cancelled.`,
 			Args: cobra.RangeArgs(1, 2),
 		},
 		Flags: func(fs *pflag.FlagSet) {
 			mf.Register(fs)
+			pf.Register(fs)
 			lrf.Register(fs)
+			cf.Register(fs)
 		},
 		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
 			name := args.String(0)
-			b, err := args.File(1)
+
+			region, err := appRegion(ctx, project, name)
+			if err != nil {
+				return err
+			}
+
+			config, resolved, err := cf.Parse(ctx, args, 1, variables(project.Name(), name, "", region))
+			if err != nil {
+				return err
+			}
+
+			if err := printResolved(out, resolved, mf.DryRun); err != nil {
+				return err
+			}
+
+			return project.Apps().Update(ctx, name, config, mf.DryRun, pf.Preview, lrf.Interval)
+		},
+	}
+}
+
+func newAppsDiffCmd() *cli.Command {
+	var cf cli.ConfigFlags
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `diff <name> [<config-file>]`,
+			Example: `belvedere apps diff my-app my-app.yaml`,
+			Short:   `Show the changes an update would make to an application`,
+			Long: `Show the changes an update would make to an application.
+
+This compares the application's current resources with those the given configuration would produce,
+field by field. In a terminal, the changes are shown in color; otherwise, they're printed as JSON.
+
+If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render').`,
+			Args: cobra.RangeArgs(1, 2),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			cf.Register(fs)
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			name := args.String(0)
+
+			region, err := appRegion(ctx, project, name)
+			if err != nil {
+				return err
+			}
+
+			config, _, err := cf.Parse(ctx, args, 1, variables(project.Name(), name, "", region))
+			if err != nil {
+				return err
+			}
+
+			diffs, err := project.Apps().Diff(ctx, name, config)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintDiff(diffs)
+		},
+	}
+}
+
+func newAppsConfigCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `config <name>`,
+			Example: `belvedere apps config my-app > my-app.yaml`,
+			Short:   `Print the configuration an application was deployed with`,
+			Long: `Print the configuration an application was deployed with.
+
+This prints the configuration, with its overlays and variables resolved, which the application was
+last created or updated with. It can be passed back to 'belvedere apps update' to recreate the
+application as it was. Configurations are kept in the project's <project>-belvedere-configs
+bucket, which also keeps previous versions of them.`,
+			Args: cobra.ExactArgs(1),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			config, err := project.Apps().Config(ctx, args.String(0))
 			if err != nil {
 				return err
 			}
 
-			config, err := cfg.Parse(bytes.NewReader(b))
+			b, err := cfg.Marshal(config)
 			if err != nil {
 				return err
 			}
 
-			return project.Apps().Update(ctx, name, config, mf.DryRun, lrf.Interval)
+			return out.PrintText(string(b))
 		},
 	}
 }
@@ -56,18 +56,29 @@ func TestAppsCreate(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
 	apps := NewMockAppService(ctrl)
 	apps.EXPECT().
-		Create(gomock.Any(), "us-west1", "my-app", &config, true, 10*time.Minute).
+		Create(gomock.Any(), "us-west1", "my-app", &config, true, false, 10*time.Minute).
 		Return(nil)
 
 	project.EXPECT().Apps().Return(apps)
 
 	cmd := newRootCmd("test").ToCobra(pf, of)
-	cmd.SetIn(bytes.NewBufferString(`numReplicas: 10`))
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
 	cmd.SetOut(bytes.NewBuffer(nil))
 	cmd.SetErr(bytes.NewBuffer(nil))
 	cmd.SetArgs([]string{
@@ -93,12 +104,19 @@ func TestAppsCreate_WithFilename(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
 	apps := NewMockAppService(ctrl)
 	apps.EXPECT().
-		Create(gomock.Any(), "us-west1", "my-app", &config, true, 10*time.Minute).
+		Create(gomock.Any(), "us-west1", "my-app", &config, true, false, 10*time.Minute).
 		Return(nil)
 
 	project.EXPECT().Apps().Return(apps)
@@ -121,6 +139,67 @@ func TestAppsCreate_WithFilename(t *testing.T) {
 	}
 }
 
+func TestAppsCreate_WithVariables(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+				Env:   map[string]string{"BUILD": "1234", "REGION": "us-west1"},
+			},
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Create(gomock.Any(), "us-west1", "my-app", &config, true, false, 10*time.Minute).
+		Return(nil)
+
+	project.EXPECT().Apps().Return(apps)
+
+	output.EXPECT().
+		PrintText(`numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+  env:
+    BUILD: "1234"
+    REGION: us-west1
+`)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString(`numReplicas: 10
+container:
+  image: gcr.io/${project}/${app}
+  env:
+    BUILD: "${BUILD}"
+    REGION: ${region}
+`))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"apps",
+		"create",
+		"us-west1",
+		"my-app",
+		"--var=BUILD=1234",
+		"--dry-run",
+		"--interval=10m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAppsUpdate(t *testing.T) {
 	t.Parallel()
 
@@ -130,18 +209,33 @@ func TestAppsUpdate(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
 	apps := NewMockAppService(ctrl)
 	apps.EXPECT().
-		Update(gomock.Any(), "my-app", &config, true, 10*time.Minute).
+		Update(gomock.Any(), "my-app", &config, true, false, 10*time.Minute).
 		Return(nil)
 
-	project.EXPECT().Apps().Return(apps)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps).Times(2)
 
 	cmd := newRootCmd("test").ToCobra(pf, of)
-	cmd.SetIn(bytes.NewBufferString(`numReplicas: 10`))
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
 	cmd.SetOut(bytes.NewBuffer(nil))
 	cmd.SetErr(bytes.NewBuffer(nil))
 	cmd.SetArgs([]string{
@@ -166,15 +260,74 @@ func TestAppsUpdate_WithFilename(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
 	apps := NewMockAppService(ctrl)
 	apps.EXPECT().
-		Update(gomock.Any(), "my-app", &config, true, 10*time.Minute).
+		Update(gomock.Any(), "my-app", &config, true, false, 10*time.Minute).
 		Return(nil)
 
-	project.EXPECT().Apps().Return(apps)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps).Times(2)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"apps",
+		"update",
+		"my-app",
+		"example.yaml",
+		"--dry-run",
+		"--interval=10m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppsUpdate_WithOverlay(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 20,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+				Env:   map[string]string{"ENV": "prod"},
+			},
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Update(gomock.Any(), "my-app", &config, true, false, 10*time.Minute).
+		Return(nil)
+
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps).Times(2)
 
 	cmd := newRootCmd("test").ToCobra(pf, of)
 	cmd.SetOut(bytes.NewBuffer(nil))
@@ -184,6 +337,7 @@ func TestAppsUpdate_WithFilename(t *testing.T) {
 		"update",
 		"my-app",
 		"example.yaml",
+		"--overlay=example.overlay.yaml",
 		"--dry-run",
 		"--interval=10m",
 	})
@@ -193,6 +347,107 @@ func TestAppsUpdate_WithFilename(t *testing.T) {
 	}
 }
 
+func TestAppsDiff(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	diffs := []belvedere.ResourceDiff{
+		{
+			Name:   "my-app-bes",
+			Action: belvedere.DiffChange,
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Diff(gomock.Any(), "my-app", &config).
+		Return(diffs, nil)
+
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps).Times(2)
+
+	output.EXPECT().
+		PrintDiff(diffs)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"apps",
+		"diff",
+		"my-app",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppsConfig(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Config(gomock.Any(), "my-app").
+		Return(&cfg.Config{
+			ReleaseConfig: cfg.ReleaseConfig{
+				NumReplicas: 10,
+				Container: cfg.Container{
+					Image: "gcr.io/my-project/my-app",
+				},
+			},
+		}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
+	output.EXPECT().
+		PrintText(`container:
+  image: gcr.io/my-project/my-app
+numReplicas: 10
+`)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"apps",
+		"config",
+		"my-app",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAppsDelete(t *testing.T) {
 	t.Parallel()
 
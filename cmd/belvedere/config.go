@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newConfigCmd(version string) *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:   `config`,
+			Short: `Commands for working with application configuration`,
+			Long:  `Commands for working with application configuration.`,
+		},
+		Subcommands: []*cli.Command{
+			newConfigValidateCmd(),
+			newConfigRenderCmd(),
+			newConfigSchemaCmd(version),
+			newConfigMigrateCmd(),
+		},
+	}
+}
+
+func newConfigValidateCmd() *cli.Command {
+	var (
+		region, digest string
+		cf             cli.ConfigFlags
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use: `validate <app> [<config-file>]`,
+			Example: `belvedere config validate my-app my-app.yaml
+belvedere config validate my-app my-app.yaml --digest=` +
+				`5fb4ba1a651bae8057ec6b5cdafc93fa7e0b7d944d6f02a4b751de4e15464def
+belvedere config validate my-app my-app.yaml --region=us-west1`,
+			Short: `Validate an application configuration against the project`,
+			Long: `Validate an application configuration against the project.
+
+Checks that the configuration's machine type is offered in the application's region, that its
+network, subnetwork, and IAM roles exist, and that its sidecar images exist in their registries. With
+--digest, the application's image is also checked to exist with the given SHA-256 digest. All
+problems are reported at once.
+
+The same checks are made before an application is created or updated and before a release is
+created. To validate the configuration of an application which hasn't been created yet, pass its
+region with --region.
+
+If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render').`,
+			Args: cobra.RangeArgs(1, 2),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&region, "region", "", "the region of the app, if it hasn't been created yet")
+			fs.StringVar(&digest, "digest", "", "the SHA-256 digest of the app's image to check for")
+			cf.Register(fs)
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			if region == "" {
+				r, err := appRegion(ctx, project, args.String(0))
+				if err != nil {
+					return err
+				}
+
+				region = r
+			}
+
+			config, _, err := cf.Parse(ctx, args, 1, variables(project.Name(), args.String(0), "", region))
+			if err != nil {
+				return err
+			}
+
+			return project.ValidateConfig(ctx, region, config, digest)
+		},
+	}
+}
+
+func newConfigRenderCmd() *cli.Command {
+	var cf cli.ConfigFlags
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `render [<config-file>]`,
+			Example: `belvedere config render my-app.yaml --overlay=my-app.prod.yaml --var=BUILD=1234`,
+			Short:   `Print an application configuration with its overlays and variables resolved`,
+			Long: `Print an application configuration with its overlays and variables resolved.
+
+Each config file given with --overlay is merged over the configuration, in order. Mappings (e.g. env
+and sidecars) are merged key by key, lists and all other values are replaced, and keys set to null
+are removed. Problems with values set by an overlay are reported by other commands with the overlay's
+number, in the order given, and the line in that overlay.
+
+Variables are then interpolated into the configuration's values. ${NAME} is replaced with the value
+of NAME, which must be defined, and ${NAME:-default} is replaced with the value of NAME or, if it's
+undefined or empty, with default. To include a literal ${, write $${. Variables are given with --var
+(e.g. --var=BUILD=1234) or are taken from the environment. Commands which read configuration also
+define ${project}, ${app}, ${release}, and ${region} where they apply; here, they must be given with
+--var. With --dry-run, commands print the resolved configuration if it refers to any variables.
+
+If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
+instead.`,
+			Args: cobra.RangeArgs(0, 1),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			cf.Register(fs)
+		},
+		RunOffline: func(ctx context.Context, project string, args cli.Args, out cli.Output) error {
+			b, err := cf.Resolve(args, 0, nil)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(string(b))
+		},
+	}
+}
+
+func newConfigSchemaCmd(version string) *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `schema`,
+			Example: `belvedere config schema > belvedere.schema.json`,
+			Short:   `Print a JSON Schema for application configuration`,
+			Long: `Print a JSON Schema for application configuration.
+
+The schema describes the configuration format of this version of Belvedere, including the GCP API
+types which it embeds, and can be used by editors (e.g. via yaml-language-server) and CI to validate
+configuration files. It doesn't require any access to GCP.`,
+			Args: cobra.NoArgs,
+		},
+		RunOffline: func(ctx context.Context, project string, args cli.Args, out cli.Output) error {
+			b, err := cfg.Schema(version)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(string(b))
+		},
+	}
+}
+
+func newConfigMigrateCmd() *cli.Command {
+	var mf cli.ModifyFlags
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `migrate <config-file>`,
+			Example: `belvedere config migrate my-app.yaml`,
+			Short:   `Upgrade an application configuration to the current format`,
+			Long: `Upgrade an application configuration to the current format.
+
+Each configuration declares the version of the format it uses with its apiVersion field; those
+without one use the format from before it was versioned. This rewrites the given configuration file
+in place as the current version, keeping its comments. Variables aren't interpolated. With
+--dry-run, the upgraded configuration is printed instead.
+
+Other commands upgrade old configurations as they read them, with a warning, but don't change the
+configuration files.`,
+			Args: cobra.ExactArgs(1),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			mf.Register(fs)
+		},
+		RunOffline: func(ctx context.Context, project string, args cli.Args, out cli.Output) error {
+			path := args.String(0)
+
+			fi, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			b, err := args.File(0)
+			if err != nil {
+				return err
+			}
+
+			migrated, err := cfg.Migrate(b)
+			if err != nil {
+				return err
+			}
+
+			if mf.DryRun {
+				return out.PrintText(string(migrated))
+			}
+
+			return ioutil.WriteFile(path, migrated, fi.Mode())
+		},
+	}
+}
+
+// variables returns the built-in variables which are interpolated into an app's configuration,
+// omitting any which don't apply.
+func variables(project, app, release, region string) cfg.Variables {
+	vars := cfg.Variables{}
+
+	for name, value := range map[string]string{
+		"project": project,
+		"app":     app,
+		"release": release,
+		"region":  region,
+	} {
+		if value != "" {
+			vars[name] = value
+		}
+	}
+
+	return vars
+}
+
+// appRegion returns the region of the given app.
+func appRegion(ctx context.Context, project belvedere.Project, name string) (string, error) {
+	app, err := project.Apps().Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return app.Region, nil
+}
+
+// printResolved prints the resolved configuration, if it refers to any variables, during a dry run,
+// so that the values of the variables can be checked.
+func printResolved(out cli.Output, resolved []byte, dryRun bool) error {
+	if !dryRun || resolved == nil {
+		return nil
+	}
+
+	return out.PrintText(string(resolved))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+	project.EXPECT().
+		ValidateConfig(gomock.Any(), "us-west1", &config, "abcdef").
+		Return(nil)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"validate",
+		"my-app",
+		"example.yaml",
+		"--digest=abcdef",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigValidate_WithRegion(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	project.EXPECT().
+		ValidateConfig(gomock.Any(), "us-east1", &config, "").
+		Return(nil)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"validate",
+		"my-app",
+		"example.yaml",
+		"--region=us-east1",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	want, err := cfg.Schema("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output.EXPECT().
+		PrintText(string(want))
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"schema",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigRender(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	output.EXPECT().
+		PrintText(`numReplicas: 20
+container:
+  image: gcr.io/my-project/my-app
+  env:
+    ENV: prod
+`)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"render",
+		"example.yaml",
+		"--overlay=example.overlay.yaml",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, _, _, of := mockFactories(ctrl)
+
+	path := filepath.Join(t.TempDir(), "my-app.yaml")
+	if err := ioutil.WriteFile(path, []byte(`# My app.
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app # pinned later
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"migrate",
+		path,
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# My app.
+apiVersion: belvedere/v2
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app # pinned later
+`
+
+	assert.Equal(t, "migrated config", want, string(got))
+}
+
+func TestConfigMigrate_DryRun(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	output.EXPECT().
+		PrintText(`apiVersion: belvedere/v2
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"config",
+		"migrate",
+		"example.yaml",
+		"--dry-run",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/spf13/cobra"
+)
+
+func newDriftCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `drift [<app>]`,
+			Example: `belvedere drift my-app`,
+			Short:   `Detect changes made to application resources outside of Belvedere`,
+			Long: `Detect changes made to application resources outside of Belvedere.
+
+Compares each resource in the Deployment Manager deployments of an application and its releases (or
+of all applications, if none is given) to the live GCE, DNS, and IAM resources. Prints the fields
+which differ, the resources which are missing, and any backends attached to an application's backend
+service outside of Belvedere.
+
+Only fields which Belvedere manages are compared. Changed fields will be reverted by the next
+update of the application or release.`,
+			Args: cobra.RangeArgs(0, 1),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			drifts, err := project.Drift(ctx, args.String(0))
+			if err != nil {
+				return err
+			}
+
+			return out.Print(drifts)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDrift(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	drifts := []belvedere.Drift{
+		{
+			App:      "my-app",
+			Resource: "my-app-bes",
+			Type:     "compute.v1.backendService",
+			Kind:     belvedere.DriftChanged,
+			Path:     "timeoutSec",
+			Expected: "30",
+			Actual:   "60",
+		},
+	}
+
+	project.EXPECT().
+		Drift(gomock.Any(), "my-app").
+		Return(drifts, nil)
+
+	output.EXPECT().
+		Print(drifts)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"drift",
+		"my-app",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
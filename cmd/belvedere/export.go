@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:   `export`,
+			Short: `Commands for exporting application resources to other tools`,
+			Long:  `Commands for exporting application resources to other tools.`,
+		},
+		Subcommands: []*cli.Command{
+			newExportTerraformCmd(),
+		},
+	}
+}
+
+func newExportTerraformCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use: `terraform <app> [<release>]`,
+			Example: `belvedere export terraform my-app > my-app.tf
+belvedere export terraform my-app v43 > my-app-v43.tf`,
+			Short: `Export an application or release as Terraform configuration`,
+			Long: `Export an application or release as Terraform configuration.
+
+Prints Terraform configuration for the google provider which is equivalent to the resources in the
+Deployment Manager deployment of an application, or of a release if one is given. References between
+resources are translated into Terraform references, and an import block is included for each
+resource which already exists, so the resources can be adopted by Terraform without being
+recreated.
+
+Once the resources are managed by Terraform, abandon the deployment with 'belvedere repair app
+--abandon' or 'belvedere repair release --abandon' so that Belvedere no longer modifies them.`,
+			Args: cobra.RangeArgs(1, 2),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			tf, err := project.ExportTerraform(ctx, args.String(0), args.String(1))
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(tf)
+		},
+	}
+}
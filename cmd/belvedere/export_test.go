@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestExportTerraform(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	project.EXPECT().
+		ExportTerraform(gomock.Any(), "my-app", "v43").
+		Return("resource {}\n", nil)
+
+	output.EXPECT().
+		PrintText("resource {}\n")
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"export",
+		"terraform",
+		"my-app",
+		"v43",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
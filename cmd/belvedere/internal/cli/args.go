@@ -45,8 +45,8 @@ func (a *args) File(idx int) ([]byte, error) {
 	return ioutil.ReadAll(a.stdin)
 }
 
-func isTerminal(r io.Reader) bool {
-	f, ok := r.(*os.File)
+func isTerminal(v interface{}) bool {
+	f, ok := v.(*os.File)
 	if !ok {
 		return false
 	}
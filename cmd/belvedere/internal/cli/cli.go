@@ -21,7 +21,13 @@ import (
 
 type CommandFunc func(ctx context.Context, project belvedere.Project, args Args, out Output) error
 
-type ProjectFactory func(ctx context.Context, name string, opts ...option.ClientOption) (belvedere.Project, error)
+// OfflineCommandFunc is a command which doesn't make any API calls, and so is passed the name of the
+// project instead of a client for it.
+type OfflineCommandFunc func(ctx context.Context, project string, args Args, out Output) error
+
+type ProjectFactory func(
+	ctx context.Context, name string, engine belvedere.Engine, opts ...option.ClientOption,
+) (belvedere.Project, error)
 
 type OutputFactory func(w io.Writer, format string) (Output, error)
 
@@ -29,6 +35,7 @@ type Command struct {
 	UI          cobra.Command
 	Flags       func(fs *pflag.FlagSet)
 	Run         CommandFunc
+	RunOffline  OfflineCommandFunc
 	Subcommands []*Command
 }
 
@@ -53,19 +60,21 @@ func (c *Command) ToCobra(pf ProjectFactory, of OutputFactory) *cobra.Command {
 	}
 
 	// Wrap the func, if one is provided.
-	if c.Run != nil {
+	if c.Run != nil || c.RunOffline != nil {
 		// Register the global flags for each command.
 		var gf GlobalFlags
 
 		gf.Register(cmd.Flags())
 
-		cmd.RunE = runE(&gf, pf, of, c.Run)
+		cmd.RunE = runE(&gf, pf, of, c.Run, c.RunOffline)
 	}
 
 	return &cmd
 }
 
-func runE(gf *GlobalFlags, pf ProjectFactory, of OutputFactory, f CommandFunc) func(*cobra.Command, []string) error {
+func runE(
+	gf *GlobalFlags, pf ProjectFactory, of OutputFactory, f CommandFunc, offline OfflineCommandFunc,
+) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, cmdArgs []string) error {
 		// Enable trace logging.
 		enableLogging(cmd.ErrOrStderr(), gf.Debug, gf.Quiet)
@@ -78,15 +87,8 @@ func runE(gf *GlobalFlags, pf ProjectFactory, of OutputFactory, f CommandFunc) f
 		ctx, span := rootSpan(ctx)
 		defer span.End()
 
-		// Create a Belvedere project.
-		project, err := pf(ctx, gf.Project,
-			option.WithUserAgent(fmt.Sprintf("belvedere/%s", cmd.Root().Version)))
-		if err != nil {
-			return err
-		}
-
 		span.AddAttributes(
-			trace.StringAttribute("project", project.Name()),
+			trace.StringAttribute("project", gf.Project),
 			trace.StringAttribute("args", shellescape.QuoteCommand(cmdArgs)),
 		)
 
@@ -103,7 +105,12 @@ func runE(gf *GlobalFlags, pf ProjectFactory, of OutputFactory, f CommandFunc) f
 		}
 
 		// Execute command.
-		err = f(ctx, project, args, output)
+		if offline != nil {
+			err = offline(ctx, gf.Project, args, output)
+		} else {
+			err = runOnline(ctx, cmd, gf, pf, f, args, output)
+		}
+
 		if err != nil {
 			span.SetStatus(trace.Status{
 				Code:    trace.StatusCodeInternal,
@@ -115,6 +122,20 @@ func runE(gf *GlobalFlags, pf ProjectFactory, of OutputFactory, f CommandFunc) f
 	}
 }
 
+// runOnline creates a Belvedere project and executes the command with it.
+func runOnline(
+	ctx context.Context, cmd *cobra.Command, gf *GlobalFlags, pf ProjectFactory, f CommandFunc, args Args,
+	out Output,
+) error {
+	project, err := pf(ctx, gf.Project, belvedere.Engine(gf.Engine),
+		option.WithUserAgent(fmt.Sprintf("belvedere/%s", cmd.Root().Version)))
+	if err != nil {
+		return err
+	}
+
+	return f(ctx, project, args, out)
+}
+
 func rootSpan(ctx context.Context) (context.Context, *trace.Span) {
 	ctx, span := trace.StartSpan(ctx, "belvedere.main")
 
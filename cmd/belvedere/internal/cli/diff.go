@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// PrintDiff prints the given diffs in color if the output is a terminal, or as JSON otherwise.
+func (o *tableOutput) PrintDiff(diffs []belvedere.ResourceDiff) error {
+	if !isTerminal(o.w) {
+		return (&jsonOutput{w: o.w}).Print(diffs)
+	}
+
+	return printColorDiff(o.w, diffs)
+}
+
+func (o *jsonOutput) PrintDiff(diffs []belvedere.ResourceDiff) error {
+	return o.Print(diffs)
+}
+
+func (o *prettyJSONOutput) PrintDiff(diffs []belvedere.ResourceDiff) error {
+	return o.Print(diffs)
+}
+
+func (o *yamlOutput) PrintDiff(diffs []belvedere.ResourceDiff) error {
+	return o.Print(diffs)
+}
+
+//nolint:gochecknoglobals // can't have non-scalar consts
+var (
+	diffSymbols = map[string]string{
+		belvedere.DiffAdd:    "+",
+		belvedere.DiffRemove: "-",
+		belvedere.DiffChange: "~",
+	}
+	diffColors = map[string]text.Colors{
+		belvedere.DiffAdd:    {text.FgGreen},
+		belvedere.DiffRemove: {text.FgRed},
+		belvedere.DiffChange: {text.FgYellow},
+	}
+)
+
+// printColorDiff prints a human-readable, colorized version of the given diffs.
+func printColorDiff(w io.Writer, diffs []belvedere.ResourceDiff) error {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	var sb strings.Builder
+
+	for _, rd := range diffs {
+		_, _ = fmt.Fprintf(&sb, "%s\n", diffColors[rd.Action].Sprintf("%s %s (%s)",
+			diffSymbols[rd.Action], rd.Name, rd.Type))
+
+		for i := range rd.Fields {
+			writeFieldDiff(&sb, &rd.Fields[i])
+		}
+
+		sb.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+func writeFieldDiff(sb *strings.Builder, fd *belvedere.FieldDiff) {
+	colors := diffColors[fd.Action]
+	prefix := fmt.Sprintf("    %s %s:", diffSymbols[fd.Action], fd.Path)
+
+	// Show changes to multi-line strings, like systemd services, line by line.
+	before, bok := fd.Before.(string)
+	after, aok := fd.After.(string)
+
+	if bok && aok && (strings.Contains(before, "\n") || strings.Contains(after, "\n")) {
+		_, _ = fmt.Fprintf(sb, "%s\n", colors.Sprint(prefix))
+
+		for _, line := range diffLines(strings.Split(before, "\n"), strings.Split(after, "\n")) {
+			_, _ = fmt.Fprintf(sb, "%s\n", diffColors[line.action].Sprintf("        %s %s",
+				diffSymbols[line.action], line.text))
+		}
+
+		return
+	}
+
+	switch fd.Action {
+	case belvedere.DiffAdd:
+		_, _ = fmt.Fprintf(sb, "%s\n", colors.Sprintf("%s %s", prefix, diffValue(fd.After)))
+	case belvedere.DiffRemove:
+		_, _ = fmt.Fprintf(sb, "%s\n", colors.Sprintf("%s %s", prefix, diffValue(fd.Before)))
+	default:
+		_, _ = fmt.Fprintf(sb, "%s\n", colors.Sprintf("%s %s => %s", prefix, diffValue(fd.Before),
+			diffValue(fd.After)))
+	}
+}
+
+// diffValue returns the given value as compact JSON.
+func diffValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+type diffLine struct {
+	action string
+	text   string
+}
+
+// diffLines returns a line-by-line diff of the two lists of lines, using their longest common
+// subsequence. Unchanged lines have no action.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{text: a[i]})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{action: belvedere.DiffRemove, text: a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{action: belvedere.DiffAdd, text: b[j]})
+			j++
+		}
+	}
+
+	return lines
+}
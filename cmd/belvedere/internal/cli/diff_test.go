@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestTableOutput_PrintDiff(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer(nil)
+	output := &tableOutput{w: buf}
+
+	// Buffers aren't terminals, so this should be JSON.
+	if err := output.PrintDiff([]belvedere.ResourceDiff{
+		{
+			Name:   "my-app-bes",
+			Type:   "compute.beta.backendService",
+			Action: belvedere.DiffChange,
+			Fields: []belvedere.FieldDiff{
+				{
+					Path:   "timeoutSec",
+					Action: belvedere.DiffChange,
+					Before: 30,
+					After:  60,
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"my-app-bes","type":"compute.beta.backendService","action":"change",` +
+		`"fields":[{"path":"timeoutSec","action":"change","before":30,"after":60}]}` + "\n"
+	assert.Equal(t, "PrintDiff()", want, buf.String())
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	got := diffLines(
+		[]string{"[Service]", "ExecStart=one", "ExecStop=two"},
+		[]string{"[Service]", "ExecStart=three", "ExecStop=two", "TimeoutStopSec=90"},
+	)
+
+	lines := make([]string, len(got))
+	for i, line := range got {
+		lines[i] = diffSymbols[line.action] + line.text
+	}
+
+	want := []string{
+		"[Service]",
+		"-ExecStart=one",
+		"+ExecStart=three",
+		"ExecStop=two",
+		"+TimeoutStopSec=90",
+	}
+
+	assert.Equal(t, "diffLines()", want, lines)
+}
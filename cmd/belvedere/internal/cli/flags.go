@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -9,8 +12,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/pflag"
+	"go.opencensus.io/trace"
 	"gopkg.in/ini.v1"
 )
 
@@ -20,6 +25,7 @@ type GlobalFlags struct {
 	Timeout time.Duration
 	Project string
 	Format  string
+	Engine  string
 }
 
 func (gf *GlobalFlags) Register(fs *pflag.FlagSet) {
@@ -28,6 +34,7 @@ func (gf *GlobalFlags) Register(fs *pflag.FlagSet) {
 	fs.DurationVar(&gf.Timeout, "timeout", 10*time.Minute, "maximum time allowed for total execution")
 	fs.StringVar(&gf.Project, "project", defaultProject, "specify a Google Cloud Platform project ID")
 	fs.StringVar(&gf.Format, "format", "table", "specify an output format (table, csv, json, prettyjson, yaml)")
+	fs.StringVar(&gf.Engine, "engine", "dm", "specify how deployments are applied (dm, direct)")
 }
 
 type ModifyFlags struct {
@@ -38,6 +45,14 @@ func (m *ModifyFlags) Register(fs *pflag.FlagSet) {
 	fs.BoolVar(&m.DryRun, "dry-run", false, "print modifications instead of performing them")
 }
 
+type PreviewFlags struct {
+	Preview bool
+}
+
+func (p *PreviewFlags) Register(fs *pflag.FlagSet) {
+	fs.BoolVar(&p.Preview, "preview", false, "validate and print planned changes with Deployment Manager instead of making them")
+}
+
 type LongRunningFlags struct {
 	Interval time.Duration
 }
@@ -54,6 +69,106 @@ func (a *AsyncFlags) Register(fs *pflag.FlagSet) {
 	fs.BoolVar(&a.Async, "async", false, "return without waiting for completion")
 }
 
+type ConfigFlags struct {
+	Overlays []string
+	Vars     []string
+}
+
+func (c *ConfigFlags) Register(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&c.Overlays, "overlay", nil, "a config file to merge over the base config (can be repeated)")
+	fs.StringArrayVar(&c.Vars, "var", nil, "a variable to interpolate into the config, as name=value (can be repeated)")
+}
+
+var errInvalidVar = fmt.Errorf("invalid variable, must be name=value")
+
+// Resolve returns the base config file in the given argument with the overlays merged over it, in
+// order, and the given built-in variables, the variables given with --var, and the process
+// environment interpolated into it.
+func (c *ConfigFlags) Resolve(args Args, idx int, builtins cfg.Variables) ([]byte, error) {
+	base, overlays, vars, err := c.read(args, idx, builtins)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.Resolve(base, vars, overlays...)
+}
+
+// Parse parses the base config file in the given argument with the overlays merged over it, in
+// order, and the given built-in variables, the variables given with --var, and the process
+// environment interpolated into it. If the configuration refers to any variables, the resolved
+// configuration is also returned as YAML. If the configuration is an old version of the format, a
+// warning is recorded in the given context's span.
+func (c *ConfigFlags) Parse(
+	ctx context.Context, args Args, idx int, builtins cfg.Variables,
+) (*cfg.Config, []byte, error) {
+	base, overlays, vars, err := c.read(args, idx, builtins)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	readers := make([]io.Reader, len(overlays))
+	for i, b := range overlays {
+		readers[i] = bytes.NewReader(b)
+	}
+
+	config, resolved, err := cfg.ParseWithVariables(bytes.NewReader(base), vars, readers...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config.Upgraded {
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("to", cfg.CurrentVersion),
+			},
+			"Config is an old version and was upgraded; run 'belvedere config migrate' to update it",
+		)
+	}
+
+	return config, resolved, nil
+}
+
+func (c *ConfigFlags) read(args Args, idx int, builtins cfg.Variables) ([]byte, [][]byte, cfg.Variables, error) {
+	// Variables are looked up in the process environment unless they're built in.
+	vars := cfg.Variables{}
+
+	for _, kv := range os.Environ() {
+		if parts := strings.SplitN(kv, "=", 2); len(parts) == 2 {
+			vars[parts[0]] = parts[1]
+		}
+	}
+
+	for k, v := range builtins {
+		vars[k] = v
+	}
+
+	// Variables given with --var override the built-in variables.
+	for _, kv := range c.Vars {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, nil, nil, fmt.Errorf("%w: %q", errInvalidVar, kv)
+		}
+
+		vars[parts[0]] = parts[1]
+	}
+
+	base, err := args.File(idx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	overlays := make([][]byte, len(c.Overlays))
+
+	for i, path := range c.Overlays {
+		overlays[i], err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return base, overlays, vars, nil
+}
+
 //nolint:gochecknoglobals // project name has to be a singleton value
 var (
 	defaultProject string
@@ -86,13 +201,13 @@ func init() {
 		fmt.Sprintf("config_%s", strings.TrimSpace(string(configName))))
 
 	// Read and parse it.
-	cfg, err := ini.Load(configPath)
+	sdkConfig, err := ini.Load(configPath)
 	if err != nil {
 		return
 	}
 
 	// Find core.project, if any.
-	key, err := cfg.Section("core").GetKey("project")
+	key, err := sdkConfig.Section("core").GetKey("project")
 	if err != nil {
 		return
 	}
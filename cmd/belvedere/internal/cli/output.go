@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/codahale/belvedere/pkg/belvedere"
 	"github.com/ghodss/yaml"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -15,6 +16,14 @@ import (
 
 type Output interface {
 	Print(v interface{}) error
+
+	// PrintDiff prints the given resource diffs. Table and CSV output is colorized text if the
+	// output is a terminal and JSON otherwise.
+	PrintDiff(diffs []belvedere.ResourceDiff) error
+
+	// PrintText prints the given text as-is, regardless of format. It's used for output which is
+	// already in a particular format, like configuration files.
+	PrintText(text string) error
 }
 
 var errBadFormat = fmt.Errorf("format must be one of: table, csv, json, prettyjson, yaml")
@@ -170,22 +179,22 @@ func toRows(v interface{}) (table.Row, []table.ColumnConfig, []table.Row, error)
 		return nil, nil, nil, errBadInput
 	}
 
-	headers, cols := collectHeaders(t)
-	rows := collectRows(v, headers)
+	headers, cols, fields := collectHeaders(t)
+	rows := collectRows(v, fields)
 
 	return headers, cols, rows, nil
 }
 
-func collectRows(v interface{}, headers table.Row) []table.Row {
+func collectRows(v interface{}, fields []int) []table.Row {
 	iv := reflect.ValueOf(v)
 	rows := make([]table.Row, iv.Len())
 
 	for i := 0; i < iv.Len(); i++ {
-		row := make(table.Row, len(headers))
+		row := make(table.Row, len(fields))
 		ev := iv.Index(i)
 
-		for j := range headers {
-			f := ev.Field(j)
+		for j, idx := range fields {
+			f := ev.Field(idx)
 
 			if t, ok := f.Interface().(time.Time); ok {
 				row[j] = t.Format(time.Stamp)
@@ -202,13 +211,21 @@ func collectRows(v interface{}, headers table.Row) []table.Row {
 	return rows
 }
 
-func collectHeaders(t reflect.Type) (table.Row, []table.ColumnConfig) {
+// collectHeaders returns the headers and column configs for the given struct type, plus the indexes
+// of the fields which are included in the table. Fields tagged with `table:"-"` are omitted.
+func collectHeaders(t reflect.Type) (table.Row, []table.ColumnConfig, []int) {
 	var headers table.Row
 
 	var cols []table.ColumnConfig
 
+	var fields []int
+
 	for i := 0; i < t.NumField(); i++ {
 		s := t.Field(i).Tag.Get("table")
+		if s == "-" {
+			continue
+		}
+
 		if s == "" {
 			s = t.Field(i).Name
 		}
@@ -216,6 +233,7 @@ func collectHeaders(t reflect.Type) (table.Row, []table.ColumnConfig) {
 		parts := strings.Split(s, ",")
 
 		headers = append(headers, parts[0])
+		fields = append(fields, i)
 
 		if strings.Contains(s, ",ralign") {
 			cols = append(cols, table.ColumnConfig{
@@ -225,5 +243,5 @@ func collectHeaders(t reflect.Type) (table.Row, []table.ColumnConfig) {
 		}
 	}
 
-	return headers, cols
+	return headers, cols, fields
 }
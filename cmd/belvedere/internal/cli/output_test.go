@@ -48,6 +48,39 @@ func TestTableOutput_Print(t *testing.T) {
 	assert.Equal(t, "Table", want, got)
 }
 
+func TestTableOutput_Print_Omitted(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer(nil)
+	output := &tableOutput{w: buf}
+
+	type omitted struct {
+		Name    string
+		Details map[string]string `table:"-"`
+		Size    int               `table:"Size,ralign"`
+	}
+
+	if err := output.Print([]omitted{
+		{
+			Name:    "one",
+			Details: map[string]string{"two": "three"},
+			Size:    4,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
++------+------+
+| Name | Size |
++------+------+
+| one  |    4 |
++------+------+
+`
+	got := "\n" + buf.String()
+	assert.Equal(t, "Table", want, got)
+}
+
 func TestCSVOutput_Print(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (o *tableOutput) PrintText(text string) error {
+	return printText(o.w, text)
+}
+
+func (o *jsonOutput) PrintText(text string) error {
+	return printText(o.w, text)
+}
+
+func (o *prettyJSONOutput) PrintText(text string) error {
+	return printText(o.w, text)
+}
+
+func (o *yamlOutput) PrintText(text string) error {
+	return printText(o.w, text)
+}
+
+// printText prints the given text, adding a trailing newline if it doesn't have one.
+func printText(w io.Writer, text string) error {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	_, err := fmt.Fprint(w, text)
+
+	return err
+}
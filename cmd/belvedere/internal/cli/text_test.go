@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestYAMLOutput_PrintText(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer(nil)
+	output := &yamlOutput{w: buf}
+
+	if err := output.PrintText("resource {}"); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "PrintText()", "resource {}\n", buf.String())
+}
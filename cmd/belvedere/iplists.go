@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newIPListsCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:   `iplists`,
+			Short: `Commands for managing IP lists`,
+			Long: `Commands for managing IP lists.
+
+IP lists are named lists of IP addresses and CIDR ranges (e.g. office networks or known bad actors)
+which are shared by the applications in the project. An application allows or denies the IPs in a
+list by referring to it by name in the allowIPs or denyIPs section of its configuration.`,
+		},
+		Subcommands: []*cli.Command{
+			newIPListsListCmd(),
+			newIPListsCreateCmd(),
+			newIPListsUpdateCmd(),
+		},
+	}
+}
+
+func newIPListsListCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `list`,
+			Example: `belvedere iplists list`,
+			Short:   `List IP lists`,
+			Long: `List IP lists.
+
+Prints a table of the project's IP lists and their ranges.`,
+			Args: cobra.NoArgs,
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			lists, err := project.IPLists().List(ctx)
+			if err != nil {
+				return err
+			}
+
+			return out.Print(lists)
+		},
+	}
+}
+
+func newIPListsCreateCmd() *cli.Command {
+	var mf cli.ModifyFlags
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `create <name> [<ranges-file>]`,
+			Example: `belvedere iplists create office office-ips.txt`,
+			Short:   `Create an IP list`,
+			Long: `Create an IP list.
+
+Creates a new IP list with the IP addresses and CIDR ranges in ranges-file, one per line. Blank
+lines and lines starting with '#' are ignored.
+
+If ranges-file is not specified (or is specified as '-'), the ranges are read from STDIN instead.`,
+			Args: cobra.RangeArgs(1, 2),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			mf.Register(fs)
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			name := args.String(0)
+
+			b, err := args.File(1)
+			if err != nil {
+				return err
+			}
+
+			return project.IPLists().Create(ctx, name, parseRanges(b), mf.DryRun)
+		},
+	}
+}
+
+func newIPListsUpdateCmd() *cli.Command {
+	var (
+		mf  cli.ModifyFlags
+		lrf cli.LongRunningFlags
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `update <name> [<ranges-file>]`,
+			Example: `belvedere iplists update office office-ips.txt`,
+			Short:   `Update an IP list`,
+			Long: `Update an IP list.
+
+Replaces the IP list's ranges with the IP addresses and CIDR ranges in ranges-file, one per line.
+Blank lines and lines starting with '#' are ignored.
+
+Each application which refers to the IP list is then updated with the configuration it was last
+created or updated with, so that its WAF rules have the new ranges. Each application is checked with
+the new ranges before anything is changed. Applications without a recorded configuration are
+skipped, with a warning.
+
+If ranges-file is not specified (or is specified as '-'), the ranges are read from STDIN instead.`,
+			Args: cobra.RangeArgs(1, 2),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			mf.Register(fs)
+			lrf.Register(fs)
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			name := args.String(0)
+
+			b, err := args.File(1)
+			if err != nil {
+				return err
+			}
+
+			return project.IPLists().Update(ctx, name, parseRanges(b), mf.DryRun, lrf.Interval)
+		},
+	}
+}
+
+// parseRanges returns the IP ranges in the given file, one per line, skipping blank lines and
+// comments.
+func parseRanges(b []byte) []string {
+	var ranges []string
+
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ranges = append(ranges, line)
+	}
+
+	return ranges
+}
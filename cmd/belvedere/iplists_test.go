@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/golang/mock/gomock"
+)
+
+func TestIPListsList(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	list := []belvedere.IPList{
+		{
+			Name:   "office",
+			Ranges: []string{"203.0.113.0/24"},
+		},
+	}
+
+	ipLists := NewMockIPListService(ctrl)
+	ipLists.EXPECT().
+		List(gomock.Any()).
+		Return(list, nil)
+
+	project.EXPECT().IPLists().Return(ipLists)
+
+	output.EXPECT().
+		Print(list)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"iplists",
+		"list",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPListsCreate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	ipLists := NewMockIPListService(ctrl)
+	ipLists.EXPECT().
+		Create(gomock.Any(), "office", []string{"203.0.113.0/24", "198.51.100.7"}, true)
+
+	project.EXPECT().IPLists().Return(ipLists)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString("# HQ\n203.0.113.0/24\n\n  198.51.100.7  \n"))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"iplists",
+		"create",
+		"office",
+		"--dry-run",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPListsUpdate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	ipLists := NewMockIPListService(ctrl)
+	ipLists.EXPECT().
+		Update(gomock.Any(), "office", []string{"203.0.113.0/24"}, false, 10*time.Millisecond)
+
+	project.EXPECT().IPLists().Return(ipLists)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString("203.0.113.0/24\n"))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"iplists",
+		"update",
+		"office",
+		"--interval=10ms",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
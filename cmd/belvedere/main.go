@@ -13,7 +13,7 @@ import (
 func main() {
 	cobra.EnableCommandSorting = false
 	version := buildVersion(version, commit, date, builtBy)
-	root := newRootCmd(version).ToCobra(belvedere.NewProject, cli.NewOutput)
+	root := newRootCmd(version).ToCobra(belvedere.NewProjectWithEngine, cli.NewOutput)
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -37,12 +37,18 @@ configuration, access control, secret management, configuration, and more.`,
 			newTeardownCmd(),
 			newDNSServersCmd(),
 			newInstancesCmd(),
+			newDriftCmd(),
 			newLogsCmd(),
 			newMachineTypesCmd(),
 			newSSHCmd(),
 			newAppsCmd(),
 			newReleasesCmd(),
 			newSecretsCmd(),
+			newIPListsCmd(),
+			newRepairCmd(),
+			newExportCmd(),
+			newRenderCmd(),
+			newConfigCmd(version),
 			// hidden commands!
 			newCompletionCmd(),
 			newDocsCmd(),
@@ -17,7 +17,9 @@ func mockFactories(ctrl *gomock.Controller) (*MockProject, *MockOutput, cli.Proj
 	output := NewMockOutput(ctrl)
 
 	return project, output,
-		func(ctx context.Context, name string, opts ...option.ClientOption) (belvedere.Project, error) {
+		func(
+			ctx context.Context, name string, engine belvedere.Engine, opts ...option.ClientOption,
+		) (belvedere.Project, error) {
 			return project, nil
 		},
 		func(w io.Writer, format string) (cli.Output, error) {
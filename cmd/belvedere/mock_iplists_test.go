@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../../pkg/belvedere/iplists.go
+
+// Package main is a generated GoMock package.
+package main
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	belvedere "github.com/codahale/belvedere/pkg/belvedere"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIPListService is a mock of IPListService interface.
+type MockIPListService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIPListServiceMockRecorder
+}
+
+// MockIPListServiceMockRecorder is the mock recorder for MockIPListService.
+type MockIPListServiceMockRecorder struct {
+	mock *MockIPListService
+}
+
+// NewMockIPListService creates a new mock instance.
+func NewMockIPListService(ctrl *gomock.Controller) *MockIPListService {
+	mock := &MockIPListService{ctrl: ctrl}
+	mock.recorder = &MockIPListServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIPListService) EXPECT() *MockIPListServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockIPListService) Create(ctx context.Context, name string, ranges []string, dryRun bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, name, ranges, dryRun)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockIPListServiceMockRecorder) Create(ctx, name, ranges, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockIPListService)(nil).Create), ctx, name, ranges, dryRun)
+}
+
+// List mocks base method.
+func (m *MockIPListService) List(ctx context.Context) ([]belvedere.IPList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]belvedere.IPList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockIPListServiceMockRecorder) List(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockIPListService)(nil).List), ctx)
+}
+
+// Update mocks base method.
+func (m *MockIPListService) Update(ctx context.Context, name string, ranges []string, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, name, ranges, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockIPListServiceMockRecorder) Update(ctx, name, ranges, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockIPListService)(nil).Update), ctx, name, ranges, dryRun, interval)
+}
@@ -7,6 +7,7 @@ package main
 import (
 	reflect "reflect"
 
+	belvedere "github.com/codahale/belvedere/pkg/belvedere"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -46,3 +47,31 @@ func (mr *MockOutputMockRecorder) Print(v interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Print", reflect.TypeOf((*MockOutput)(nil).Print), v)
 }
+
+// PrintDiff mocks base method.
+func (m *MockOutput) PrintDiff(diffs []belvedere.ResourceDiff) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrintDiff", diffs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PrintDiff indicates an expected call of PrintDiff.
+func (mr *MockOutputMockRecorder) PrintDiff(diffs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrintDiff", reflect.TypeOf((*MockOutput)(nil).PrintDiff), diffs)
+}
+
+// PrintText mocks base method.
+func (m *MockOutput) PrintText(text string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrintText", text)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PrintText indicates an expected call of PrintText.
+func (mr *MockOutputMockRecorder) PrintText(text interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrintText", reflect.TypeOf((*MockOutput)(nil).PrintText), text)
+}
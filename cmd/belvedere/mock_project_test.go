@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	belvedere "github.com/codahale/belvedere/pkg/belvedere"
+	cfg "github.com/codahale/belvedere/pkg/belvedere/cfg"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -65,6 +66,64 @@ func (mr *MockProjectMockRecorder) DNSServers(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DNSServers", reflect.TypeOf((*MockProject)(nil).DNSServers), ctx)
 }
 
+// Drift mocks base method.
+func (m *MockProject) Drift(ctx context.Context, app string) ([]belvedere.Drift, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drift", ctx, app)
+	ret0, _ := ret[0].([]belvedere.Drift)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Drift indicates an expected call of Drift.
+func (mr *MockProjectMockRecorder) Drift(ctx, app interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drift", reflect.TypeOf((*MockProject)(nil).Drift), ctx, app)
+}
+
+// ExportTerraform mocks base method.
+func (m *MockProject) ExportTerraform(ctx context.Context, app, release string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTerraform", ctx, app, release)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportTerraform indicates an expected call of ExportTerraform.
+func (mr *MockProjectMockRecorder) ExportTerraform(ctx, app, release interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTerraform", reflect.TypeOf((*MockProject)(nil).ExportTerraform), ctx, app, release)
+}
+
+// IPLists mocks base method.
+func (m *MockProject) IPLists() belvedere.IPListService {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IPLists")
+	ret0, _ := ret[0].(belvedere.IPListService)
+	return ret0
+}
+
+// IPLists indicates an expected call of IPLists.
+func (mr *MockProjectMockRecorder) IPLists() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IPLists", reflect.TypeOf((*MockProject)(nil).IPLists))
+}
+
+// Images mocks base method.
+func (m *MockProject) Images() belvedere.ImageService {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Images")
+	ret0, _ := ret[0].(belvedere.ImageService)
+	return ret0
+}
+
+// Images indicates an expected call of Images.
+func (mr *MockProjectMockRecorder) Images() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Images", reflect.TypeOf((*MockProject)(nil).Images))
+}
+
 // Instances mocks base method.
 func (m *MockProject) Instances(ctx context.Context, app, release string) ([]belvedere.Instance, error) {
 	m.ctrl.T.Helper()
@@ -178,3 +237,17 @@ func (mr *MockProjectMockRecorder) Teardown(ctx, dryRun, async, interval interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teardown", reflect.TypeOf((*MockProject)(nil).Teardown), ctx, dryRun, async, interval)
 }
+
+// ValidateConfig mocks base method.
+func (m *MockProject) ValidateConfig(ctx context.Context, region string, config *cfg.Config, imageSHA256 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateConfig", ctx, region, config, imageSHA256)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateConfig indicates an expected call of ValidateConfig.
+func (mr *MockProjectMockRecorder) ValidateConfig(ctx, region, config, imageSHA256 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateConfig", reflect.TypeOf((*MockProject)(nil).ValidateConfig), ctx, region, config, imageSHA256)
+}
@@ -37,18 +37,33 @@ func (m *MockReleaseService) EXPECT() *MockReleaseServiceMockRecorder {
 	return m.recorder
 }
 
+// Config mocks base method.
+func (m *MockReleaseService) Config(ctx context.Context, app, name string) (*cfg.Config, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Config", ctx, app, name)
+	ret0, _ := ret[0].(*cfg.Config)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Config indicates an expected call of Config.
+func (mr *MockReleaseServiceMockRecorder) Config(ctx, app, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Config", reflect.TypeOf((*MockReleaseService)(nil).Config), ctx, app, name)
+}
+
 // Create mocks base method.
-func (m *MockReleaseService) Create(ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string, dryRun bool, interval time.Duration) error {
+func (m *MockReleaseService) Create(ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string, dryRun, preview bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, app, name, config, imageSHA256, dryRun, interval)
+	ret := m.ctrl.Call(m, "Create", ctx, app, name, config, imageSHA256, dryRun, preview, interval)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockReleaseServiceMockRecorder) Create(ctx, app, name, config, imageSHA256, dryRun, interval interface{}) *gomock.Call {
+func (mr *MockReleaseServiceMockRecorder) Create(ctx, app, name, config, imageSHA256, dryRun, preview, interval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockReleaseService)(nil).Create), ctx, app, name, config, imageSHA256, dryRun, interval)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockReleaseService)(nil).Create), ctx, app, name, config, imageSHA256, dryRun, preview, interval)
 }
 
 // Delete mocks base method.
@@ -65,6 +80,21 @@ func (mr *MockReleaseServiceMockRecorder) Delete(ctx, app, name, dryRun, async,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockReleaseService)(nil).Delete), ctx, app, name, dryRun, async, interval)
 }
 
+// Diff mocks base method.
+func (m *MockReleaseService) Diff(ctx context.Context, app, a, b string) ([]belvedere.ResourceDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diff", ctx, app, a, b)
+	ret0, _ := ret[0].([]belvedere.ResourceDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diff indicates an expected call of Diff.
+func (mr *MockReleaseServiceMockRecorder) Diff(ctx, app, a, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diff", reflect.TypeOf((*MockReleaseService)(nil).Diff), ctx, app, a, b)
+}
+
 // Disable mocks base method.
 func (m *MockReleaseService) Disable(ctx context.Context, app, name string, dryRun bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
@@ -93,6 +123,36 @@ func (mr *MockReleaseServiceMockRecorder) Enable(ctx, app, name, dryRun, interva
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockReleaseService)(nil).Enable), ctx, app, name, dryRun, interval)
 }
 
+// Failures mocks base method.
+func (m *MockReleaseService) Failures(ctx context.Context, app, name string) ([]belvedere.FailedResource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Failures", ctx, app, name)
+	ret0, _ := ret[0].([]belvedere.FailedResource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Failures indicates an expected call of Failures.
+func (mr *MockReleaseServiceMockRecorder) Failures(ctx, app, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Failures", reflect.TypeOf((*MockReleaseService)(nil).Failures), ctx, app, name)
+}
+
+// Get mocks base method.
+func (m *MockReleaseService) Get(ctx context.Context, app, name string) (*belvedere.ReleaseDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, app, name)
+	ret0, _ := ret[0].(*belvedere.ReleaseDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockReleaseServiceMockRecorder) Get(ctx, app, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockReleaseService)(nil).Get), ctx, app, name)
+}
+
 // List mocks base method.
 func (m *MockReleaseService) List(ctx context.Context, app string) ([]belvedere.Release, error) {
 	m.ctrl.T.Helper()
@@ -107,3 +167,17 @@ func (mr *MockReleaseServiceMockRecorder) List(ctx, app interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockReleaseService)(nil).List), ctx, app)
 }
+
+// Repair mocks base method.
+func (m *MockReleaseService) Repair(ctx context.Context, app, name string, abandon, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Repair", ctx, app, name, abandon, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Repair indicates an expected call of Repair.
+func (mr *MockReleaseServiceMockRecorder) Repair(ctx, app, name, abandon, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Repair", reflect.TypeOf((*MockReleaseService)(nil).Repair), ctx, app, name, abandon, dryRun, interval)
+}
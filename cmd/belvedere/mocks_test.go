@@ -7,3 +7,5 @@ package main
 //go:generate mockgen -package main -destination mock_output_test.go -source ./internal/cli/output.go Output
 //go:generate mockgen -package main -destination mock_apps_test.go -source ../../pkg/belvedere/apps.go AppService
 //go:generate mockgen -package main -destination mock_releases_test.go -source ../../pkg/belvedere/releases.go ReleaseService
+//go:generate mockgen -package main -destination mock_images_test.go -source ../../pkg/belvedere/images.go ImageService
+//go:generate mockgen -package main -destination mock_iplists_test.go -source ../../pkg/belvedere/iplists.go IPListService
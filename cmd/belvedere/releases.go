@@ -1,8 +1,10 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
 	"github.com/codahale/belvedere/pkg/belvedere"
@@ -23,6 +25,9 @@ of GCE instances running the Docker image.`,
 		},
 		Subcommands: []*cli.Command{
 			newReleasesListCmd(),
+			newReleasesDescribeCmd(),
+			newReleasesConfigCmd(),
+			newReleasesDiffCmd(),
 			newReleasesCreateCmd(),
 			newReleasesEnableCmd(),
 			newReleasesDisableCmd(),
@@ -55,52 +60,193 @@ The list of releases can by filtered by application.`,
 	}
 }
 
+func newReleasesDescribeCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `describe <app> <name>`,
+			Example: `belvedere releases describe my-app v1 --format=yaml`,
+			Short:   `Describe a release`,
+			Long: `Describe a release.
+
+This shows the release's full image digests, when it was created and by whom, and the effective
+configuration it was created with, including pinned sidecar images and boot image. The configuration
+and images are only included in the JSON and YAML formats.`,
+			Args: cobra.ExactArgs(2),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			app := args.String(0)
+			name := args.String(1)
+
+			release, err := project.Releases().Get(ctx, app, name)
+			if err != nil {
+				return err
+			}
+
+			return out.Print([]belvedere.ReleaseDetails{*release})
+		},
+	}
+}
+
+func newReleasesConfigCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `config <app> <name>`,
+			Example: `belvedere releases config my-app v1 > my-app-v1.yaml`,
+			Short:   `Print the configuration a release was created with`,
+			Long: `Print the configuration a release was created with.
+
+Unlike 'belvedere releases describe', this prints the configuration, with its overlays and variables
+resolved, which the release was created with, with its boot image and sidecar images pinned to the
+versions which were deployed. Comments and formatting aren't kept. It can be passed back to
+'belvedere releases create' to recreate the release. Configurations are kept in
+the project's <project>-belvedere-configs bucket, which also keeps previous versions of them.`,
+			Args: cobra.ExactArgs(2),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			config, err := project.Releases().Config(ctx, args.String(0), args.String(1))
+			if err != nil {
+				return err
+			}
+
+			b, err := cfg.Marshal(config)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(string(b))
+		},
+	}
+}
+
+func newReleasesDiffCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `diff <app> <a> <b>`,
+			Example: `belvedere releases diff my-app v1 v2`,
+			Short:   `Show the differences between two releases`,
+			Long: `Show the differences between two releases.
+
+This compares the resources of release a with those of release b, field by field. In a terminal, the
+differences are shown in color; otherwise, they're printed as JSON.`,
+			Args: cobra.ExactArgs(3),
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			diffs, err := project.Releases().Diff(ctx, args.String(0), args.String(1), args.String(2))
+			if err != nil {
+				return err
+			}
+
+			return out.PrintDiff(diffs)
+		},
+	}
+}
+
 func newReleasesCreateCmd() *cli.Command {
 	var (
 		mf     cli.ModifyFlags
+		cf     cli.ConfigFlags
+		pf     cli.PreviewFlags
 		lrf    cli.LongRunningFlags
 		enable bool
+		tag    string
 	)
 
 	return &cli.Command{
 		UI: cobra.Command{
-			Use: `create <app> <name> <sha-256> [<config-file>]`,
+			Use: `create <app> <name> [<sha-256>] [<config-file>]`,
 			Example: `belvedere releases create my-app v1 ` +
-				`5fb4ba1a651bae8057ec6b5cdafc93fa7e0b7d944d6f02a4b751de4e15464def my-app.yaml`,
+				`5fb4ba1a651bae8057ec6b5cdafc93fa7e0b7d944d6f02a4b751de4e15464def my-app.yaml
+belvedere releases create my-app v1 --tag=v1.2.3 my-app.yaml`,
 			Short: `Create a release`,
 			Long: `Create a release.
 
 This requires the application name, a release name, the SHA-256 digest of the Docker image to
-deploy, and the application configuration.
+deploy, and the application configuration. Instead of a digest, the -tag flag can be used to resolve
+a tag of the application's image to its current digest via the image's registry, as long as the
+configured image doesn't already have a tag or digest.
 
 If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
-instead.`,
-			Args: cobra.RangeArgs(3, 4),
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render'). Any files in the configuration with a local
+source are read relative to the base configuration file's directory (or the current directory, if
+the configuration is read from STDIN).
+
+Only the release-level settings of the configuration (e.g. machineType, numReplicas, and container)
+are used. The app-level settings (identityAwareProxy, cdnPolicy, wafRules, rateLimits, allowIPs,
+denyIPs, sessionAffinity, drainingTimeout, and iamRoles) only take effect when the application is updated; if they differ
+from the application's, a warning is logged.
+
+Sidecar images are pinned to the digests their tags currently refer to, so that all instances of the
+release run the same sidecar builds, unless the configuration sets pinSidecars to false. Dry runs and
+previews don't look the images up.
+
+With --preview, Deployment Manager validates the release and plans its resources without creating
+them. The planned intent for each resource is printed along with any validation errors, and then the
+preview is cancelled.`,
+			Args: cobra.RangeArgs(2, 4),
 		},
 		Flags: func(fs *pflag.FlagSet) {
 			mf.Register(fs)
+			pf.Register(fs)
 			lrf.Register(fs)
+			cf.Register(fs)
 			fs.BoolVar(&enable, "enable", false, "enable the release after its successful creation")
+			fs.StringVar(&tag, "tag", "", "resolve the given tag of the app's image instead of passing a digest")
 		},
 		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
 			app := args.String(0)
 			name := args.String(1)
-			digest := args.String(2)
-			b, err := args.File(3)
+
+			// If a tag is passed, the digest is omitted from the args.
+			digest, configIdx := args.String(2), 3
+			if tag != "" {
+				if args.String(3) != "" {
+					return errDigestAndTag
+				}
+
+				digest, configIdx = "", 2
+			} else if digest == "" {
+				return errDigestRequired
+			}
+
+			region, err := appRegion(ctx, project, app)
 			if err != nil {
 				return err
 			}
 
-			config, err := cfg.Parse(bytes.NewReader(b))
+			config, resolved, err := cf.Parse(ctx, args, configIdx, variables(project.Name(), app, name, region))
 			if err != nil {
 				return err
 			}
 
-			if err := project.Releases().Create(ctx, app, name, config, digest, mf.DryRun, lrf.Interval); err != nil {
+			if err := printResolved(out, resolved, mf.DryRun); err != nil {
 				return err
 			}
 
-			if enable {
+			if err := config.ReadFiles(configDir(args.String(configIdx))); err != nil {
+				return err
+			}
+
+			if tag != "" {
+				image, err := imageWithTag(config.Container.Image, tag)
+				if err != nil {
+					return err
+				}
+
+				digest, err = project.Images().Digest(ctx, image)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := project.Releases().Create(
+				ctx, app, name, config, digest, mf.DryRun, pf.Preview, lrf.Interval,
+			); err != nil {
+				return err
+			}
+
+			// A previewed release doesn't exist, so it can't be enabled.
+			if enable && !pf.Preview {
 				return project.Releases().Enable(ctx, app, name, mf.DryRun, lrf.Interval)
 			}
 			return nil
@@ -108,6 +254,33 @@ instead.`,
 	}
 }
 
+var (
+	errDigestRequired = fmt.Errorf("either a SHA-256 digest or a tag is required")
+	errDigestAndTag   = fmt.Errorf("a SHA-256 digest and a tag are mutually exclusive")
+	errTaggedImage    = fmt.Errorf("the app's image already has a tag or digest, so a tag can't be given")
+)
+
+// imageWithTag returns the given image reference with the given tag. Images which already have a tag
+// or digest are rejected, rather than guessing which one was meant.
+func imageWithTag(image, tag string) (string, error) {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if strings.ContainsAny(name, ":@") {
+		return "", fmt.Errorf("%w: %s", errTaggedImage, image)
+	}
+
+	return fmt.Sprintf("%s:%s", image, tag), nil
+}
+
+// configDir returns the directory of the given config file, or the current directory if the config
+// was read from STDIN.
+func configDir(path string) string {
+	if path == "" || path == "-" {
+		return "."
+	}
+
+	return filepath.Dir(path)
+}
+
 func newReleasesEnableCmd() *cli.Command {
 	var (
 		mf  cli.ModifyFlags
@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere"
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/gubbins/assert"
 	"github.com/golang/mock/gomock"
 )
 
@@ -48,6 +50,128 @@ func TestReleasesList(t *testing.T) {
 	}
 }
 
+func TestReleasesDescribe(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	release := &belvedere.ReleaseDetails{
+		App:     "my-app",
+		Release: "v1",
+	}
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Get(gomock.Any(), "my-app", "v1").
+		Return(release, nil)
+
+	project.EXPECT().Releases().Return(releases)
+
+	output.EXPECT().
+		Print([]belvedere.ReleaseDetails{*release})
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"describe",
+		"my-app",
+		"v1",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleasesConfig(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Config(gomock.Any(), "my-app", "v1").
+		Return(&cfg.Config{
+			ReleaseConfig: cfg.ReleaseConfig{
+				NumReplicas: 10,
+				Container: cfg.Container{
+					Image: "gcr.io/my-project/my-app",
+				},
+			},
+		}, nil)
+
+	project.EXPECT().Releases().Return(releases)
+
+	output.EXPECT().
+		PrintText(`container:
+  image: gcr.io/my-project/my-app
+numReplicas: 10
+`)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"config",
+		"my-app",
+		"v1",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleasesDiff(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	diffs := []belvedere.ResourceDiff{
+		{
+			Name:   "my-app-v2-ig",
+			Action: belvedere.DiffChange,
+		},
+	}
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Diff(gomock.Any(), "my-app", "v1", "v2").
+		Return(diffs, nil)
+
+	project.EXPECT().Releases().Return(releases)
+
+	output.EXPECT().
+		PrintDiff(diffs)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"diff",
+		"my-app",
+		"v1",
+		"v2",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestReleasesCreate(t *testing.T) {
 	t.Parallel()
 
@@ -57,17 +181,35 @@ func TestReleasesCreate(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
 	releases := NewMockReleaseService(ctrl)
 	releases.EXPECT().
-		Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, 5*time.Minute)
+		Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, false, 5*time.Minute)
 
 	project.EXPECT().Releases().Return(releases)
 
 	cmd := newRootCmd("test").ToCobra(pf, of)
-	cmd.SetIn(bytes.NewBufferString(`numReplicas: 10`))
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
 	cmd.SetOut(bytes.NewBuffer(nil))
 	cmd.SetErr(bytes.NewBuffer(nil))
 	cmd.SetArgs([]string{
@@ -94,21 +236,39 @@ func TestReleasesCreate_AndEnable(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
 	releases := NewMockReleaseService(ctrl)
 	releases.EXPECT().
 		Enable(gomock.Any(), "my-app", "my-release", true, 5*time.Minute).
 		After(
 			releases.EXPECT().
-				Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, 5*time.Minute),
+				Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, false, 5*time.Minute),
 		)
 
 	project.EXPECT().Releases().Return(releases).AnyTimes()
 
 	cmd := newRootCmd("test").ToCobra(pf, of)
-	cmd.SetIn(bytes.NewBufferString(`numReplicas: 10`))
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
 	cmd.SetOut(bytes.NewBuffer(nil))
 	cmd.SetErr(bytes.NewBuffer(nil))
 	cmd.SetArgs([]string{
@@ -127,6 +287,62 @@ func TestReleasesCreate_AndEnable(t *testing.T) {
 	}
 }
 
+func TestReleasesCreate_Preview(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Create(gomock.Any(), "my-app", "my-release", &config, "12345", false, true, 5*time.Minute)
+
+	project.EXPECT().Releases().Return(releases).AnyTimes()
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"create",
+		"my-app",
+		"my-release",
+		"12345",
+		"--enable",
+		"--preview",
+		"--interval=5m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestReleasesCreate_WithFilename(t *testing.T) {
 	t.Parallel()
 
@@ -136,12 +352,26 @@ func TestReleasesCreate_WithFilename(t *testing.T) {
 	project, _, pf, of := mockFactories(ctrl)
 
 	config := cfg.Config{
-		NumReplicas: 10,
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
 	}
 
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
 	releases := NewMockReleaseService(ctrl)
 	releases.EXPECT().
-		Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, 5*time.Minute)
+		Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, false, 5*time.Minute)
 
 	project.EXPECT().Releases().Return(releases)
 
@@ -164,6 +394,68 @@ func TestReleasesCreate_WithFilename(t *testing.T) {
 	}
 }
 
+func TestReleasesCreate_WithTag(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	config := cfg.Config{
+		APIVersion: cfg.CurrentVersion,
+		Upgraded:   true,
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	images := NewMockImageService(ctrl)
+	images.EXPECT().
+		Digest(gomock.Any(), "gcr.io/my-project/my-app:v1.2.3").
+		Return("12345", nil)
+
+	project.EXPECT().Images().Return(images)
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Create(gomock.Any(), "my-app", "my-release", &config, "12345", true, false, 5*time.Minute)
+
+	project.EXPECT().Releases().Return(releases)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+`))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"create",
+		"my-app",
+		"my-release",
+		"--tag=v1.2.3",
+		"--dry-run",
+		"--interval=5m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestReleasesEnable(t *testing.T) {
 	t.Parallel()
 
@@ -257,3 +549,77 @@ func TestReleasesDelete(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReleasesCreate_WithTag_TaggedImage(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Get(gomock.Any(), "my-app").
+		Return(&belvedere.App{Name: "my-app", Region: "us-west1"}, nil)
+
+	project.EXPECT().Apps().Return(apps)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetIn(bytes.NewBufferString(`
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app:latest
+`))
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"releases",
+		"create",
+		"my-app",
+		"my-release",
+		"--tag=v1.2.3",
+		"--dry-run",
+		"--interval=5m",
+	})
+
+	if err := cmd.Execute(); !errors.Is(err, errTaggedImage) {
+		t.Fatalf("Execute() = %v, want %v", err, errTaggedImage)
+	}
+}
+
+func TestImageWithTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{
+			image: "gcr.io/my-project/my-app",
+			want:  "gcr.io/my-project/my-app:v1",
+		},
+		{
+			image: "localhost:5000/my-app",
+			want:  "localhost:5000/my-app:v1",
+		},
+		{
+			image: "gcr.io/my-project/my-app:latest",
+		},
+		{
+			image: "gcr.io/my-project/my-app@sha256:abcdef",
+		},
+	}
+	for _, testCase := range tests {
+		got, err := imageWithTag(testCase.image, "v1")
+		if testCase.want == "" {
+			if !errors.Is(err, errTaggedImage) {
+				t.Errorf("imageWithTag(%q) = %q, %v, want %v", testCase.image, got, err, errTaggedImage)
+			}
+
+			continue
+		}
+
+		assert.Equal(t, testCase.image, testCase.want, got)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newRenderCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:   `render`,
+			Short: `Commands for rendering resources without accessing GCP`,
+			Long: `Commands for rendering resources without accessing GCP.
+
+Prints the resources which would be created for an application or release, given the same inputs
+which would otherwise be looked up in the project. No API calls are made, so these commands can be
+used to check rendered configurations into source control or compare them against golden files in
+CI.`,
+		},
+		Subcommands: []*cli.Command{
+			newRenderAppCmd(),
+			newRenderReleaseCmd(),
+		},
+	}
+}
+
+var (
+	errZoneRequired  = fmt.Errorf("a DNS zone is required")
+	errInvalidIPList = fmt.Errorf("IP lists must be given as name=range,range,...")
+)
+
+func newRenderAppCmd() *cli.Command {
+	var (
+		zone    string
+		ipLists []string
+		cf      cli.ConfigFlags
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `app <name> <region> [<config-file>]`,
+			Example: `belvedere render app my-app us-west1 my-app.yaml --zone=example.com.`,
+			Short:   `Render an application's resources`,
+			Long: `Render an application's resources.
+
+Prints the Deployment Manager configuration which 'belvedere apps create' would use to create the
+application, with the given DNS zone in place of the project's managed zone. An application's
+resources are global, so the region is accepted only so the arguments match those of 'belvedere apps
+create'.
+
+If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render').
+
+The ranges of any project IP lists which the configuration refers to must be given with --ip-list
+(e.g. --ip-list=office=203.0.113.0/24,198.51.100.7), since they would otherwise be looked up in the
+project.`,
+			Args: cobra.RangeArgs(2, 3),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&zone, "zone", "", "the DNS name of the project's managed zone (e.g. example.com.)")
+			fs.StringArrayVar(&ipLists, "ip-list", nil, "the ranges of a project IP list (e.g. office=203.0.113.0/24)")
+			cf.Register(fs)
+		},
+		RunOffline: func(ctx context.Context, project string, args cli.Args, out cli.Output) error {
+			if zone == "" {
+				return errZoneRequired
+			}
+
+			config, _, err := cf.Parse(ctx, args, 2, variables(project, args.String(0), "", args.String(1)))
+			if err != nil {
+				return err
+			}
+
+			lists, err := parseIPLists(ipLists)
+			if err != nil {
+				return err
+			}
+
+			s, err := belvedere.RenderApp(project, args.String(0), zone, config, lists)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(s)
+		},
+	}
+}
+
+func newRenderReleaseCmd() *cli.Command {
+	var (
+		userData bool
+		cf       cli.ConfigFlags
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use: `release <app> <name> <region> <sha-256> [<config-file>]`,
+			Example: `belvedere render release my-app v1 us-west1 ` +
+				`5fb4ba1a651bae8057ec6b5cdafc93fa7e0b7d944d6f02a4b751de4e15464def my-app.yaml
+belvedere render release my-app v1 us-west1 ` +
+				`5fb4ba1a651bae8057ec6b5cdafc93fa7e0b7d944d6f02a4b751de4e15464def my-app.yaml --user-data`,
+			Short: `Render a release's resources`,
+			Long: `Render a release's resources.
+
+Prints the Deployment Manager configuration which 'belvedere releases create' would use to create
+the release in the given region. Unlike 'belvedere releases create', the boot image and sidecar
+images are used exactly as configured instead of being pinned to the images they currently refer to.
+
+With --user-data, only the cloud-config user data of the release's instances is printed.
+
+If config-file is not specified (or is specified as '-'), the configuration file is read from STDIN
+instead. Any config files given with --overlay are merged over it, in order, and variables are
+interpolated into it (see 'belvedere config render'). Any files in the configuration with a local
+source are read relative to the base configuration file's directory (or the current directory, if
+the configuration is read from STDIN).`,
+			Args: cobra.RangeArgs(4, 5),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			fs.BoolVar(&userData, "user-data", false, "print only the instances' cloud-config user data")
+			cf.Register(fs)
+		},
+		RunOffline: func(ctx context.Context, project string, args cli.Args, out cli.Output) error {
+			config, _, err := cf.Parse(ctx, args, 4, variables(project, args.String(0), args.String(1), args.String(2)))
+			if err != nil {
+				return err
+			}
+
+			if err := config.ReadFiles(configDir(args.String(4))); err != nil {
+				return err
+			}
+
+			render := belvedere.RenderRelease
+			if userData {
+				render = belvedere.RenderUserData
+			}
+
+			s, err := render(project, args.String(2), args.String(0), args.String(1), args.String(3), config)
+			if err != nil {
+				return err
+			}
+
+			return out.PrintText(s)
+		},
+	}
+}
+
+// parseIPLists parses IP lists given as name=range,range,... into their ranges, keyed by name.
+func parseIPLists(flags []string) (map[string][]string, error) {
+	lists := make(map[string][]string, len(flags))
+
+	for _, f := range flags {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errInvalidIPList
+		}
+
+		lists[parts[0]] = strings.Split(parts[1], ",")
+	}
+
+	return lists, nil
+}
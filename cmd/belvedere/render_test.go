@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/api/option"
+)
+
+// offlineProjectFactory returns a project factory which fails the test if it's called.
+func offlineProjectFactory(t *testing.T) cli.ProjectFactory {
+	t.Helper()
+
+	return func(context.Context, string, belvedere.Engine, ...option.ClientOption) (belvedere.Project, error) {
+		t.Error("project should not have been created")
+		return nil, nil
+	}
+}
+
+func TestRenderApp(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	want, err := belvedere.RenderApp("my-project", "my-app", "example.com.", &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output.EXPECT().
+		PrintText(want)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"render",
+		"app",
+		"my-app",
+		"us-west1",
+		"example.yaml",
+		"--zone=example.com.",
+		"--project=my-project",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderApp_NoZone(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, _, _, of := mockFactories(ctrl)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"render",
+		"app",
+		"my-app",
+		"us-west1",
+		"example.yaml",
+		"--project=my-project",
+	})
+
+	if err := cmd.Execute(); !errors.Is(err, errZoneRequired) {
+		t.Fatalf("Execute() = %v, want %v", err, errZoneRequired)
+	}
+}
+
+func TestRenderApp_InvalidIPList(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, _, _, of := mockFactories(ctrl)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"render",
+		"app",
+		"my-app",
+		"us-west1",
+		"example.yaml",
+		"--zone=example.com.",
+		"--ip-list=203.0.113.0/24",
+		"--project=my-project",
+	})
+
+	if err := cmd.Execute(); !errors.Is(err, errInvalidIPList) {
+		t.Fatalf("Execute() = %v, want %v", err, errInvalidIPList)
+	}
+}
+
+func TestRenderRelease(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	digest := strings.Repeat("1", 64)
+
+	want, err := belvedere.RenderRelease("my-project", "us-west1", "my-app", "v43", digest, &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output.EXPECT().
+		PrintText(want)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"render",
+		"release",
+		"my-app",
+		"v43",
+		"us-west1",
+		digest,
+		"example.yaml",
+		"--project=my-project",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderRelease_UserData(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, output, _, of := mockFactories(ctrl)
+
+	digest := strings.Repeat("1", 64)
+
+	want, err := belvedere.RenderUserData("my-project", "us-west1", "my-app", "v43", digest, &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 10,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output.EXPECT().
+		PrintText(want)
+
+	cmd := newRootCmd("test").ToCobra(offlineProjectFactory(t), of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"render",
+		"release",
+		"my-app",
+		"v43",
+		"us-west1",
+		digest,
+		"example.yaml",
+		"--project=my-project",
+		"--user-data",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
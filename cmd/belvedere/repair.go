@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+
+	"github.com/codahale/belvedere/cmd/belvedere/internal/cli"
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newRepairCmd() *cli.Command {
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:   `repair`,
+			Short: `Commands for repairing failed deployments`,
+			Long: `Commands for repairing failed deployments.
+
+When a change to an application or release fails partway through, its Deployment Manager deployment
+is left in an error state and further changes may be blocked. These commands describe the failed
+resources and walk the deployment back to a consistent state.`,
+		},
+		Subcommands: []*cli.Command{
+			newRepairAppCmd(),
+			newRepairReleaseCmd(),
+		},
+	}
+}
+
+const repairLong = `
+
+Any failed resources are printed first. Then any running operation is stopped, any outstanding
+preview is cancelled, and the deployment is updated with the last configuration which was
+successfully deployed. Deployments which are healthy are left alone.
+
+With --abandon, the deployment is deleted instead but its resources are left in place. This is
+useful when a deployment was never successfully created or can't be deleted normally, but the
+abandoned resources must then be cleaned up by hand.`
+
+func newRepairAppCmd() *cli.Command {
+	var (
+		mf      cli.ModifyFlags
+		lrf     cli.LongRunningFlags
+		abandon bool
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `app <name>`,
+			Example: `belvedere repair app my-app`,
+			Short:   `Repair an application`,
+			Long:    `Repair an application.` + repairLong,
+			Args:    cobra.ExactArgs(1),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			mf.Register(fs)
+			lrf.Register(fs)
+			fs.BoolVar(&abandon, "abandon", false, "delete the deployment but leave its resources in place")
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			name := args.String(0)
+
+			failed, err := project.Apps().Failures(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			if len(failed) > 0 {
+				if err := out.Print(failed); err != nil {
+					return err
+				}
+			}
+
+			return project.Apps().Repair(ctx, name, abandon, mf.DryRun, lrf.Interval)
+		},
+	}
+}
+
+func newRepairReleaseCmd() *cli.Command {
+	var (
+		mf      cli.ModifyFlags
+		lrf     cli.LongRunningFlags
+		abandon bool
+	)
+
+	return &cli.Command{
+		UI: cobra.Command{
+			Use:     `release <app> <name>`,
+			Example: `belvedere repair release my-app v43`,
+			Short:   `Repair a release`,
+			Long:    `Repair a release.` + repairLong,
+			Args:    cobra.ExactArgs(2),
+		},
+		Flags: func(fs *pflag.FlagSet) {
+			mf.Register(fs)
+			lrf.Register(fs)
+			fs.BoolVar(&abandon, "abandon", false, "delete the deployment but leave its resources in place")
+		},
+		Run: func(ctx context.Context, project belvedere.Project, args cli.Args, out cli.Output) error {
+			app := args.String(0)
+			name := args.String(1)
+
+			failed, err := project.Releases().Failures(ctx, app, name)
+			if err != nil {
+				return err
+			}
+
+			if len(failed) > 0 {
+				if err := out.Print(failed); err != nil {
+					return err
+				}
+			}
+
+			return project.Releases().Repair(ctx, app, name, abandon, mf.DryRun, lrf.Interval)
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere"
+	"github.com/golang/mock/gomock"
+)
+
+func TestRepairApp(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, output, pf, of := mockFactories(ctrl)
+
+	failed := []belvedere.FailedResource{
+		{
+			Name:  "my-app-ig",
+			Type:  "compute.v1.instanceGroupManager",
+			State: "FAILED",
+			Error: "quota exceeded",
+		},
+	}
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Failures(gomock.Any(), "my-app").
+		Return(failed, nil)
+	apps.EXPECT().
+		Repair(gomock.Any(), "my-app", false, true, 10*time.Minute).
+		Return(nil)
+
+	project.EXPECT().Apps().Return(apps).AnyTimes()
+
+	output.EXPECT().
+		Print(failed)
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"repair",
+		"app",
+		"my-app",
+		"--dry-run",
+		"--interval=10m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairRelease(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	project, _, pf, of := mockFactories(ctrl)
+
+	releases := NewMockReleaseService(ctrl)
+	releases.EXPECT().
+		Failures(gomock.Any(), "my-app", "v43").
+		Return(nil, nil)
+	releases.EXPECT().
+		Repair(gomock.Any(), "my-app", "v43", true, false, 10*time.Minute).
+		Return(nil)
+
+	project.EXPECT().Releases().Return(releases).AnyTimes()
+
+	cmd := newRootCmd("test").ToCobra(pf, of)
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+	cmd.SetArgs([]string{
+		"repair",
+		"release",
+		"my-app",
+		"v43",
+		"--abandon",
+		"--interval=10m",
+	})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -21,4 +21,5 @@ require (
 	google.golang.org/api v0.44.0
 	gopkg.in/ini.v1 v1.62.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
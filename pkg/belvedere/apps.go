@@ -2,10 +2,14 @@ package belvedere
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
@@ -22,14 +26,40 @@ type AppService interface {
 	// List returns a list of applications which have been created in the project.
 	List(ctx context.Context) ([]App, error)
 
-	// Create creates an application in the given region with the given name and configuration.
-	Create(ctx context.Context, region, name string, config *cfg.Config, dryRun bool, interval time.Duration) error
+	// Create creates an application in the given region with the given name and configuration. If
+	// preview is true, the planned changes are validated and printed by Deployment Manager but not
+	// made.
+	Create(
+		ctx context.Context, region, name string, config *cfg.Config, dryRun, preview bool,
+		interval time.Duration,
+	) error
 
-	// Update updates the resources for the given application to match the given configuration.
-	Update(ctx context.Context, name string, config *cfg.Config, dryRun bool, interval time.Duration) error
+	// Update updates the resources for the given application to match the given configuration. If
+	// preview is true, the planned changes are validated and printed by Deployment Manager but not
+	// made.
+	Update(
+		ctx context.Context, name string, config *cfg.Config, dryRun, preview bool, interval time.Duration,
+	) error
+
+	// Diff returns the changes to the given application's resources which updating it with the given
+	// configuration would make.
+	Diff(ctx context.Context, name string, config *cfg.Config) ([]ResourceDiff, error)
+
+	// Config returns the configuration which the given application was last created or updated with.
+	Config(ctx context.Context, name string) (*cfg.Config, error)
 
 	// Delete deletes all the resources associated with the given application.
 	Delete(ctx context.Context, name string, dryRun, async bool, interval time.Duration) error
+
+	// Failures returns the resources of the given application whose most recent changes failed or
+	// never finished.
+	Failures(ctx context.Context, name string) ([]FailedResource, error)
+
+	// Repair stops any running changes to the given application, cancels any outstanding preview,
+	// and restores its resources to the last configuration which was successfully deployed. If
+	// abandon is true, the application's deployment is deleted instead, leaving its resources in
+	// place.
+	Repair(ctx context.Context, name string, abandon, dryRun bool, interval time.Duration) error
 }
 
 // App is a Belvedere application.
@@ -45,6 +75,8 @@ type appService struct {
 	dm        deployments.Manager
 	resources resources.Builder
 	gce       *compute.Service
+	validator ConfigValidator
+	configs   configs.Store
 }
 
 var _ AppService = &appService{}
@@ -95,7 +127,8 @@ func (e *RegionDownError) Error() string {
 }
 
 func (s *appService) Create(
-	ctx context.Context, region, name string, config *cfg.Config, dryRun bool, interval time.Duration,
+	ctx context.Context, region, name string, config *cfg.Config, dryRun, preview bool,
+	interval time.Duration,
 ) error {
 	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Create")
 	defer span.End()
@@ -104,6 +137,7 @@ func (s *appService) Create(
 		trace.StringAttribute("region", region),
 		trace.StringAttribute("name", name),
 		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
 	)
 
 	// Validate the application name.
@@ -122,26 +156,41 @@ func (s *appService) Create(
 		return &RegionDownError{Region: region, Status: r.Status}
 	}
 
+	// Check the configuration against the project before making any changes.
+	if err := s.validator.ValidateApp(ctx, region, config); err != nil {
+		return err
+	}
+
 	// Find the project's managed zone.
 	managedZone, err := s.setup.ManagedZone(ctx, s.project)
 	if err != nil {
 		return err
 	}
 
+	// Find the ranges of the IP lists the app refers to.
+	ipLists, err := s.ipLists(ctx, &config.AppConfig, nil)
+	if err != nil {
+		return err
+	}
+
 	// Create a deployment with all the application resources.
-	return s.dm.Insert(ctx, s.project, resources.Name(name),
-		s.resources.App(s.project, name, managedZone, config),
+	if err := s.dm.Insert(ctx, s.project, resources.Name(name),
+		s.resources.App(s.project, name, managedZone, &config.AppConfig, ipLists),
 		deployments.Labels{
 			Type:   "app",
 			App:    name,
 			Region: region,
 		},
-		dryRun, interval,
-	)
+		dryRun, preview, interval,
+	); err != nil {
+		return err
+	}
+
+	return s.putConfig(ctx, name, config, dryRun, preview)
 }
 
 func (s *appService) Update(
-	ctx context.Context, name string, config *cfg.Config, dryRun bool, interval time.Duration,
+	ctx context.Context, name string, config *cfg.Config, dryRun, preview bool, interval time.Duration,
 ) error {
 	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Update")
 	defer span.End()
@@ -149,19 +198,169 @@ func (s *appService) Update(
 	span.AddAttributes(
 		trace.StringAttribute("name", name),
 		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
 	)
 
+	app, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Check the configuration against the project before making any changes.
+	if err := s.validator.ValidateApp(ctx, app.Region, config); err != nil {
+		return err
+	}
+
+	// Warn if the release-level settings differ from the app's releases, since they're ignored.
+	releases, fields, err := s.ignoredChanges(ctx, name, config)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		span.Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("releases", strings.Join(releases, ",")),
+				trace.StringAttribute("fields", strings.Join(fields, ",")),
+			},
+			"Release-level settings differ from the app's releases and were ignored; create a release to change them",
+		)
+	}
+
 	// Find the project's managed zone.
 	managedZone, err := s.setup.ManagedZone(ctx, s.project)
 	if err != nil {
 		return err
 	}
 
+	// Find the ranges of the IP lists the app refers to.
+	ipLists, err := s.ipLists(ctx, &config.AppConfig, nil)
+	if err != nil {
+		return err
+	}
+
 	// Update the deployment with the new application resources.
-	return s.dm.Update(ctx, s.project, resources.Name(name),
-		s.resources.App(s.project, name, managedZone, config),
-		dryRun, interval,
+	if err := s.dm.Update(ctx, s.project, resources.Name(name),
+		s.resources.App(s.project, name, managedZone, &config.AppConfig, ipLists),
+		dryRun, preview, interval,
+	); err != nil {
+		return err
+	}
+
+	return s.putConfig(ctx, name, config, dryRun, preview)
+}
+
+// ignoredChanges returns the names of the given application's releases whose release-level settings,
+// which updating the application ignores, differ from the given configuration's, and the fields
+// which differ. Releases without a recorded configuration are skipped.
+func (s *appService) ignoredChanges(ctx context.Context, name string, config *cfg.Config) ([]string, []string, error) {
+	list, err := s.dm.List(ctx, s.project, releaseFilter(name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var releases []string
+
+	fields := map[string]bool{}
+
+	for _, dep := range list {
+		live, err := s.configs.Get(ctx, s.project, resources.Name(name, dep.Release))
+		if err != nil {
+			var notFound *configs.NotFoundError
+			if errors.As(err, &notFound) {
+				continue
+			}
+
+			return nil, nil, err
+		}
+
+		changes := config.ReleaseConfig.Changes(&live.ReleaseConfig)
+		if len(changes) == 0 {
+			continue
+		}
+
+		releases = append(releases, dep.Release)
+
+		for _, f := range changes {
+			fields[f] = true
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+
+	sort.Strings(names)
+
+	return releases, names, nil
+}
+
+// ipLists returns the ranges of the project IP lists which the given configuration refers to, keyed
+// by name. The ranges of any lists in updated are used instead of their stored ranges.
+func (s *appService) ipLists(
+	ctx context.Context, config *cfg.AppConfig, updated map[string][]string,
+) (map[string][]string, error) {
+	ipLists := map[string][]string{}
+
+	for _, name := range config.IPLists() {
+		if ranges, ok := updated[name]; ok {
+			ipLists[name] = ranges
+			continue
+		}
+
+		ranges, err := s.configs.GetIPList(ctx, s.project, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ipLists[name] = ranges
+	}
+
+	if err := checkIPLists(config, ipLists); err != nil {
+		return nil, err
+	}
+
+	return ipLists, nil
+}
+
+// putConfig records the configuration the given application was deployed with, unless no changes
+// were made.
+func (s *appService) putConfig(ctx context.Context, name string, config *cfg.Config, dryRun, preview bool) error {
+	if dryRun || preview {
+		return nil
+	}
+
+	return s.configs.Put(ctx, s.project, resources.Name(name), config)
+}
+
+func (s *appService) Diff(ctx context.Context, name string, config *cfg.Config) ([]ResourceDiff, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Diff")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
 	)
+
+	// Find the project's managed zone.
+	managedZone, err := s.setup.ManagedZone(ctx, s.project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the application's current resources.
+	current, err := s.dm.Resources(ctx, s.project, resources.Name(name))
+	if err != nil {
+		return nil, fmt.Errorf("error getting app: %w", err)
+	}
+
+	// Find the ranges of the IP lists the app refers to.
+	ipLists, err := s.ipLists(ctx, &config.AppConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffResources(current, s.resources.App(s.project, name, managedZone, &config.AppConfig, ipLists))
 }
 
 func (s *appService) Delete(ctx context.Context, name string, dryRun, async bool, interval time.Duration) error {
@@ -175,5 +374,57 @@ func (s *appService) Delete(ctx context.Context, name string, dryRun, async bool
 	)
 
 	// Delete the application deployment.
-	return s.dm.Delete(ctx, s.project, resources.Name(name), dryRun, async, interval)
+	if err := s.dm.Delete(ctx, s.project, resources.Name(name), dryRun, async, interval); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	// Only delete the application's recorded configuration once the application is gone.
+	if async {
+		span.Annotate(nil, "Kept the app's recorded configuration, since its deletion may still fail")
+
+		return nil
+	}
+
+	return s.configs.Delete(ctx, s.project, resources.Name(name))
+}
+
+func (s *appService) Config(ctx context.Context, name string) (*cfg.Config, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Config")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+	)
+
+	return s.configs.Get(ctx, s.project, resources.Name(name))
+}
+
+func (s *appService) Failures(ctx context.Context, name string) ([]FailedResource, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Failures")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+	)
+
+	return failures(ctx, s.dm, s.project, resources.Name(name))
+}
+
+func (s *appService) Repair(
+	ctx context.Context, name string, abandon, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.apps.Repair")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("abandon", abandon),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	return repair(ctx, s.dm, s.project, resources.Name(name), abandon, dryRun, interval)
 }
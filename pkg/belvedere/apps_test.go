@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
 	"github.com/codahale/gubbins/assert"
 	"github.com/codahale/gubbins/httpmock"
@@ -118,12 +119,16 @@ func TestAppService_Create(t *testing.T) {
 	}
 	config := &cfg.Config{}
 
+	validator := NewMockConfigValidator(ctrl)
+	validator.EXPECT().
+		ValidateApp(gomock.Any(), "us-west1", config)
+
 	setupService.EXPECT().
 		ManagedZone(gomock.Any(), "my-project").
 		Return(mz, nil)
 
 	resourceBuilder.EXPECT().
-		App("my-project", "my-app", mz, config).
+		App("my-project", "my-app", mz, &config.AppConfig, map[string][]string{}).
 		Return(res)
 
 	dm.EXPECT().
@@ -133,7 +138,11 @@ func TestAppService_Create(t *testing.T) {
 				App:    "my-app",
 				Region: "us-west1",
 			},
-			false, 10*time.Millisecond)
+			false, false, 10*time.Millisecond)
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Put(gomock.Any(), "my-project", "belvedere-my-app", config)
 
 	gce, err := compute.NewService(
 		context.Background(),
@@ -150,12 +159,67 @@ func TestAppService_Create(t *testing.T) {
 		resources: resourceBuilder,
 		setup:     setupService,
 		gce:       gce,
+		validator: validator,
+		configs:   store,
 	}
-	if err := apps.Create(context.Background(), "us-west1", "my-app", config, false, 10*time.Millisecond); err != nil {
+	if err := apps.Create(context.Background(), "us-west1", "my-app", config, false, false, 10*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestAppService_Create_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/regions/us-west1?alt=json&fields=status&prettyPrint=false`,
+		httpmock.RespJSON(compute.Region{
+			Status: "UP",
+		}))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			MachineType: "n9-huge",
+		},
+	}
+	invalid := &cfg.ValidationError{
+		Errors: []*cfg.FieldError{
+			{
+				Path: "machineType",
+				Err:  &cfg.InvalidValueError{Value: "n9-huge", Reason: "not offered in us-west1"},
+			},
+		},
+	}
+
+	validator := NewMockConfigValidator(ctrl)
+	validator.EXPECT().
+		ValidateApp(gomock.Any(), "us-west1", config).
+		Return(invalid)
+
+	gce, err := compute.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps := &appService{
+		project:   "my-project",
+		gce:       gce,
+		validator: validator,
+	}
+
+	err = apps.Create(context.Background(), "us-west1", "my-app", config, false, false, 10*time.Millisecond)
+
+	assert.Equal(t, "Create() error", invalid, err)
+}
+
 func TestAppService_Create_DownRegion(t *testing.T) {
 	t.Parallel()
 
@@ -193,7 +257,7 @@ func TestAppService_Create_DownRegion(t *testing.T) {
 		gce:       gce,
 	}
 
-	err = apps.Create(context.Background(), "us-west1", "my-app", config, false, 10*time.Millisecond)
+	err = apps.Create(context.Background(), "us-west1", "my-app", config, false, false, 10*time.Millisecond)
 	if err == nil {
 		t.Fatal("no error")
 	}
@@ -234,7 +298,7 @@ func TestAppService_Create_BadRegion(t *testing.T) {
 		gce:       gce,
 	}
 
-	err = apps.Create(context.Background(), "us-west1", "my-app", config, false, 10*time.Millisecond)
+	err = apps.Create(context.Background(), "us-west1", "my-app", config, false, false, 10*time.Millisecond)
 	if err == nil {
 		t.Fatal("no error")
 	}
@@ -258,27 +322,166 @@ func TestAppService_Update(t *testing.T) {
 	}
 	config := &cfg.Config{}
 
+	dm.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&deployments.Deployment{
+			Labels: deployments.Labels{
+				Type:   "app",
+				App:    "my-app",
+				Region: "us-west1",
+			},
+		}, nil)
+
+	validator := NewMockConfigValidator(ctrl)
+	validator.EXPECT().
+		ValidateApp(gomock.Any(), "us-west1", config)
+
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "release" AND labels.belvedere-app eq "my-app"`)
+
 	setupService.EXPECT().
 		ManagedZone(gomock.Any(), "my-project").
 		Return(mz, nil)
 
 	resourceBuilder.EXPECT().
-		App("my-project", "my-app", mz, config).
+		App("my-project", "my-app", mz, &config.AppConfig, map[string][]string{}).
 		Return(res)
 
 	dm.EXPECT().
-		Update(gomock.Any(), "my-project", "belvedere-my-app", res, false, 10*time.Millisecond)
+		Update(gomock.Any(), "my-project", "belvedere-my-app", res, false, false, 10*time.Millisecond)
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Put(gomock.Any(), "my-project", "belvedere-my-app", config)
 
 	apps := &appService{
 		project:   "my-project",
 		dm:        dm,
 		resources: resourceBuilder,
 		setup:     setupService,
+		validator: validator,
+		configs:   store,
+	}
+
+	if err := apps.Update(context.Background(), "my-app", config, false, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppService_ignoredChanges(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 2,
+			MachineType: "n1-standard-1",
+		},
+	}
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "release" AND labels.belvedere-app eq "my-app"`).
+		Return([]deployments.Deployment{
+			{Labels: deployments.Labels{App: "my-app", Release: "v1"}},
+			{Labels: deployments.Labels{App: "my-app", Release: "v2"}},
+			{Labels: deployments.Labels{App: "my-app", Release: "v3"}},
+		}, nil)
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return(config, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app-v2").
+		Return(&cfg.Config{
+			ReleaseConfig: cfg.ReleaseConfig{
+				NumReplicas: 10,
+				MachineType: "n2-standard-4",
+			},
+		}, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app-v3").
+		Return(nil, &configs.NotFoundError{Name: "belvedere-my-app-v3"})
+
+	apps := &appService{
+		project: "my-project",
+		dm:      dm,
+		configs: store,
 	}
 
-	if err := apps.Update(context.Background(), "my-app", config, false, 10*time.Millisecond); err != nil {
+	releases, fields, err := apps.ignoredChanges(context.Background(), "my-app", config)
+	if err != nil {
 		t.Fatal(err)
 	}
+
+	assert.Equal(t, "releases", []string{"v2"}, releases)
+	assert.Equal(t, "fields", []string{"machineType", "numReplicas"}, fields)
+}
+
+func TestAppService_Diff(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resourceBuilder := NewResourceBuilder(ctrl)
+	dm := NewDeploymentsManager(ctrl)
+	setupService := NewSetupService(ctrl)
+
+	mz := &dns.ManagedZone{}
+	config := &cfg.Config{}
+
+	setupService.EXPECT().
+		ManagedZone(gomock.Any(), "my-project").
+		Return(mz, nil)
+
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app").
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-bes",
+				Type:       "compute.beta.backendService",
+				Properties: &compute.BackendService{TimeoutSec: 30},
+			},
+		}, nil)
+
+	resourceBuilder.EXPECT().
+		App("my-project", "my-app", mz, &config.AppConfig, map[string][]string{}).
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-bes",
+				Type:       "compute.beta.backendService",
+				Properties: &compute.BackendService{TimeoutSec: 60},
+			},
+		})
+
+	apps := &appService{
+		project:   "my-project",
+		dm:        dm,
+		resources: resourceBuilder,
+		setup:     setupService,
+	}
+
+	got, err := apps.Diff(context.Background(), "my-app", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ResourceDiff{
+		{
+			Name:   "my-app-bes",
+			Type:   "compute.beta.backendService",
+			Action: DiffChange,
+			Fields: []FieldDiff{
+				{Path: "timeoutSec", Action: DiffChange, Before: 30.0, After: 60.0},
+			},
+		},
+	}
+
+	assert.Equal(t, "Diff()", want, got)
 }
 
 func TestAppService_Delete(t *testing.T) {
@@ -292,12 +495,66 @@ func TestAppService_Delete(t *testing.T) {
 	dm.EXPECT().
 		Delete(gomock.Any(), "my-project", "belvedere-my-app", false, false, 10*time.Millisecond)
 
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Delete(gomock.Any(), "my-project", "belvedere-my-app")
+
 	apps := &appService{
 		project: "my-project",
 		dm:      dm,
+		configs: store,
 	}
 
 	if err := apps.Delete(context.Background(), "my-app", false, false, 10*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestAppService_Delete_Async(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+
+	dm.EXPECT().
+		Delete(gomock.Any(), "my-project", "belvedere-my-app", false, true, 10*time.Millisecond)
+
+	// The recorded configuration is kept, since the deletion may still fail.
+	apps := &appService{
+		project: "my-project",
+		dm:      dm,
+		configs: NewConfigStore(ctrl),
+	}
+
+	if err := apps.Delete(context.Background(), "my-app", false, true, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppService_Config(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &cfg.Config{ReleaseConfig: cfg.ReleaseConfig{NumReplicas: 2}}
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(config, nil)
+
+	apps := &appService{
+		project: "my-project",
+		configs: store,
+	}
+
+	got, err := apps.Config(context.Background(), "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Config()", config, got)
+}
@@ -11,13 +11,19 @@ import (
 
 	// Make the GCP libs a direct dependency so we can get dependabot updates for it.
 	_ "cloud.google.com/go"
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/check"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/direct"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/live"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/setup"
 	"go.opencensus.io/trace"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iam/v1"
 	"google.golang.org/api/logging/v2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/secretmanager/v1"
@@ -48,6 +54,22 @@ type Project interface {
 	// GCE region, if one is provided.
 	MachineTypes(ctx context.Context, region string) ([]MachineType, error)
 
+	// Drift compares the resources in the deployments of the given app and its releases, or of all
+	// apps if none is given, to the live resources. It returns the fields which differ, the
+	// resources which are missing, and any backends attached to the apps' backend services outside
+	// of Belvedere.
+	Drift(ctx context.Context, app string) ([]Drift, error)
+
+	// ExportTerraform returns Terraform configuration for the google provider which is equivalent
+	// to the deployment of the given app, or of the given release if one is provided. References
+	// between resources are translated into Terraform references, and import blocks are included
+	// for the resources which already exist.
+	ExportTerraform(ctx context.Context, app, release string) (string, error)
+
+	// ValidateConfig checks the given configuration against the resources which are available in
+	// the project. See ConfigValidator.
+	ValidateConfig(ctx context.Context, region string, config *cfg.Config, imageSHA256 string) error
+
 	// Logs provides methods for viewing application logs.
 	Logs() LogService
 
@@ -59,25 +81,59 @@ type Project interface {
 
 	// Releases provides methods for managing releases.
 	Releases() ReleaseService
+
+	// Images provides methods for working with Docker images.
+	Images() ImageService
+
+	// IPLists provides methods for managing the project's IP lists.
+	IPLists() IPListService
 }
 
-// NewProject returns a new Project instance for the given GCP project.
+// Engine is the mechanism used to apply deployments.
+type Engine string
+
+const (
+	// EngineDM applies deployments with Deployment Manager.
+	EngineDM Engine = "dm"
+	// EngineDirect applies deployments with the Compute Engine, Cloud DNS, and IAM APIs directly,
+	// and stores their state in a Cloud Storage bucket in the project.
+	EngineDirect Engine = "direct"
+)
+
+type UnknownEngineError struct {
+	Engine Engine
+}
+
+func (e *UnknownEngineError) Error() string {
+	return fmt.Sprintf("unknown engine: %s", e.Engine)
+}
+
+// NewProject returns a new Project instance for the given GCP project. Deployments are applied with
+// Deployment Manager.
 func NewProject(ctx context.Context, name string, opts ...option.ClientOption) (Project, error) {
+	return NewProjectWithEngine(ctx, name, EngineDM, opts...)
+}
+
+// NewProjectWithEngine returns a new Project instance for the given GCP project which applies
+// deployments with the given engine.
+func NewProjectWithEngine(
+	ctx context.Context, name string, engine Engine, opts ...option.ClientOption,
+) (Project, error) {
 	if err := gcp.ValidateRFC1035(name); err != nil {
 		return nil, err
 	}
 
-	ls, err := logging.NewService(ctx, opts...)
+	dm, err := newManager(ctx, engine, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	sm, err := secretmanager.NewService(ctx, opts...)
+	ls, err := logging.NewService(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	dm, err := deployments.NewManager(ctx, opts...)
+	sm, err := secretmanager.NewService(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -92,8 +148,34 @@ func NewProject(ctx context.Context, name string, opts ...option.ClientOption) (
 		return nil, err
 	}
 
+	rc, err := registry.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lf, err := live.NewFetcher(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := configs.NewStore(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	res := resources.NewBuilder()
 	hc := check.NewHealthChecker(gce)
+	validator := &configValidator{
+		project:  name,
+		gce:      gce,
+		iam:      is,
+		registry: rc,
+	}
 
 	apps := &appService{
 		project:   name,
@@ -101,9 +183,15 @@ func NewProject(ctx context.Context, name string, opts ...option.ClientOption) (
 		setup:     s,
 		gce:       gce,
 		resources: res,
+		validator: validator,
+		configs:   cs,
+	}
+
+	images := &imageService{
+		registry: rc,
 	}
 
-	return &project{
+	p := &project{
 		logs: &logService{
 			project: name,
 			clock:   time.Now,
@@ -121,13 +209,40 @@ func NewProject(ctx context.Context, name string, opts ...option.ClientOption) (
 			resources: res,
 			health:    hc,
 			apps:      apps,
+			validator: validator,
+			configs:   cs,
+		},
+		images: images,
+		ipLists: &ipListService{
+			project: name,
+			configs: cs,
+			apps:    apps,
 		},
+		validator: validator,
 		name:      name,
 		dm:        dm,
 		gce:       gce,
 		setup:     s,
 		resources: res,
-	}, nil
+		live:      lf,
+	}
+
+	// Machine types are validated using the same list as MachineTypes.
+	validator.machineTypes = p.MachineTypes
+
+	return p, nil
+}
+
+// newManager returns a deployment manager for the given engine.
+func newManager(ctx context.Context, engine Engine, opts ...option.ClientOption) (deployments.Manager, error) {
+	switch engine {
+	case EngineDM:
+		return deployments.NewManager(ctx, opts...)
+	case EngineDirect:
+		return direct.NewManager(ctx, opts...)
+	default:
+		return nil, &UnknownEngineError{Engine: engine}
+	}
 }
 
 type project struct {
@@ -136,10 +251,14 @@ type project struct {
 	secrets   SecretsService
 	apps      *appService
 	releases  *releaseService
+	images    *imageService
+	ipLists   *ipListService
+	validator ConfigValidator
 	dm        deployments.Manager
 	gce       *compute.Service
 	setup     setup.Service
 	resources resources.Builder
+	live      live.Fetcher
 }
 
 func (p *project) Name() string {
@@ -162,6 +281,20 @@ func (p *project) Releases() ReleaseService {
 	return p.releases
 }
 
+func (p *project) Images() ImageService {
+	return p.images
+}
+
+func (p *project) IPLists() IPListService {
+	return p.ipLists
+}
+
+func (p *project) ValidateConfig(
+	ctx context.Context, region string, config *cfg.Config, imageSHA256 string,
+) error {
+	return p.validator.Validate(ctx, region, config, imageSHA256)
+}
+
 // DNSServer is a DNS server run by Google.
 type DNSServer struct {
 	Hostname string
@@ -266,3 +266,16 @@ func TestProject_InstancesAppRelease(t *testing.T) {
 
 	assert.Equal(t, "Instances()", want, got)
 }
+
+func TestNewProject_UnknownEngine(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewProjectWithEngine(
+		context.Background(),
+		"my-project",
+		"carrier-pigeon",
+		option.WithoutAuthentication(),
+	)
+
+	assert.Equal(t, "NewProjectWithEngine() error", &UnknownEngineError{Engine: "carrier-pigeon"}, err)
+}
@@ -2,32 +2,128 @@
 package cfg
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
 	"github.com/ghodss/yaml"
 	"google.golang.org/api/compute/v1"
+	yamlv3 "gopkg.in/yaml.v3"
 
 	// Make the YAML lib a direct dependency so we can get dependabot updates for it.
 	_ "gopkg.in/yaml.v2"
 )
 
-// Config contains all the mutable parameters of an app's configuration.
+// Config contains all the mutable parameters of an app's configuration. It's a single document which
+// contains both the app-level and release-level settings.
 type Config struct {
-	IAMRoles          []string                         `json:"iamRoles,omitempty"`
-	NumReplicas       int                              `json:"numReplicas"`
-	MachineType       string                           `json:"machineType"`
-	Container         Container                        `json:"container"`
-	Sidecars          map[string]Container             `json:"sidecars"`
-	IAP               *compute.BackendServiceIAP       `json:"identityAwareProxy"`
-	AutoscalingPolicy *compute.AutoscalingPolicy       `json:"autoscalingPolicy"`
-	CDNPolicy         *compute.BackendServiceCdnPolicy `json:"cdnPolicy"`
-	Network           string                           `json:"network"`
-	Subnetwork        string                           `json:"subnetwork"`
-	WAFRules          []*compute.SecurityPolicyRule    `json:"wafRules"`
-	SessionAffinity   string                           `json:"sessionAffinity"`
+	APIVersion string `json:"apiVersion"`
+	AppConfig
+	ReleaseConfig
+
+	// Upgraded is true if the configuration was an older version of the format, which was upgraded
+	// to the current version when it was parsed.
+	Upgraded bool `json:"-"`
+}
+
+// AppConfig contains the settings which apply to the app as a whole, and which are changed by
+// updating the app.
+type AppConfig struct {
+	IAMRoles        []string                         `json:"iamRoles,omitempty"`
+	IAP             *compute.BackendServiceIAP       `json:"identityAwareProxy"`
+	CDNPolicy       *compute.BackendServiceCdnPolicy `json:"cdnPolicy"`
+	WAFRules        []*compute.SecurityPolicyRule    `json:"wafRules"`
+	RateLimits      []RateLimit                      `json:"rateLimits,omitempty"`
+	AllowIPs        *IPs                             `json:"allowIPs,omitempty"`
+	DenyIPs         *IPs                             `json:"denyIPs,omitempty"`
+	SessionAffinity string                           `json:"sessionAffinity"`
+	DrainingTimeout *int                             `json:"drainingTimeout,omitempty"` // seconds
+}
+
+// ReleaseConfig contains the settings which apply to a release's instances, and which are changed by
+// creating a new release.
+type ReleaseConfig struct {
+	NumReplicas       int                        `json:"numReplicas"`
+	MachineType       string                     `json:"machineType"`
+	Container         Container                  `json:"container"`
+	Sidecars          map[string]Container       `json:"sidecars"`
+	PinSidecars       *bool                      `json:"pinSidecars,omitempty"`
+	AutoscalingPolicy *compute.AutoscalingPolicy `json:"autoscalingPolicy"`
+	Network           string                     `json:"network"`
+	Subnetwork        string                     `json:"subnetwork"`
+	Disks             *Disks                     `json:"disks,omitempty"`
+	BootImage         string                     `json:"bootImage,omitempty"`
+	Files             []File                     `json:"files,omitempty"`
+}
+
+// Changes returns the JSON names of the fields which differ between the app-level settings and the
+// given ones, in order.
+func (c *AppConfig) Changes(other *AppConfig) []string {
+	return changes(c, other)
+}
+
+// Changes returns the JSON names of the fields which differ between the release-level settings and
+// the given ones, in order.
+func (c *ReleaseConfig) Changes(other *ReleaseConfig) []string {
+	return changes(c, other)
+}
+
+// Validate checks that the release-level settings are complete enough to create a release: the
+// app's image is required, and the sources of files must have been read with ReadFiles. Parse
+// doesn't require them, since app-level commands only use the app-level settings.
+func (c *ReleaseConfig) Validate() error {
+	var problems []*FieldError
+
+	if c.Container.Image == "" {
+		problems = append(problems, &FieldError{Path: "container.image", Err: errRequired})
+	}
+
+	for i, f := range c.Files {
+		if f.Source != "" {
+			problems = append(problems, &FieldError{
+				Path: fmt.Sprintf("files[%d].source", i),
+				Err:  &InvalidFileError{Path: f.Path, Reason: "source must be read with ReadFiles"},
+			})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Errors: problems}
+	}
+
+	return nil
 }
 
+// changes returns the JSON names of the fields which differ between the given structs, which must be
+// pointers to the same type.
+func changes(a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+
+	var names []string
+
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			names = append(names, strings.Split(va.Type().Field(i).Tag.Get("json"), ",")[0])
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// DefaultBootImage is the image family used for app instances if no boot image is specified: the
+// stable channel of Google's Container-Optimized OS. https://cloud.google.com/container-optimized-os/docs/
+const DefaultBootImage = "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/family/cos-stable"
+
+// MaxDrainingTimeout is the longest time, in seconds, the load balancer can wait for connections to
+// drain.
+const MaxDrainingTimeout = 3600
+
 type InvalidSessionAffinityError struct {
 	Value string
 }
@@ -42,29 +138,266 @@ const (
 	SessionAffinityCookie = "cookie"
 )
 
-// Parse loads the given bytes as a YAML configuration.
-func Parse(r io.Reader) (*Config, error) {
+type InvalidDiskTypeError struct {
+	Name  string
+	Value string
+}
+
+func (e *InvalidDiskTypeError) Error() string {
+	return fmt.Sprintf("invalid disk type for %s: %s", e.Name, e.Value)
+}
+
+type UnknownDiskError struct {
+	Container string
+	Disk      string
+}
+
+func (e *UnknownDiskError) Error() string {
+	return fmt.Sprintf("unknown disk for container %s: %s", e.Container, e.Disk)
+}
+
+const (
+	DiskTypeStandard = "pd-standard"
+	DiskTypeBalanced = "pd-balanced"
+	DiskTypeSSD      = "pd-ssd"
+	DiskTypeLocalSSD = "local-ssd"
+)
+
+// Parse loads the given bytes as a YAML configuration, with any given overlays merged over it in
+// order (see Merge). No variables are defined, so any references to them are undefined, but escaped
+// references (see Marshal) are unescaped. If the configuration is invalid, a ValidationError with all
+// of its problems is returned. Problems in overlays are reported with the numbers of the overlays they
+// appear in.
+func Parse(r io.Reader, overlays ...io.Reader) (*Config, error) {
+	config, _, err := ParseWithVariables(r, nil, overlays...)
+
+	return config, err
+}
+
+// ParseWithVariables is like Parse, but interpolates the given variables into the configuration's
+// values (see Resolve). If the configuration refers to any variables, the resolved configuration is
+// also returned as YAML.
+func ParseWithVariables(r io.Reader, vars Variables, overlays ...io.Reader) (*Config, []byte, error) {
 	// Read the configuration.
 	b, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("error reading config: %w", err)
+		return nil, nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	// Read the overlays, if any.
+	o := make([][]byte, len(overlays))
+
+	for i, r := range overlays {
+		o[i], err = io.ReadAll(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading overlay %d: %w", i+1, err)
+		}
+	}
+
+	refs := bytes.Contains(bytes.Join(append(o, b), nil), []byte("${"))
+
+	// Merge the overlays and interpolate variables, keeping the YAML document itself, which has the
+	// location of each field.
+	doc, b, sources, err := resolve(b, vars, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resolved []byte
+	if refs {
+		resolved = b
+	}
+
+	// Upgrade old versions of the configuration format.
+	from, err := migrate(doc, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if from != CurrentVersion {
+		b, err = encode(doc)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Unmarshal from YAML using the YAML->JSON route. This allows us to embed GCP API structs in
 	// our Config struct.
 	var config Config
 	if err := yaml.Unmarshal(b, &config); err != nil {
-		return nil, fmt.Errorf("error parsing config: %w", err)
+		return nil, nil, fmt.Errorf("error parsing config: %w", err)
 	}
 
 	// Validate properties.
-	switch config.SessionAffinity {
-	case "", SessionAffinityCookie, SessionAffinityIP, SessionAffinityNone:
-	default:
-		return nil, &InvalidSessionAffinityError{Value: config.SessionAffinity}
+	if err := validate(&config, doc, sources); err != nil {
+		return nil, nil, err
 	}
 
-	return &config, nil
+	config.Upgraded = from != CurrentVersion
+
+	return &config, resolved, nil
+}
+
+// Marshal returns the given configuration as YAML which Parse reads back as the same configuration.
+// Fields which are null or empty are omitted, and references to variables in values are escaped.
+func Marshal(config *Config) ([]byte, error) {
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+
+	if len(doc.Content) > 0 {
+		prune(doc.Content[0], reflect.TypeOf(config))
+	}
+
+	b, err = encode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.ReplaceAll(string(b), "${", "$${")), nil
+}
+
+// prune removes null or empty struct fields from the given node, a value of the given type, and its
+// children. Map values are only removed if they're null.
+func prune(n *yamlv3.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case n.Kind == yamlv3.MappingNode && t.Kind() == reflect.Struct:
+		fields := jsonFields(t)
+		content := n.Content[:0]
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if isNull(value) || (value.Kind == yamlv3.ScalarNode && value.Value == "") {
+				continue
+			}
+
+			if f, ok := fields[strings.ToLower(key.Value)]; ok {
+				prune(value, f.Type)
+			}
+
+			content = append(content, key, value)
+		}
+
+		n.Content = content
+	case n.Kind == yamlv3.MappingNode && t.Kind() == reflect.Map:
+		content := n.Content[:0]
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if isNull(value) {
+				continue
+			}
+
+			prune(value, t.Elem())
+			content = append(content, key, value)
+		}
+
+		n.Content = content
+	case n.Kind == yamlv3.SequenceNode && t.Kind() == reflect.Slice:
+		for _, e := range n.Content {
+			prune(e, t.Elem())
+		}
+	}
+}
+
+// isNull returns true if the given node is null.
+func isNull(n *yamlv3.Node) bool {
+	return n.Kind == yamlv3.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// checkDisks checks that all disks have unique, valid names, valid types, and valid sizes, and that
+// all volumes which aren't host paths refer to a declared disk.
+func (v *validator) checkDisks(config *Config) {
+	names := map[string]bool{}
+
+	if config.Disks != nil {
+		if err := validateDiskType("boot", config.Disks.BootType, false); err != nil {
+			v.fail("disks.bootType", err)
+		}
+
+		if config.Disks.BootSizeGB < 0 {
+			v.fail("disks.bootSizeGb", &InvalidValueError{Value: config.Disks.BootSizeGB, Reason: "must not be negative"})
+		}
+
+		paths := map[string]string{}
+
+		for i, d := range config.Disks.Extra {
+			path := fmt.Sprintf("disks.extra[%d]", i)
+
+			if err := gcp.ValidateRFC1035(d.Name); err != nil {
+				v.fail(join(path, "name"), err)
+			} else if other, ok := paths[d.Name]; ok {
+				v.fail(join(path, "name"), &InvalidValueError{
+					Value: d.Name, Reason: fmt.Sprintf("duplicate name (also used by %s)", other),
+				})
+			} else {
+				paths[d.Name] = path
+			}
+
+			if err := validateDiskType(d.Name, d.Type, true); err != nil {
+				v.fail(join(path, "type"), err)
+			}
+
+			// Local SSDs are always the same size.
+			if d.Type != DiskTypeLocalSSD && d.SizeGB <= 0 {
+				v.fail(join(path, "sizeGb"), &InvalidValueError{Value: d.SizeGB, Reason: "must be positive"})
+			}
+
+			names[d.Name] = true
+		}
+	}
+
+	v.checkVolumes("container", "app", &config.Container, names)
+
+	sidecars := make([]string, 0, len(config.Sidecars))
+	for name := range config.Sidecars {
+		sidecars = append(sidecars, name)
+	}
+
+	sort.Strings(sidecars)
+
+	for _, name := range sidecars {
+		sidecar := config.Sidecars[name]
+		v.checkVolumes(join("sidecars", name), name, &sidecar, names)
+	}
+}
+
+// checkVolumes checks that all of the container's volumes which aren't host paths refer to one of
+// the given disks.
+func (v *validator) checkVolumes(path, name string, c *Container, disks map[string]bool) {
+	for i, vol := range c.Volumes {
+		if !strings.HasPrefix(vol.Source, "/") && !disks[vol.Source] {
+			v.fail(fmt.Sprintf("%s.volumes[%d].source", path, i),
+				&UnknownDiskError{Container: name, Disk: vol.Source})
+		}
+	}
+}
+
+func validateDiskType(name, diskType string, extra bool) error {
+	switch diskType {
+	case DiskTypeStandard, DiskTypeBalanced, DiskTypeSSD:
+		return nil
+	case "":
+		if !extra {
+			return nil
+		}
+	case DiskTypeLocalSSD:
+		if extra {
+			return nil
+		}
+	}
+
+	return &InvalidDiskTypeError{Name: name, Value: diskType}
 }
 
 // A Container describes all the elements of an app or sidecar container.
@@ -74,4 +407,40 @@ type Container struct {
 	Args          []string          `json:"args"`
 	Env           map[string]string `json:"env"`
 	DockerOptions []string          `json:"dockerOptions"`
+	Volumes       []Volume          `json:"volumes,omitempty"`
+	StopTimeout   int               `json:"stopTimeout,omitempty"` // seconds
+	PreStop       []string          `json:"preStop,omitempty"`
+}
+
+// A Volume describes a bind mount of a disk or host path into a container.
+type Volume struct {
+	// Source is either the name of a disk or an absolute path on the host.
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// Disks describes the boot disk and any additional disks attached to an app's instances.
+type Disks struct {
+	BootSizeGB int64  `json:"bootSizeGb,omitempty"`
+	BootType   string `json:"bootType,omitempty"`
+	Extra      []Disk `json:"extra,omitempty"`
+}
+
+// A Disk describes an additional persistent disk or local SSD which is formatted and mounted on each
+// of an app's instances.
+type Disk struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	SizeGB    int64  `json:"sizeGb,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// MountPoint returns the path on the host where the disk is mounted.
+func (d *Disk) MountPoint() string {
+	if d.MountPath != "" {
+		return d.MountPath
+	}
+
+	return fmt.Sprintf("/mnt/disks/%s", d.Name)
 }
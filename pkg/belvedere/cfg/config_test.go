@@ -1,7 +1,9 @@
 package cfg
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/codahale/gubbins/assert"
@@ -24,71 +26,250 @@ func TestParse(t *testing.T) {
 	}
 
 	want := &Config{
-		MachineType: "n1-standard-1",
-		NumReplicas: 2,
-		Container: Container{
-			Image:         "gcr.io/cloudslap/helloworld",
-			Command:       "ls",
-			Args:          []string{"-al"},
-			Env:           map[string]string{"ONE": "1"},
-			DockerOptions: []string{"--verbose"},
-		},
-		Sidecars: map[string]Container{
-			"nginx-frontend": {
-				Image: "gcr.io/cloudslap/nginx-frontend",
+		APIVersion: CurrentVersion,
+		AppConfig: AppConfig{
+			IAP: &compute.BackendServiceIAP{
+				Enabled:            true,
+				Oauth2ClientId:     "client-id",
+				Oauth2ClientSecret: "secret-id",
 			},
-		},
-		AutoscalingPolicy: &compute.AutoscalingPolicy{
-			MinNumReplicas:    1,
-			MaxNumReplicas:    10,
-			CoolDownPeriodSec: 60,
-			CpuUtilization: &compute.AutoscalingPolicyCpuUtilization{
-				UtilizationTarget: 0.6,
+			CDNPolicy: &compute.BackendServiceCdnPolicy{
+				CacheKeyPolicy: &compute.CacheKeyPolicy{
+					IncludeProtocol:      true,
+					IncludeHost:          true,
+					IncludeQueryString:   false,
+					QueryStringWhitelist: []string{"q"},
+					QueryStringBlacklist: []string{"id"},
+				},
+				SignedUrlKeyNames:       []string{"one"},
+				SignedUrlCacheMaxAgeSec: 200,
 			},
-			CustomMetricUtilizations: []*compute.AutoscalingPolicyCustomMetricUtilization{
+			IAMRoles: []string{"roles/cloudkms.cryptoKeyDecrypter"},
+			WAFRules: []*compute.SecurityPolicyRule{
 				{
-					Metric:                "www.googleapis.com/compute/instance/network/received_bytes_count",
-					UtilizationTargetType: "GAUGE",
-					UtilizationTarget:     200,
+					Action:      "deny(403)",
+					Description: "Prevent XSS attacks.",
+					Match: &compute.SecurityPolicyRuleMatcher{
+						Expr: &compute.Expr{
+							Expression: "evaluatePreconfiguredExpr('xss-stable')",
+						},
+					},
+					Priority: 2000,
 				},
 			},
-			LoadBalancingUtilization: &compute.AutoscalingPolicyLoadBalancingUtilization{
-				UtilizationTarget: 0.6,
+			RateLimits: []RateLimit{
+				{
+					Description:    "Ban credential stuffing.",
+					Paths:          []string{"^/login$"},
+					Requests:       10,
+					IntervalSec:    60,
+					BanDurationSec: 600,
+				},
 			},
+			SessionAffinity: "none",
 		},
-		IAP: &compute.BackendServiceIAP{
-			Enabled:            true,
-			Oauth2ClientId:     "client-id",
-			Oauth2ClientSecret: "secret-id",
-		},
-		CDNPolicy: &compute.BackendServiceCdnPolicy{
-			CacheKeyPolicy: &compute.CacheKeyPolicy{
-				IncludeProtocol:      true,
-				IncludeHost:          true,
-				IncludeQueryString:   false,
-				QueryStringWhitelist: []string{"q"},
-				QueryStringBlacklist: []string{"id"},
+		ReleaseConfig: ReleaseConfig{
+			MachineType: "n1-standard-1",
+			NumReplicas: 2,
+			Container: Container{
+				Image:         "gcr.io/cloudslap/helloworld",
+				Command:       "ls",
+				Args:          []string{"-al"},
+				Env:           map[string]string{"ONE": "1"},
+				DockerOptions: []string{"--verbose"},
+				Volumes: []Volume{
+					{
+						Source: "scratch",
+						Target: "/var/cache",
+					},
+				},
 			},
-			SignedUrlKeyNames:       []string{"one"},
-			SignedUrlCacheMaxAgeSec: 200,
-		},
-		IAMRoles: []string{"roles/cloudkms.cryptoKeyDecrypter"},
-		WAFRules: []*compute.SecurityPolicyRule{
-			{
-				Action:      "deny(403)",
-				Description: "Prevent XSS attacks.",
-				Match: &compute.SecurityPolicyRuleMatcher{
-					Expr: &compute.Expr{
-						Expression: "evaluatePreconfiguredExpr('xss-stable')",
+			Sidecars: map[string]Container{
+				"nginx-frontend": {
+					Image: "gcr.io/cloudslap/nginx-frontend",
+				},
+			},
+			AutoscalingPolicy: &compute.AutoscalingPolicy{
+				MinNumReplicas:    1,
+				MaxNumReplicas:    10,
+				CoolDownPeriodSec: 60,
+				CpuUtilization: &compute.AutoscalingPolicyCpuUtilization{
+					UtilizationTarget: 0.6,
+				},
+				CustomMetricUtilizations: []*compute.AutoscalingPolicyCustomMetricUtilization{
+					{
+						Metric:                "www.googleapis.com/compute/instance/network/received_bytes_count",
+						UtilizationTargetType: "GAUGE",
+						UtilizationTarget:     200,
 					},
 				},
-				Priority: 1,
+				LoadBalancingUtilization: &compute.AutoscalingPolicyLoadBalancingUtilization{
+					UtilizationTarget: 0.6,
+				},
 			},
+			Network:    "projects/project/global/networks/network",
+			Subnetwork: "regions/region/subnetworks/subnetwork",
+			Disks: &Disks{
+				BootSizeGB: 20,
+				BootType:   "pd-ssd",
+				Extra: []Disk{
+					{
+						Name: "scratch",
+						Type: "local-ssd",
+					},
+				},
+			},
+			BootImage: "projects/cos-cloud/global/images/cos-89-16108-403-15",
 		},
-		SessionAffinity: "none",
-		Network:         "projects/project/global/networks/network",
-		Subnetwork:      "regions/region/subnetworks/subnetwork",
 	}
 
 	assert.Equal(t, "Parse()", want, got)
 }
+
+func TestParse_InvalidDiskType(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+disks:
+  bootType: local-ssd
+`))
+
+	want := &ValidationError{
+		Errors: []*FieldError{
+			{
+				Path: "disks.bootType",
+				Line: 5,
+				Err:  &InvalidDiskTypeError{Name: "boot", Value: "local-ssd"},
+			},
+		},
+	}
+
+	assert.Equal(t, "Parse() error", want, err)
+}
+
+func TestParse_InvalidDisks(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+disks:
+  bootSizeGb: -10
+  extra:
+    - name: data
+      type: pd-ssd
+      sizeGb: 100
+    - name: data
+      type: pd-ssd
+      sizeGb: 0
+    - name: Scratch
+      type: local-ssd
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 5: disks.bootSizeGb: must not be negative: -10
+  line 10: disks.extra[1].name: duplicate name (also used by disks.extra[0]): "data"
+  line 12: disks.extra[1].sizeGb: must be positive: 0
+  line 13: disks.extra[2].name: invalid name: "Scratch"`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
+
+func TestParse_UnknownDisk(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+  volumes:
+    - source: scratch
+      target: /var/cache
+`))
+
+	want := &ValidationError{
+		Errors: []*FieldError{
+			{
+				Path: "container.volumes[0].source",
+				Line: 5,
+				Err:  &UnknownDiskError{Container: "app", Disk: "scratch"},
+			},
+		},
+	}
+
+	assert.Equal(t, "Parse() error", want, err)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("config-example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	want, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want.Container.Command = "echo ${HOME}"
+	want.Container.Env["EMPTY"] = ""
+
+	b, err := Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Marshal()", want, got)
+}
+
+func TestAppConfig_Changes(t *testing.T) {
+	t.Parallel()
+
+	a := &AppConfig{
+		IAMRoles:        []string{"roles/logging.logWriter"},
+		SessionAffinity: SessionAffinityIP,
+	}
+	b := &AppConfig{
+		IAMRoles:        []string{"roles/logging.logWriter"},
+		SessionAffinity: SessionAffinityCookie,
+		DrainingTimeout: new(int),
+	}
+
+	assert.Equal(t, "Changes()", []string{"drainingTimeout", "sessionAffinity"}, a.Changes(b))
+	assert.Equal(t, "Changes()", []string(nil), a.Changes(a))
+}
+
+func TestReleaseConfig_Changes(t *testing.T) {
+	t.Parallel()
+
+	a := &ReleaseConfig{
+		NumReplicas: 2,
+		Container: Container{
+			Image: "gcr.io/my-project/my-app",
+		},
+	}
+	b := &ReleaseConfig{
+		NumReplicas: 2,
+		Container: Container{
+			Image: "gcr.io/my-project/my-app",
+			Env:   map[string]string{"ONE": "1"},
+		},
+	}
+
+	assert.Equal(t, "Changes()", []string{"container"}, a.Changes(b))
+}
@@ -0,0 +1,75 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A File describes a file which is written onto each of an app's instances. Its contents are either
+// given inline or read from a local file when a release is created. Files can be bound into
+// containers using volumes with the file's path as the source.
+type File struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Content string `json:"content,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+type InvalidFileError struct {
+	Path   string
+	Reason string
+}
+
+func (e *InvalidFileError) Error() string {
+	return fmt.Sprintf("invalid file %s: %s", e.Path, e.Reason)
+}
+
+// checkFiles checks that all files have absolute paths, octal modes, and at most one source of
+// content.
+func (v *validator) checkFiles(config *Config) {
+	for i, f := range config.Files {
+		if !strings.HasPrefix(f.Path, "/") {
+			v.fail(fmt.Sprintf("files[%d].path", i), &InvalidFileError{Path: f.Path, Reason: "path must be absolute"})
+		}
+
+		if f.Mode != "" && !fileModeFormat.MatchString(f.Mode) {
+			v.fail(fmt.Sprintf("files[%d].mode", i),
+				&InvalidFileError{Path: f.Path, Reason: fmt.Sprintf("mode %q must be octal (e.g. 0644)", f.Mode)})
+		}
+
+		if f.Content != "" && f.Source != "" {
+			v.fail(fmt.Sprintf("files[%d]", i),
+				&InvalidFileError{Path: f.Path, Reason: "content and source are mutually exclusive"})
+		}
+	}
+}
+
+// ReadFiles replaces the source of each file with the contents of the local file it refers to.
+// Relative sources are resolved against the given directory. Files with sources must be read before
+// a release is created; see ReleaseConfig.Validate.
+func (c *Config) ReadFiles(dir string) error {
+	for i := range c.Files {
+		f := &c.Files[i]
+		if f.Source == "" {
+			continue
+		}
+
+		path := f.Source
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file for %s: %w", f.Path, err)
+		}
+
+		f.Content = string(b)
+		f.Source = ""
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestConfig_ReadFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("worker_processes 1;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		ReleaseConfig: ReleaseConfig{
+			Files: []File{
+				{
+					Path:   "/etc/nginx/nginx.conf",
+					Source: "nginx.conf",
+				},
+				{
+					Path:    "/etc/motd",
+					Content: "hello",
+				},
+			},
+		},
+	}
+
+	if err := config.ReadFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []File{
+		{
+			Path:    "/etc/nginx/nginx.conf",
+			Content: "worker_processes 1;\n",
+		},
+		{
+			Path:    "/etc/motd",
+			Content: "hello",
+		},
+	}
+
+	assert.Equal(t, "ReadFiles()", want, config.Files)
+}
+
+func TestParse_InvalidFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+files:
+  - path: /etc/motd
+    content: hello
+    source: motd.txt
+  - path: /etc/issue
+    mode: rw-r--r--
+    content: hello
+`))
+
+	want := &ValidationError{
+		Errors: []*FieldError{
+			{
+				Path: "files[0]",
+				Line: 5,
+				Err:  &InvalidFileError{Path: "/etc/motd", Reason: "content and source are mutually exclusive"},
+			},
+			{
+				Path: "files[1].mode",
+				Line: 9,
+				Err:  &InvalidFileError{Path: "/etc/issue", Reason: `mode "rw-r--r--" must be octal (e.g. 0644)`},
+			},
+		},
+	}
+
+	assert.Equal(t, "Parse() error", want, err)
+}
+
+func TestReleaseConfig_Validate_UnreadFiles(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+files:
+  - path: /etc/motd
+    source: motd.txt
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ValidationError{
+		Errors: []*FieldError{
+			{
+				Path: "files[0].source",
+				Err:  &InvalidFileError{Path: "/etc/motd", Reason: "source must be read with ReadFiles"},
+			},
+		},
+	}
+
+	assert.Equal(t, "Validate()", want, config.ReleaseConfig.Validate())
+}
@@ -0,0 +1,125 @@
+package cfg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variables are the values of the variables which are interpolated into a configuration, keyed by
+// name.
+type Variables map[string]string
+
+// An UndefinedVariableError is returned when a configuration refers to a variable which isn't
+// defined and has no default.
+type UndefinedVariableError struct {
+	Name string
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable: %s", e.Name)
+}
+
+//nolint:gochecknoglobals // can't have const regexps
+var variableFormat = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Resolve merges the given overlays over the given base YAML configuration (see Merge) and
+// interpolates variables into its values, returning the result. ${NAME} is replaced with the value
+// of the variable NAME, which must be defined; ${NAME:-default} is replaced with the value of NAME,
+// or default if NAME is undefined or empty; and $${ is replaced with ${. If any variables are
+// undefined, a ValidationError is returned.
+func Resolve(base []byte, vars Variables, overlays ...[]byte) ([]byte, error) {
+	_, b, _, err := resolve(base, vars, overlays)
+
+	return b, err
+}
+
+// resolve merges the overlays over the base configuration and interpolates variables into it,
+// returning the parsed YAML document, its encoded form, and the numbers of the overlays which its
+// nodes came from (see mergeDocs).
+func resolve(
+	base []byte, vars Variables, overlays [][]byte,
+) (*yaml.Node, []byte, map[*yaml.Node]int, error) {
+	doc, sources, err := mergeDocs(base, overlays)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	b := base
+	if len(overlays) > 0 {
+		b, err = encode(doc)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Leave the configuration as-is unless it refers to variables.
+	if !bytes.Contains(b, []byte("${")) {
+		return doc, b, sources, nil
+	}
+
+	v := &validator{sources: sources}
+	v.interpolate(doc, "", vars)
+
+	if len(v.errors) > 0 {
+		return nil, nil, nil, &ValidationError{Errors: v.errors}
+	}
+
+	b, err = encode(doc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return doc, b, sources, nil
+}
+
+// interpolate replaces references to variables in the values of the given node and its children
+// with the values of those variables (see Resolve), recording any undefined variables. Keys are left
+// as-is.
+func (v *validator) interpolate(n *yaml.Node, path string, vars Variables) {
+	switch n.Kind { //nolint:exhaustive // aliases refer to nodes which are interpolated elsewhere
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			v.interpolate(c, path, vars)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.interpolate(n.Content[i+1], join(path, n.Content[i].Value), vars)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			v.interpolate(c, fmt.Sprintf("%s[%d]", path, i), vars)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return
+		}
+
+		n.Value = variableFormat.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if ref == "$${" {
+				return "${"
+			}
+
+			m := variableFormat.FindStringSubmatch(ref)
+			name, hasDefault, def := m[1], m[2] != "", m[3]
+
+			if s, ok := vars[name]; ok && (s != "" || !hasDefault) {
+				return s
+			}
+
+			if !hasDefault {
+				v.failAt(path, n, &UndefinedVariableError{Name: name})
+			}
+
+			return def
+		})
+
+		// Re-resolve the types of unquoted values (e.g. numReplicas: ${REPLICAS}).
+		if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			n.Tag = ""
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestParseWithVariables(t *testing.T) {
+	t.Parallel()
+
+	config, resolved, err := ParseWithVariables(strings.NewReader(`
+numReplicas: ${REPLICAS}
+container:
+  image: gcr.io/${project}/${app}
+  command: "echo $${HOME}"
+  env:
+    BUILD: "${BUILD_NUMBER}"
+    REGION: ${region}
+    LEVEL: ${LOG_LEVEL:-info}
+    EMPTY: ${EMPTY:-default}
+`), Variables{
+		"project":      "my-project",
+		"app":          "my-app",
+		"region":       "us-west1",
+		"REPLICAS":     "3",
+		"BUILD_NUMBER": "1234",
+		"EMPTY":        "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "ParseWithVariables()",
+		&Config{
+			APIVersion: CurrentVersion,
+			ReleaseConfig: ReleaseConfig{
+				NumReplicas: 3,
+				Container: Container{
+					Image:   "gcr.io/my-project/my-app",
+					Command: "echo ${HOME}",
+					Env: map[string]string{
+						"BUILD":  "1234",
+						"REGION": "us-west1",
+						"LEVEL":  "info",
+						"EMPTY":  "default",
+					},
+				},
+			},
+			Upgraded: true,
+		},
+		config)
+
+	want := `numReplicas: 3
+container:
+  image: gcr.io/my-project/my-app
+  command: "echo ${HOME}"
+  env:
+    BUILD: "1234"
+    REGION: us-west1
+    LEVEL: info
+    EMPTY: default
+`
+
+	assert.Equal(t, "ParseWithVariables() resolved", want, string(resolved))
+}
+
+func TestParseWithVariables_NoReferences(t *testing.T) {
+	t.Parallel()
+
+	_, resolved, err := ParseWithVariables(strings.NewReader(`container:
+  image: gcr.io/my-project/my-app
+`), Variables{"project": "my-project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "ParseWithVariables() resolved", []byte(nil), resolved)
+}
+
+func TestParse_UndefinedVariables(t *testing.T) {
+	// The library doesn't look variables up in the process environment.
+	t.Setenv("BELVEDERE_UNDEFINED_PROJECT", "my-project")
+
+	_, err := Parse(strings.NewReader(`numReplicas: 2
+container:
+  image: gcr.io/${BELVEDERE_UNDEFINED_PROJECT}/my-app
+  args:
+    - one
+    - ${BELVEDERE_UNDEFINED_ARG}
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 3: container.image: undefined variable: BELVEDERE_UNDEFINED_PROJECT
+  line 6: container.args[1]: undefined variable: BELVEDERE_UNDEFINED_ARG`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	got, err := Resolve([]byte(`numReplicas: 2
+container:
+  image: gcr.io/my-project/my-app:${tag}
+`), Variables{"tag": "v1.2.3", "REPLICAS": "10"}, []byte(`numReplicas: ${REPLICAS}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app:v1.2.3
+`
+
+	assert.Equal(t, "Resolve()", want, string(got))
+}
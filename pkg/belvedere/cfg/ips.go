@@ -0,0 +1,116 @@
+package cfg
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+)
+
+const (
+	// MaxIPRules is the maximum number of WAF rules an app's allowed or denied IPs can each be split
+	// into.
+	MaxIPRules = 1000
+	// DenyIPRulePriority is the priority of the first WAF rule for the app's denied IPs. The rules for
+	// the rest follow it, after the built-in health check rule and before all other rules, so that no
+	// other rule can allow a denied IP.
+	DenyIPRulePriority = HealthCheckRulePriority + 1
+	// AllowIPRulePriority is the priority of the first WAF rule for the app's allowed IPs. The rules
+	// for the rest follow it, after all other rules and before the default rule, which denies all
+	// other requests if the app has allowed IPs.
+	AllowIPRulePriority = DefaultRulePriority - MaxIPRules
+	// MaxRangesPerRule is the maximum number of IP ranges Cloud Armor allows in a single WAF rule.
+	MaxRangesPerRule = 10
+)
+
+// IPs are a set of client IP addresses, given as ranges (e.g. 203.0.113.0/24 or 198.51.100.7) and
+// as the names of the project's IP lists.
+type IPs struct {
+	Ranges []string `json:"ranges,omitempty"`
+	Lists  []string `json:"lists,omitempty"`
+}
+
+// IPLists returns the names of the project IP lists which the app's allowed and denied IPs refer to,
+// in order.
+func (c *AppConfig) IPLists() []string {
+	names := map[string]bool{}
+
+	for _, ips := range []*IPs{c.AllowIPs, c.DenyIPs} {
+		if ips == nil {
+			continue
+		}
+
+		for _, name := range ips.Lists {
+			names[name] = true
+		}
+	}
+
+	lists := make([]string, 0, len(names))
+	for name := range names {
+		lists = append(lists, name)
+	}
+
+	sort.Strings(lists)
+
+	return lists
+}
+
+//nolint:gochecknoglobals // can't have const errors
+var errAllowIPsWithRateLimits = fmt.Errorf("not allowed with rate limits, which allow requests under the limit")
+
+type InvalidIPRangeError struct {
+	Range string
+}
+
+func (e *InvalidIPRangeError) Error() string {
+	return fmt.Sprintf("invalid IP range: %q", e.Range)
+}
+
+// ValidateIPRange returns an error if the given string isn't an IP address or a CIDR range of IP
+// addresses.
+func ValidateIPRange(s string) error {
+	if strings.Contains(s, "/") {
+		if _, _, err := net.ParseCIDR(s); err == nil {
+			return nil
+		}
+	} else if net.ParseIP(s) != nil {
+		return nil
+	}
+
+	return &InvalidIPRangeError{Range: s}
+}
+
+// checkIPs checks that all allowed and denied IPs are valid ranges or the names of IP lists, and that
+// the app doesn't have both allowed IPs and rate limits.
+func (v *validator) checkIPs(config *Config) {
+	// Requests which conform to a rate limit are allowed before the allowed IPs are checked.
+	if config.AllowIPs != nil && len(config.RateLimits) > 0 {
+		v.fail("allowIPs", errAllowIPsWithRateLimits)
+	}
+
+	for _, f := range []struct {
+		path string
+		ips  *IPs
+	}{
+		{"allowIPs", config.AllowIPs},
+		{"denyIPs", config.DenyIPs},
+	} {
+		if f.ips == nil {
+			continue
+		}
+
+		for i, r := range f.ips.Ranges {
+			if err := ValidateIPRange(r); err != nil {
+				v.fail(fmt.Sprintf("%s.ranges[%d]", f.path, i), err)
+			}
+		}
+
+		for i, name := range f.ips.Lists {
+			if err := gcp.ValidateRFC1035(name); err != nil {
+				v.fail(fmt.Sprintf("%s.lists[%d]", f.path, i), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestAppConfig_IPLists(t *testing.T) {
+	t.Parallel()
+
+	config := &AppConfig{
+		AllowIPs: &IPs{
+			Ranges: []string{"203.0.113.0/24"},
+			Lists:  []string{"office", "vpn"},
+		},
+		DenyIPs: &IPs{
+			Lists: []string{"bad-actors", "vpn"},
+		},
+	}
+
+	assert.Equal(t, "IPLists()", []string{"bad-actors", "office", "vpn"}, config.IPLists())
+}
+
+func TestParse_InvalidIPs(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`container:
+  image: gcr.io/my-project/my-app
+wafRules:
+  - action: deny(403)
+    priority: 2147483000
+allowIPs:
+  ranges: ["203.0.113.0/24", "2001:db8::/32", "198.51.100.7", "198.51.100.0/33"]
+  lists: [office]
+denyIPs:
+  ranges: ["bad"]
+  lists: [Bad_Actors]
+rateLimits:
+  - requests: 10
+    intervalSec: 60
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 5: wafRules[0].priority: must be between 1002 and 2147482546: 2147483000
+  line 6: allowIPs: not allowed with rate limits, which allow requests under the limit
+  line 7: allowIPs.ranges[3]: invalid IP range: "198.51.100.0/33"
+  line 10: denyIPs.ranges[0]: invalid IP range: "bad"
+  line 11: denyIPs.lists[0]: invalid name: "Bad_Actors"`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
@@ -0,0 +1,216 @@
+package cfg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the configuration format which Config represents.
+const CurrentVersion = "belvedere/v2"
+
+// A Migration upgrades a configuration from one version of the format to the next.
+type Migration struct {
+	// From is the version which the migration upgrades from. Configurations without an apiVersion
+	// field have the empty version.
+	From string
+	// To is the version which the migration upgrades to.
+	To string
+	// Migrate modifies the given configuration, a YAML mapping, to match the new version. Its
+	// apiVersion field is updated afterwards.
+	Migrate func(config *yaml.Node) error
+}
+
+// migrations are the migrations between each version of the configuration format and the next, in
+// order. When the format changes in a way which would break existing configurations, add a new
+// version, a migration to it, and update CurrentVersion.
+//nolint:gochecknoglobals // can't have const slices
+var migrations = []Migration{
+	{
+		// Configurations written before the format was versioned are otherwise the same as v1.
+		From:    "",
+		To:      "belvedere/v1",
+		Migrate: func(*yaml.Node) error { return nil },
+	},
+	{
+		// In v1, WAF rules could have any priority which wasn't reserved for Belvedere's own rules, and
+		// were evaluated before the denied IPs. In v2, they're evaluated after the denied IPs, so their
+		// priorities are offset to follow them.
+		From:    "belvedere/v1",
+		To:      "belvedere/v2",
+		Migrate: offsetWAFRulePriorities,
+	},
+}
+
+type UnmigratablePriorityError struct {
+	Path  string
+	Value string
+}
+
+func (e *UnmigratablePriorityError) Error() string {
+	return fmt.Sprintf("%s: can't offset non-numeric priority %q by %d", e.Path, e.Value, WAFRulePriority)
+}
+
+// offsetWAFRulePriorities adds WAFRulePriority to the priority of each of the configuration's WAF rules,
+// which keeps them in the same order. Rules without priorities had a priority of zero, so they're given
+// one explicitly. Every priority which was valid in v1 is valid in v2 once offset. Priorities which
+// aren't numbers (e.g. references to variables) can't be offset.
+func offsetWAFRulePriorities(config *yaml.Node) error {
+	rules := field(config, "wafRules")
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	for i, rule := range rules.Content {
+		if rule.Kind != yaml.MappingNode {
+			continue
+		}
+
+		priority := field(rule, "priority")
+		if priority == nil {
+			rule.Content = append(rule.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "priority"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(WAFRulePriority)},
+			)
+
+			continue
+		}
+
+		p, err := strconv.ParseInt(priority.Value, 10, 64)
+		if err != nil {
+			return &UnmigratablePriorityError{Path: fmt.Sprintf("wafRules[%d].priority", i), Value: priority.Value}
+		}
+
+		// Leave priorities which weren't valid in v1 as-is, for validation to report.
+		if p < 0 || p+WAFRulePriority > MaxWAFRulePriority {
+			continue
+		}
+
+		priority.Value = strconv.FormatInt(p+WAFRulePriority, 10)
+	}
+
+	return nil
+}
+
+// An UnsupportedVersionError is returned when a configuration has a version of the format which
+// can't be migrated to the current version.
+type UnsupportedVersionError struct {
+	Version string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported version: %q", e.Version)
+}
+
+// Migrate upgrades the given YAML configuration to the current version of the format, keeping its
+// comments, and returns the result. If the configuration is already the current version, it's
+// returned as-is. Variables aren't interpolated.
+func Migrate(b []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	from, err := migrate(&doc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if from == CurrentVersion {
+		return b, nil
+	}
+
+	return encode(&doc)
+}
+
+// migrate upgrades the given YAML document to the current version of the format in place and
+// returns the version it was. The document's nodes which came from overlays are mapped to the
+// numbers of those overlays. Warning about old versions is left to the caller, which has the context
+// to report it in.
+func migrate(doc *yaml.Node, sources map[*yaml.Node]int) (string, error) {
+	// Leave empty documents as-is.
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return CurrentVersion, nil
+	}
+
+	config := doc.Content[0]
+	from, node := version(config)
+	v := from
+
+	for v != CurrentVersion {
+		m := findMigration(v)
+		if m == nil {
+			fv := &validator{sources: sources}
+			fv.failAt("apiVersion", node, &UnsupportedVersionError{Version: v})
+
+			return "", &ValidationError{Errors: fv.errors}
+		}
+
+		if err := m.Migrate(config); err != nil {
+			return "", fmt.Errorf("error migrating config from %q to %q: %w", m.From, m.To, err)
+		}
+
+		setVersion(config, m.To)
+		v = m.To
+	}
+
+	return from, nil
+}
+
+// findMigration returns the migration from the given version, if any.
+func findMigration(version string) *Migration {
+	for i := range migrations {
+		if migrations[i].From == version {
+			return &migrations[i]
+		}
+	}
+
+	return nil
+}
+
+// version returns the value of the given configuration's apiVersion field and its node, if any.
+func version(config *yaml.Node) (string, *yaml.Node) {
+	node := field(config, "apiVersion")
+	if node == nil {
+		return "", nil
+	}
+
+	return node.Value, node
+}
+
+// field returns the value of the given field of the given mapping, if any. Like Parse, it matches
+// field names regardless of case.
+func field(mapping *yaml.Node, name string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, name) {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// setVersion sets the value of the given configuration's apiVersion field, adding it as the first
+// field if it's missing.
+func setVersion(config *yaml.Node, v string) {
+	if node := field(config, "apiVersion"); node != nil {
+		node.Value = v
+		node.Tag = "!!str"
+
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"}
+
+	// Keep any comments at the top of the configuration above the new field.
+	if len(config.Content) > 0 {
+		key.HeadComment, config.Content[0].HeadComment = config.Content[0].HeadComment, ""
+	}
+
+	config.Content = append([]*yaml.Node{
+		key,
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: v},
+	}, config.Content...)
+}
@@ -0,0 +1,141 @@
+package cfg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	got, err := Migrate([]byte(`# yaml-language-server: $schema=./belvedere.schema.json
+
+numReplicas: 2 # two is plenty
+container:
+  # The app itself.
+  image: gcr.io/my-project/my-app:${tag}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# yaml-language-server: $schema=./belvedere.schema.json
+
+apiVersion: belvedere/v2
+numReplicas: 2 # two is plenty
+container:
+  # The app itself.
+  image: gcr.io/my-project/my-app:${tag}
+`
+
+	assert.Equal(t, "Migrate()", want, string(got))
+}
+
+func TestMigrate_WAFRulePriorities(t *testing.T) {
+	t.Parallel()
+
+	got, err := Migrate([]byte(`apiVersion: belvedere/v1
+wafRules:
+  - action: deny(403)
+    priority: 1000 # after the other rule
+  - action: deny(404)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: belvedere/v2
+wafRules:
+  - action: deny(403)
+    priority: 2002 # after the other rule
+  - action: deny(404)
+    priority: 1002
+`
+
+	assert.Equal(t, "Migrate()", want, string(got))
+}
+
+func TestMigrate_VariablePriority(t *testing.T) {
+	t.Parallel()
+
+	_, err := Migrate([]byte(`apiVersion: belvedere/v1
+wafRules:
+  - action: deny(403)
+    priority: ${priority}
+`))
+	if err == nil {
+		t.Fatal("Migrate() = nil, want an error")
+	}
+
+	assert.Equal(t, "Migrate() error",
+		`error migrating config from "belvedere/v1" to "belvedere/v2": `+
+			`wafRules[0].priority: can't offset non-numeric priority "${priority}" by 1002`,
+		err.Error())
+}
+
+func TestMigrate_Current(t *testing.T) {
+	t.Parallel()
+
+	config := `apiVersion:   belvedere/v2
+numReplicas:  2
+container: {image: gcr.io/my-project/my-app}
+`
+
+	got, err := Migrate([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Migrate()", config, string(got))
+}
+
+func TestMigrate_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := Migrate([]byte(`numReplicas: 2
+apiVersion: belvedere/v99
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Migrate() error = %v, want a ValidationError", err)
+	}
+
+	var unsupported *UnsupportedVersionError
+	if !errors.As(got.Errors[0], &unsupported) {
+		t.Fatalf("Migrate() error = %v, want an UnsupportedVersionError", got.Errors[0])
+	}
+
+	want := `invalid config:
+  line 2: apiVersion: unsupported version: "belvedere/v99"`
+
+	assert.Equal(t, "Migrate() error", want, got.Error())
+}
+
+func TestParse_OldVersion(t *testing.T) {
+	t.Parallel()
+
+	got, err := Parse(strings.NewReader(`numReplicas: 2
+container:
+  image: gcr.io/my-project/my-app
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Parse()",
+		&Config{
+			APIVersion: CurrentVersion,
+			ReleaseConfig: ReleaseConfig{
+				NumReplicas: 2,
+				Container: Container{
+					Image: "gcr.io/my-project/my-app",
+				},
+			},
+			Upgraded: true,
+		},
+		got)
+}
@@ -0,0 +1,130 @@
+package cfg
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//nolint:gochecknoglobals // can't have const errors
+var errOverlayNotMapping = fmt.Errorf("not a mapping")
+
+// Merge deep-merges the given overlays, in order, over the given base YAML configuration and returns
+// the result. Mappings (e.g. env and sidecars) are merged key by key; all other values, including
+// lists, are replaced. A key can be removed by setting it to null in an overlay. If there are no
+// overlays, the base configuration is returned as-is.
+func Merge(base []byte, overlays ...[]byte) ([]byte, error) {
+	if len(overlays) == 0 {
+		return base, nil
+	}
+
+	doc, _, err := mergeDocs(base, overlays)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(doc)
+}
+
+// mergeDocs parses the base configuration and the overlays and merges the overlays over it, returning
+// the merged YAML document. The merged document keeps the original nodes, and so their lines, and the
+// nodes which came from overlays are mapped to the numbers of those overlays, starting at 1.
+func mergeDocs(base []byte, overlays [][]byte) (*yaml.Node, map[*yaml.Node]int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(base, &doc); err != nil {
+		return nil, nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	sources := map[*yaml.Node]int{}
+
+	for i, b := range overlays {
+		var overlay yaml.Node
+		if err := yaml.Unmarshal(b, &overlay); err != nil {
+			return nil, nil, fmt.Errorf("error parsing overlay %d: %w", i+1, err)
+		}
+
+		// Skip empty overlays.
+		if len(overlay.Content) == 0 {
+			continue
+		}
+
+		if overlay.Content[0].Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("error parsing overlay %d: %w", i+1, errOverlayNotMapping)
+		}
+
+		markSource(overlay.Content[0], i+1, sources)
+
+		if len(doc.Content) == 0 {
+			doc = overlay
+
+			continue
+		}
+
+		doc.Content[0] = merge(doc.Content[0], overlay.Content[0])
+	}
+
+	return &doc, sources, nil
+}
+
+// markSource maps the given node and its children to the given overlay number.
+func markSource(n *yaml.Node, overlay int, sources map[*yaml.Node]int) {
+	sources[n] = overlay
+
+	for _, c := range n.Content {
+		markSource(c, overlay, sources)
+	}
+}
+
+// encode returns the given YAML document as YAML.
+func encode(doc *yaml.Node) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// merge returns the src node merged over the dst node. If both are mappings, the values of src's
+// keys are recursively merged over dst's values and keys with null values are removed; otherwise, src
+// replaces dst.
+func merge(dst, src *yaml.Node) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		remove := value.Kind == yaml.ScalarNode && value.ShortTag() == "!!null"
+		found := false
+
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				if remove {
+					dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+				} else {
+					dst.Content[j+1] = merge(dst.Content[j+1], value)
+				}
+
+				found = true
+
+				break
+			}
+		}
+
+		if !found && !remove {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,143 @@
+package cfg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	got, err := Merge([]byte(`# The base config.
+numReplicas: 2
+container:
+  image: gcr.io/my-project/my-app
+  args: ["one", "two"]
+  env:
+    ONE: "1"
+    TWO: "2"
+sidecars:
+  nginx:
+    image: nginx:1.19
+`), []byte(`numReplicas: 10
+container:
+  args: ["three"]
+  env:
+    TWO: "two"
+    THREE: "3"
+`), []byte(``), []byte(`container:
+  env:
+    ONE: null
+sidecars:
+  envoy:
+    image: envoyproxy/envoy:v1.18
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# The base config.
+numReplicas: 10
+container:
+  image: gcr.io/my-project/my-app
+  args: ["three"]
+  env:
+    TWO: "two"
+    THREE: "3"
+sidecars:
+  nginx:
+    image: nginx:1.19
+  envoy:
+    image: envoyproxy/envoy:v1.18
+`
+
+	assert.Equal(t, "Merge()", want, string(got))
+}
+
+func TestMerge_NoOverlays(t *testing.T) {
+	t.Parallel()
+
+	base := []byte("numReplicas:    2\n")
+
+	got, err := Merge(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Merge()", string(base), string(got))
+}
+
+func TestMerge_NotMapping(t *testing.T) {
+	t.Parallel()
+
+	_, err := Merge([]byte("numReplicas: 2\n"), []byte("- one\n- two\n"))
+	if !errors.Is(err, errOverlayNotMapping) {
+		t.Errorf("Merge() error = %v, want %v", err, errOverlayNotMapping)
+	}
+}
+
+func TestParse_Overlays(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+numReplicas: 2
+container:
+  image: gcr.io/my-project/my-app
+  env:
+    ONE: "1"
+`), strings.NewReader(`
+numReplicas: 10
+container:
+  env:
+    TWO: "2"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Parse()",
+		&Config{
+			APIVersion: CurrentVersion,
+			ReleaseConfig: ReleaseConfig{
+				NumReplicas: 10,
+				Container: Container{
+					Image: "gcr.io/my-project/my-app",
+					Env:   map[string]string{"ONE": "1", "TWO": "2"},
+				},
+			},
+			Upgraded: true,
+		},
+		config)
+}
+
+func TestParse_InvalidOverlays(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`numReplicas: 2
+container:
+  image: gcr.io/my-project/my-app
+  bad: true
+`), strings.NewReader(`numReplicas: -1
+`), strings.NewReader(`
+container:
+  env:
+    bad-name: two
+sessionAffinity: sticky
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 4: container.bad: unknown field
+  overlay 1, line 1: numReplicas: must not be negative: -1
+  overlay 2, line 4: container.env.bad-name: invalid environment variable name: "bad-name"
+  overlay 2, line 5: sessionAffinity: invalid session affinity: sticky`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
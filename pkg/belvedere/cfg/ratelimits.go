@@ -0,0 +1,138 @@
+package cfg
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// RateLimitKeyIP limits the rate of requests from each client IP address.
+	RateLimitKeyIP = "ip"
+	// RateLimitKeyHeader limits the rate of requests with each value of a request header.
+	RateLimitKeyHeader = "header"
+
+	// MaxRateLimits is the maximum number of rate limits an app can have.
+	MaxRateLimits = 100
+	// RateLimitRulePriority is the priority of the WAF rule for the first rate limit. The rules for
+	// the others follow it, after the app's WAF rules and before the allowed IPs. Requests under a rate
+	// limit are allowed without any further checks, so the app's WAF rules must come first for their
+	// deny rules to apply; in turn, apps with rate limits can't have WAF rules which allow requests.
+	RateLimitRulePriority = AllowIPRulePriority - MaxRateLimits
+
+	// maxRateLimitPaths is the maximum number of paths a rate limit can match, since Cloud Armor
+	// allows at most five subexpressions in a rule's expression.
+	maxRateLimitPaths = 5
+)
+
+// A RateLimit limits the rate of requests from each client to the paths it matches. Clients which
+// exceed the limit have their requests denied until their rate drops, or, if a ban duration is given,
+// are banned for that long.
+type RateLimit struct {
+	Description    string   `json:"description,omitempty"`
+	Paths          []string `json:"paths,omitempty"`
+	Key            string   `json:"key,omitempty"`
+	Header         string   `json:"header,omitempty"`
+	Requests       int64    `json:"requests"`
+	IntervalSec    int64    `json:"intervalSec"`
+	BanDurationSec int64    `json:"banDurationSec,omitempty"`
+	ExceedAction   string   `json:"exceedAction,omitempty"`
+}
+
+//nolint:gochecknoglobals // can't have const errors, slices, or regexps
+var (
+	errUnreachableRateLimit    = fmt.Errorf("required, since another rate limit already matches all paths")
+	errAllowRuleWithRateLimits = fmt.Errorf("not allowed with rate limits, which are evaluated after the WAF rules")
+
+	// rateLimitIntervals are the intervals Cloud Armor allows for rate limits, in seconds.
+	rateLimitIntervals = []int64{10, 30, 60, 120, 180, 240, 300, 600, 900, 1200, 1800, 2700, 3600}
+
+	headerNameFormat   = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	exceedActionFormat = regexp.MustCompile(`^deny\((403|404|429|502)\)$`)
+)
+
+// checkRateLimits checks that all rate limits have valid thresholds, keys, paths, and actions.
+func (v *validator) checkRateLimits(config *Config) {
+	if len(config.RateLimits) > MaxRateLimits {
+		v.fail("rateLimits", &InvalidValueError{
+			Value: len(config.RateLimits), Reason: fmt.Sprintf("must have at most %d rate limits", MaxRateLimits),
+		})
+	}
+
+	unrestricted := false
+
+	for i := range config.RateLimits {
+		path := fmt.Sprintf("rateLimits[%d]", i)
+		v.checkRateLimit(path, &config.RateLimits[i])
+
+		// Requests which conform to a rate limit for all paths are allowed, so no other rate limit for
+		// all paths would ever apply.
+		if len(config.RateLimits[i].Paths) == 0 {
+			if unrestricted {
+				v.fail(join(path, "paths"), errUnreachableRateLimit)
+			}
+
+			unrestricted = true
+		}
+	}
+}
+
+// checkRateLimit checks the rate limit at the given path.
+func (v *validator) checkRateLimit(path string, rl *RateLimit) {
+	if rl.Requests <= 0 {
+		v.fail(join(path, "requests"), &InvalidValueError{Value: rl.Requests, Reason: "must be positive"})
+	}
+
+	if !validInterval(rl.IntervalSec) {
+		v.fail(join(path, "intervalSec"), &InvalidValueError{
+			Value: rl.IntervalSec, Reason: fmt.Sprintf("must be one of %v", rateLimitIntervals),
+		})
+	}
+
+	if rl.BanDurationSec < 0 {
+		v.fail(join(path, "banDurationSec"), &InvalidValueError{Value: rl.BanDurationSec, Reason: "must not be negative"})
+	}
+
+	switch rl.Key {
+	case "", RateLimitKeyIP:
+		if rl.Header != "" {
+			v.fail(join(path, "header"), &InvalidValueError{Value: rl.Header, Reason: "only allowed with the header key"})
+		}
+	case RateLimitKeyHeader:
+		if rl.Header == "" {
+			v.fail(join(path, "header"), errRequired)
+		} else if !headerNameFormat.MatchString(rl.Header) {
+			v.fail(join(path, "header"), &InvalidValueError{Value: rl.Header, Reason: "invalid header name"})
+		}
+	default:
+		v.fail(join(path, "key"), &InvalidValueError{
+			Value: rl.Key, Reason: fmt.Sprintf("must be %q or %q", RateLimitKeyIP, RateLimitKeyHeader),
+		})
+	}
+
+	if len(rl.Paths) > maxRateLimitPaths {
+		v.fail(join(path, "paths"), &InvalidValueError{
+			Value: len(rl.Paths), Reason: fmt.Sprintf("must have at most %d paths", maxRateLimitPaths),
+		})
+	}
+
+	for i, p := range rl.Paths {
+		if _, err := regexp.Compile(p); err != nil {
+			v.fail(fmt.Sprintf("%s.paths[%d]", path, i), &InvalidValueError{Value: p, Reason: "invalid regular expression"})
+		}
+	}
+
+	if rl.ExceedAction != "" && !exceedActionFormat.MatchString(rl.ExceedAction) {
+		v.fail(join(path, "exceedAction"), &InvalidValueError{Value: rl.ExceedAction, Reason: "invalid action"})
+	}
+}
+
+// validInterval returns whether Cloud Armor allows rate limits with the given interval.
+func validInterval(sec int64) bool {
+	for _, i := range rateLimitIntervals {
+		if i == sec {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestParse_InvalidRateLimits(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`container:
+  image: gcr.io/my-project/my-app
+wafRules:
+  - action: deny(403)
+    priority: 2147483600
+  - action: allow
+    priority: 1000
+rateLimits:
+  - requests: 0
+    intervalSec: 45
+    banDurationSec: -1
+  - key: header
+    requests: 10
+    intervalSec: 60
+  - key: ip
+    header: X-API-Key
+    paths: ["^/login(", "^/ok"]
+    requests: 10
+    intervalSec: 60
+    exceedAction: deny(418)
+  - key: cookie
+    paths: ["^/api/"]
+    requests: 10
+    intervalSec: 60
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 5: wafRules[0].priority: must be between 1002 and 2147482546: 2147483600
+  line 6: wafRules[1].action: not allowed with rate limits, which are evaluated after the WAF rules
+  line 9: rateLimits[0].requests: must be positive: 0
+  line 10: rateLimits[0].intervalSec: must be one of [10 30 60 120 180 240 300 600 900 1200 1800 2700 3600]: 45
+  line 11: rateLimits[0].banDurationSec: must not be negative: -1
+  line 12: rateLimits[1].header: required
+  line 12: rateLimits[1].paths: required, since another rate limit already matches all paths
+  line 16: rateLimits[2].header: only allowed with the header key: "X-API-Key"
+  line 17: rateLimits[2].paths[0]: invalid regular expression: "^/login("
+  line 20: rateLimits[2].exceedAction: invalid action: "deny(418)"
+  line 21: rateLimits[3].key: must be "ip" or "header": "cookie"`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
@@ -0,0 +1,394 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// jsonSchema is a JSON Schema, or a fragment of one.
+type jsonSchema map[string]interface{}
+
+// Schema returns a JSON Schema (draft-07) for the configuration format, generated from Config and
+// the GCP API types it embeds, for use by editors and CI. The given version of Belvedere is recorded
+// in the schema.
+func Schema(version string) ([]byte, error) {
+	s := &schemaBuilder{defs: map[string]jsonSchema{}}
+
+	root := jsonSchema{
+		"$schema":  "http://json-schema.org/draft-07/schema#",
+		"title":    "Belvedere app configuration",
+		"$comment": fmt.Sprintf("Generated by belvedere %s.", version),
+	}
+
+	for k, v := range s.schema(reflect.TypeOf(Config{}), "") {
+		root[k] = v
+	}
+
+	root["definitions"] = s.defs
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schema: %w", err)
+	}
+
+	return append(b, '\n'), nil
+}
+
+// schemaBuilder accumulates the definitions of the struct types used by a configuration.
+type schemaBuilder struct {
+	defs map[string]jsonSchema
+}
+
+// schema returns the schema of the given type. Structs other than Config are added to the
+// definitions and referred to by name.
+func (s *schemaBuilder) schema(t reflect.Type, tag string) jsonSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Like encoding/json, integers tagged with ",string" are encoded as strings.
+	if strings.Contains(tag, ",string") {
+		switch t.Kind() { //nolint:exhaustive // only numbers are encoded as strings
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return jsonSchema{"type": "string", "pattern": `^-?[0-9]+$`}
+		}
+	}
+
+	switch t.Kind() { //nolint:exhaustive // everything else is allowed to be anything
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": s.schema(t.Elem(), "")}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": s.schema(t.Elem(), "")}
+	case reflect.Struct:
+		if t == reflect.TypeOf(Config{}) {
+			return s.object(t)
+		}
+
+		name := t.String()
+		if _, ok := s.defs[name]; !ok {
+			s.defs[name] = jsonSchema{} // Placeholder for recursive types.
+			s.defs[name] = s.object(t)
+		}
+
+		return jsonSchema{"$ref": "#/definitions/" + name}
+	default:
+		return jsonSchema{}
+	}
+}
+
+// object returns the schema of the given struct type, with its fields as properties.
+func (s *schemaBuilder) object(t reflect.Type) jsonSchema {
+	fields := jsonFields(t)
+	keys := make([]string, 0, len(fields))
+
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	properties := jsonSchema{}
+	patterns := jsonSchema{}
+
+	for _, k := range keys {
+		f := fields[k]
+		key := t.String() + "." + f.Name
+		p := s.schema(f.Type, f.Tag.Get("json"))
+
+		// References can't have siblings in draft-07, so wrap them.
+		if _, ok := p["$ref"]; ok && schemaFields[key] != nil {
+			p = jsonSchema{"allOf": []interface{}{p}}
+		}
+
+		for k, v := range schemaFields[key] {
+			p[k] = v
+		}
+
+		properties[f.Name] = p
+
+		// Parse matches field names regardless of case, so the schema does too.
+		patterns[caseInsensitive(f.Name)] = p
+	}
+
+	o := jsonSchema{
+		"type":                 "object",
+		"properties":           properties,
+		"patternProperties":    patterns,
+		"additionalProperties": false,
+	}
+
+	if d, ok := schemaTypes[t.String()]; ok {
+		o["description"] = d
+	}
+
+	if r, ok := schemaRequired[t.String()]; ok {
+		o["required"] = r
+	}
+
+	return o
+}
+
+// caseInsensitive returns a pattern which matches the given name regardless of case. JSON Schema
+// patterns don't support flags, so each letter is matched with a character class.
+func caseInsensitive(name string) string {
+	var b strings.Builder
+
+	b.WriteString("^")
+
+	for _, r := range name {
+		if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper {
+			b.WriteString("[" + string(lower) + string(upper) + "]")
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return b.String()
+}
+
+//nolint:gochecknoglobals // can't have const maps
+var (
+	// schemaTypes has descriptions of the struct types, keyed by type.
+	schemaTypes = map[string]string{
+		"cfg.Container": "A container which runs on each of the app's instances.",
+		"cfg.Disks":     "The boot disk and any additional disks attached to the app's instances.",
+		"cfg.Disk":      "An additional disk which is formatted and mounted on each of the app's instances.",
+		"cfg.File":      "A file which is written onto each of the app's instances.",
+		"cfg.Volume":    "A bind mount of a disk, file, or host path into a container.",
+		"cfg.RateLimit": "A limit on the rate of requests from each client to the paths it matches.",
+		"cfg.IPs":       "Client IP addresses, given as ranges and as the names of the project's IP lists.",
+		"compute.AutoscalingPolicy": "See " +
+			"https://cloud.google.com/compute/docs/reference/rest/v1/autoscalers#AutoscalingPolicy.",
+		"compute.BackendServiceCdnPolicy": "See " +
+			"https://cloud.google.com/compute/docs/reference/rest/v1/backendServices#BackendServiceCdnPolicy.",
+		"compute.BackendServiceIAP": "See " +
+			"https://cloud.google.com/compute/docs/reference/rest/v1/backendServices#BackendServiceIAP.",
+		"compute.SecurityPolicyRule": "See " +
+			"https://cloud.google.com/compute/docs/reference/rest/v1/securityPolicies#SecurityPolicyRule.",
+	}
+
+	// schemaRequired has the required fields of the struct types, keyed by type.
+	schemaRequired = map[string][]string{
+		"cfg.Container": {"image"},
+		"cfg.Disk":      {"name", "type"},
+		"cfg.File":      {"path"},
+		"cfg.Volume":    {"source", "target"},
+		"cfg.RateLimit": {"requests", "intervalSec"},
+	}
+
+	// schemaFields has descriptions and constraints of fields, keyed by type and JSON name.
+	schemaFields = map[string]jsonSchema{
+		"cfg.Config.apiVersion": {
+			"description": "The version of the config format. Older versions can be upgraded with 'belvedere config migrate'.",
+			"enum":        []string{CurrentVersion},
+		},
+		"cfg.Config.iamRoles": {
+			"description": "The IAM roles granted to the app's service account.",
+			"items":       jsonSchema{"type": "string", "pattern": iamRoleFormat.String()},
+		},
+		"cfg.Config.numReplicas": {
+			"description": "The number of instances to run, if the app isn't autoscaled.",
+			"minimum":     0,
+		},
+		"cfg.Config.machineType": {
+			"description": "The GCE machine type of the app's instances (e.g. n1-standard-1).",
+			"pattern":     machineTypeFormat.String(),
+		},
+		"cfg.Config.container": {
+			"description": "The app's container.",
+		},
+		"cfg.Config.sidecars": {
+			"description":   "Additional containers which run alongside the app's container, keyed by name.",
+			"propertyNames": jsonSchema{"pattern": `^[a-z]([-a-z0-9]*[a-z0-9])?$`, "maxLength": 63},
+		},
+		"cfg.Config.pinSidecars": {
+			"description": "Whether sidecar images are pinned to the digests their tags refer to when a release " +
+				"is created. Defaults to true.",
+		},
+		"cfg.Config.identityAwareProxy": {
+			"description": "The Identity-Aware Proxy configuration of the app's backend service.",
+		},
+		"cfg.Config.autoscalingPolicy": {
+			"description": "The autoscaling policy of the app's instance groups.",
+		},
+		"cfg.Config.cdnPolicy": {
+			"description": "The Cloud CDN configuration of the app's backend service.",
+		},
+		"cfg.Config.network": {
+			"description": "The VPC network of the app's instances, if not the default.",
+		},
+		"cfg.Config.subnetwork": {
+			"description": "The subnetwork of the app's instances, if not the default.",
+		},
+		"cfg.Config.wafRules": {
+			"description": "Cloud Armor rules for the app's load balancer, evaluated after the denied IPs and before " +
+				"the rate limits. Rules which allow requests aren't allowed with rate limits.",
+		},
+		"cfg.Config.rateLimits": {
+			"description": "Limits on the rate of requests from each client, evaluated after the WAF rules. " +
+				"Rate limits with paths are evaluated first, in order.",
+			"maxItems": MaxRateLimits,
+		},
+		"cfg.Config.sessionAffinity": {
+			"description": "How requests from a client are routed to the app's instances.",
+			"enum":        []string{SessionAffinityNone, SessionAffinityIP, SessionAffinityCookie},
+		},
+		"cfg.Config.disks": {
+			"description": "The disks of the app's instances.",
+		},
+		"cfg.Config.bootImage": {
+			"description": "The boot image of the app's instances. Defaults to " + DefaultBootImage + ".",
+		},
+		"cfg.Config.files": {
+			"description": "Files which are written onto each of the app's instances.",
+		},
+		"cfg.Config.drainingTimeout": {
+			"description": "How long, in seconds, the load balancer waits for connections to drain. Defaults to 60.",
+			"minimum":     0,
+			"maximum":     MaxDrainingTimeout,
+		},
+		"cfg.Container.image": {
+			"description": "The container image (e.g. gcr.io/my-project/my-app).",
+			"pattern":     imageFormat.String(),
+		},
+		"cfg.Container.command": {
+			"description": "The command to run instead of the image's entrypoint.",
+		},
+		"cfg.Container.args": {
+			"description": "The arguments passed to the command.",
+		},
+		"cfg.Container.env": {
+			"description":   "Environment variables, keyed by name.",
+			"propertyNames": jsonSchema{"pattern": envVarFormat.String()},
+		},
+		"cfg.Container.dockerOptions": {
+			"description": "Additional options passed to docker run.",
+		},
+		"cfg.Container.volumes": {
+			"description": "Disks, files, and host paths mounted into the container.",
+		},
+		"cfg.Container.stopTimeout": {
+			"description": "How long, in seconds, the container has to stop before it's killed.",
+			"minimum":     0,
+		},
+		"cfg.Container.preStop": {
+			"description": "A command which is run in the container before it's stopped.",
+		},
+		"cfg.Volume.source": {
+			"description": "The name of a disk, or an absolute path on the host.",
+		},
+		"cfg.Volume.target": {
+			"description": "The path in the container.",
+		},
+		"cfg.Disks.bootSizeGb": {
+			"description": "The size of the boot disk, in GB.",
+			"minimum":     0,
+		},
+		"cfg.Disks.bootType": {
+			"description": "The type of the boot disk.",
+			"enum":        []string{DiskTypeStandard, DiskTypeBalanced, DiskTypeSSD},
+		},
+		"cfg.Disk.name": {
+			"description": "The name of the disk, which volumes refer to.",
+			"pattern":     `^[a-z]([-a-z0-9]*[a-z0-9])?$`,
+			"maxLength":   63,
+		},
+		"cfg.Disk.type": {
+			"description": "The type of the disk.",
+			"enum":        []string{DiskTypeStandard, DiskTypeBalanced, DiskTypeSSD, DiskTypeLocalSSD},
+		},
+		"cfg.Disk.sizeGb": {
+			"description": "The size of the disk, in GB. Local SSDs are always 375GB.",
+			"minimum":     0,
+		},
+		"cfg.Disk.mountPath": {
+			"description": "Where the disk is mounted on the host. Defaults to /mnt/disks/{name}.",
+		},
+		"cfg.File.path": {
+			"description": "The absolute path of the file on the host.",
+			"pattern":     "^/",
+		},
+		"cfg.File.mode": {
+			"description": "The octal permissions of the file (e.g. 0644).",
+			"pattern":     fileModeFormat.String(),
+		},
+		"cfg.File.owner": {
+			"description": "The owner of the file (e.g. root:root).",
+		},
+		"cfg.File.content": {
+			"description": "The contents of the file. Mutually exclusive with source.",
+		},
+		"cfg.File.source": {
+			"description": "A local file whose contents are used. Mutually exclusive with content.",
+		},
+		"cfg.Config.allowIPs": {
+			"description": "The only clients which are allowed, after the WAF rules. Not allowed with rate limits.",
+		},
+		"cfg.Config.denyIPs": {
+			"description": "Clients which are denied, before the rate limits and WAF rules.",
+		},
+		"cfg.IPs.ranges": {
+			"description": "IP addresses and CIDR ranges (e.g. 203.0.113.0/24).",
+		},
+		"cfg.IPs.lists": {
+			"description": "The names of the project's IP lists (see 'belvedere iplists').",
+			"items":       jsonSchema{"type": "string", "pattern": `^[a-z]([-a-z0-9]*[a-z0-9])?$`, "maxLength": 63},
+		},
+		"cfg.RateLimit.paths": {
+			"description": "Regular expressions matched against request paths. Defaults to all paths.",
+			"maxItems":    maxRateLimitPaths,
+		},
+		"cfg.RateLimit.key": {
+			"description": "How clients are told apart: by IP address or by the value of a header. Defaults to ip.",
+			"enum":        []string{RateLimitKeyIP, RateLimitKeyHeader},
+		},
+		"cfg.RateLimit.header": {
+			"description": "The header which identifies clients, if the key is header.",
+			"pattern":     headerNameFormat.String(),
+		},
+		"cfg.RateLimit.requests": {
+			"description": "The number of requests allowed from each client in each interval.",
+			"minimum":     1,
+		},
+		"cfg.RateLimit.intervalSec": {
+			"description": "The length of the interval, in seconds.",
+			"enum":        rateLimitIntervals,
+		},
+		"cfg.RateLimit.banDurationSec": {
+			"description": "If set, clients which exceed the limit are banned for this many seconds.",
+			"minimum":     0,
+		},
+		"cfg.RateLimit.exceedAction": {
+			"description": "The action taken on requests over the limit. Defaults to deny(429).",
+			"pattern":     exceedActionFormat.String(),
+		},
+		"compute.SecurityPolicyRule.action": {
+			"description": "The action taken when the rule matches (e.g. allow or deny(403)).",
+		},
+		"compute.SecurityPolicyRule.priority": {
+			"description": "The rule's priority. Lower priorities are evaluated first.",
+			"minimum":     WAFRulePriority,
+			"maximum":     MaxWAFRulePriority,
+		},
+		"compute.AutoscalingPolicyCustomMetricUtilization.utilizationTargetType": {
+			"enum": []string{"DELTA_PER_MINUTE", "DELTA_PER_SECOND", "GAUGE"},
+		},
+	}
+)
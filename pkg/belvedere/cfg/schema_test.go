@@ -0,0 +1,164 @@
+package cfg
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	b, err := Schema("v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "$comment", "Generated by belvedere v1.2.3.", schema["$comment"])
+	assert.Equal(t, "sessionAffinity",
+		map[string]interface{}{
+			"description": "How requests from a client are routed to the app's instances.",
+			"enum":        []interface{}{"none", "ip", "cookie"},
+			"type":        "string",
+		},
+		lookup(t, schema, "properties", "sessionAffinity"))
+	assert.Equal(t, "signedUrlCacheMaxAgeSec",
+		map[string]interface{}{"type": "string", "pattern": "^-?[0-9]+$"},
+		lookup(t, schema, "definitions", "compute.BackendServiceCdnPolicy", "properties",
+			"signedUrlCacheMaxAgeSec"))
+	assert.Equal(t, "wafRules",
+		map[string]interface{}{"$ref": "#/definitions/compute.SecurityPolicyRule"},
+		lookup(t, schema, "properties", "wafRules", "items"))
+}
+
+func TestSchema_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	b, err := Schema("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := lookup(t, schema, "definitions", "compute.BackendServiceIAP", "patternProperties")
+
+	assert.Equal(t, "oauth2ClientId",
+		map[string]interface{}{"type": "string"},
+		lookup(t, patterns, "^[oO][aA][uU][tT][hH]2[cC][lL][iI][eE][nN][tT][iI][dD]$"))
+
+	for _, key := range []string{"oauth2ClientId", "oauth2ClientID", "OAUTH2CLIENTID"} {
+		if matchPattern(t, patterns, key) == nil {
+			t.Errorf("%s is not in the schema", key)
+		}
+	}
+}
+
+func TestSchema_Example(t *testing.T) {
+	t.Parallel()
+
+	b, err := Schema("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.ReadFile("config-example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(f, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	checkKeys(t, schema, schema, doc.Content[0], "")
+}
+
+// checkKeys fails if any of the keys in the given node aren't properties in the given schema.
+func checkKeys(t *testing.T, root, schema map[string]interface{}, n *yaml.Node, path string) {
+	t.Helper()
+
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = lookup(t, root, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		schema = allOf[0].(map[string]interface{})
+		checkKeys(t, root, schema, n, path)
+
+		return
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+
+			var child map[string]interface{}
+			if patterns, ok := schema["patternProperties"].(map[string]interface{}); ok {
+				child = matchPattern(t, patterns, key)
+				if child == nil {
+					t.Errorf("%s.%s is not in the schema", path, key)
+
+					continue
+				}
+			} else {
+				child = schema["additionalProperties"].(map[string]interface{})
+			}
+
+			checkKeys(t, root, child, n.Content[i+1], path+"."+key)
+		}
+	case yaml.SequenceNode:
+		for _, e := range n.Content {
+			checkKeys(t, root, schema["items"].(map[string]interface{}), e, path+"[]")
+		}
+	}
+}
+
+// matchPattern returns the schema of the pattern property which matches the given key, if any.
+func matchPattern(t *testing.T, patterns map[string]interface{}, key string) map[string]interface{} {
+	t.Helper()
+
+	for pattern, schema := range patterns {
+		if regexp.MustCompile(pattern).MatchString(key) {
+			return schema.(map[string]interface{})
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the object at the given path in the given schema.
+func lookup(t *testing.T, schema map[string]interface{}, path ...string) map[string]interface{} {
+	t.Helper()
+
+	for _, k := range path {
+		v, ok := schema[k].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s not found in schema", strings.Join(path, "/"))
+		}
+
+		schema = v
+	}
+
+	return schema
+}
@@ -0,0 +1,393 @@
+package cfg
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// HealthCheckRulePriority is the priority of the built-in WAF rule which denies external access to
+	// health checks.
+	HealthCheckRulePriority = 1
+	// WAFRulePriority is the lowest priority an app's WAF rule can have, so that the app's WAF rules are
+	// evaluated after the built-in and denied IP rules.
+	WAFRulePriority = DenyIPRulePriority + MaxIPRules
+	// MaxWAFRulePriority is the highest priority an app's WAF rule can have, so that the app's WAF rules
+	// are evaluated before the rate limiting rules.
+	MaxWAFRulePriority = RateLimitRulePriority - 1
+	// DefaultRulePriority is the priority of the built-in WAF rule which allows all other requests, or,
+	// if the app has allowed IPs, denies them.
+	DefaultRulePriority = math.MaxInt32
+)
+
+// A FieldError is a problem with a single field of a configuration.
+type FieldError struct {
+	// Path is the path to the field (e.g. wafRules[0].priority).
+	Path string
+	// Line is the line of the configuration file on which the field (or, if the field is missing, its
+	// closest parent) appears, if known.
+	Line int
+	// Overlay is the number of the overlay, starting at 1, whose line the field appears on, or zero if
+	// it appears in the base configuration.
+	Overlay int
+	Err     error
+}
+
+func (e *FieldError) Error() string {
+	// Problems found after the configuration is parsed don't have a line.
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+
+	if e.Overlay > 0 {
+		return fmt.Sprintf("overlay %d, line %d: %s: %v", e.Overlay, e.Line, e.Path, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// A ValidationError is the set of all problems with a configuration, in the order in which they
+// appear in the configuration file and then its overlays.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		lines[i] = "  " + fe.Error()
+	}
+
+	return fmt.Sprintf("invalid config:\n%s", strings.Join(lines, "\n"))
+}
+
+// An InvalidValueError is a field value which isn't valid, and why.
+type InvalidValueError struct {
+	Value  interface{}
+	Reason string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("%s: %#v", e.Reason, e.Value)
+}
+
+//nolint:gochecknoglobals // can't have const errors
+var (
+	errUnknownField = fmt.Errorf("unknown field")
+	errRequired     = fmt.Errorf("required")
+)
+
+//nolint:gochecknoglobals // can't have const regexps
+var (
+	// https://github.com/distribution/distribution/blob/main/reference/reference.go
+	imageFormat = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::\w[\w.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+	machineTypeFormat = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	iamRoleFormat     = regexp.MustCompile(
+		`^(?:roles|projects/[a-z][a-z0-9-]*/roles|organizations/[0-9]+/roles)/[a-zA-Z0-9_.]+$`)
+	envVarFormat   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	fileModeFormat = regexp.MustCompile(`^[0-7]{3,4}$`)
+)
+
+// validator accumulates the problems with a configuration.
+type validator struct {
+	nodes   map[string]*yaml.Node
+	sources map[*yaml.Node]int
+	errors  []*FieldError
+}
+
+// validate checks the given configuration, parsed from the given YAML document, and returns a
+// ValidationError with all of its problems, if any. The document's nodes which came from overlays
+// are mapped to the numbers of those overlays.
+func validate(config *Config, doc *yaml.Node, sources map[*yaml.Node]int) error {
+	v := &validator{nodes: map[string]*yaml.Node{}, sources: sources}
+
+	if len(doc.Content) > 0 {
+		v.checkFields(doc.Content[0], reflect.TypeOf(config), "")
+	}
+
+	v.checkValues(config)
+	v.checkDisks(config)
+	v.checkFiles(config)
+	v.checkRateLimits(config)
+	v.checkIPs(config)
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		if v.errors[i].Overlay != v.errors[j].Overlay {
+			return v.errors[i].Overlay < v.errors[j].Overlay
+		}
+
+		return v.errors[i].Line < v.errors[j].Line
+	})
+
+	return &ValidationError{Errors: v.errors}
+}
+
+// fail records a problem with the field at the given path.
+func (v *validator) fail(path string, err error) {
+	v.failAt(path, v.node(path), err)
+}
+
+// failAt records a problem with the field at the given path, which appears at the given node, if
+// any.
+func (v *validator) failAt(path string, n *yaml.Node, err error) {
+	fe := &FieldError{Path: path, Err: err}
+
+	if n != nil {
+		fe.Line, fe.Overlay = n.Line, v.sources[n]
+	}
+
+	v.errors = append(v.errors, fe)
+}
+
+// node returns the node at which the field at the given path appears (i.e. its key). If the field
+// doesn't appear in the configuration, the node of its closest parent is returned.
+func (v *validator) node(path string) *yaml.Node {
+	for {
+		if n, ok := v.nodes[path]; ok {
+			return n
+		}
+
+		if path == "" {
+			return nil
+		}
+
+		idx := strings.LastIndexAny(path, ".[")
+		if idx < 0 {
+			idx = 0
+		}
+
+		path = path[:idx]
+	}
+}
+
+// checkFields records the location of each field in the given node (i.e. its key) and
+// reports any fields which don't correspond to a field of the given type. Like encoding/json, field
+// names are matched case-insensitively.
+func (v *validator) checkFields(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	v.nodes[path] = n
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := jsonFields(t)
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+
+			f, ok := fields[strings.ToLower(key.Value)]
+			if !ok {
+				v.failAt(join(path, key.Value), key, errUnknownField)
+
+				continue
+			}
+
+			v.checkFields(value, f.Type, join(path, f.Name))
+			v.locate(join(path, f.Name), key, value)
+		}
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			v.checkFields(n.Content[i+1], t.Elem(), join(path, key.Value))
+			v.locate(join(path, key.Value), key, n.Content[i+1])
+		}
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, e := range n.Content {
+			v.checkFields(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// locate records the location of the field at the given path as its key, unless an overlay replaced
+// its value, in which case the value is where the field was set.
+func (v *validator) locate(path string, key, value *yaml.Node) {
+	if v.sources[key] != v.sources[value] {
+		v.nodes[path] = value
+
+		return
+	}
+
+	v.nodes[path] = key
+}
+
+// jsonFields returns the fields of the given struct type, keyed by their lower-cased JSON names.
+// The name of each returned field is its JSON name.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" || f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		// Embedded structs without names have their fields promoted.
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, ef := range jsonFields(f.Type) {
+				fields[k] = ef
+			}
+
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		f.Name = name
+		fields[strings.ToLower(name)] = f
+	}
+
+	return fields
+}
+
+// checkValues checks the values of the configuration's fields.
+func (v *validator) checkValues(config *Config) {
+	if config.NumReplicas < 0 {
+		v.fail("numReplicas", &InvalidValueError{Value: config.NumReplicas, Reason: "must not be negative"})
+	}
+
+	if config.MachineType != "" && !machineTypeFormat.MatchString(config.MachineType) {
+		v.fail("machineType", &InvalidValueError{Value: config.MachineType, Reason: "invalid machine type"})
+	}
+
+	for i, role := range config.IAMRoles {
+		if !iamRoleFormat.MatchString(role) {
+			v.fail(fmt.Sprintf("iamRoles[%d]", i), &InvalidValueError{Value: role, Reason: "invalid IAM role"})
+		}
+	}
+
+	if t := config.DrainingTimeout; t != nil && (*t < 0 || *t > MaxDrainingTimeout) {
+		v.fail("drainingTimeout", &InvalidValueError{
+			Value: *t, Reason: fmt.Sprintf("must be between 0 and %d", MaxDrainingTimeout),
+		})
+	}
+
+	switch config.SessionAffinity {
+	case "", SessionAffinityCookie, SessionAffinityIP, SessionAffinityNone:
+	default:
+		v.fail("sessionAffinity", &InvalidSessionAffinityError{Value: config.SessionAffinity})
+	}
+
+	v.checkContainer("container", &config.Container)
+
+	names := make([]string, 0, len(config.Sidecars))
+	for name := range config.Sidecars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := join("sidecars", name)
+		if err := gcp.ValidateRFC1035(name); err != nil {
+			v.fail(path, err)
+		}
+
+		sidecar := config.Sidecars[name]
+		if sidecar.Image == "" {
+			v.fail(join(path, "image"), errRequired)
+		}
+
+		v.checkContainer(path, &sidecar)
+	}
+
+	v.checkWAFRules(config)
+}
+
+// checkContainer checks the container at the given path. Its image is only checked if it's present,
+// since app-level commands don't need the app's image; see ReleaseConfig.Validate.
+func (v *validator) checkContainer(path string, c *Container) {
+	if c.Image != "" && !imageFormat.MatchString(c.Image) {
+		v.fail(join(path, "image"), &InvalidValueError{Value: c.Image, Reason: "invalid image"})
+	}
+
+	names := make([]string, 0, len(c.Env))
+	for name := range c.Env {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !envVarFormat.MatchString(name) {
+			v.fail(join(join(path, "env"), name),
+				&InvalidValueError{Value: name, Reason: "invalid environment variable name"})
+		}
+	}
+
+	if c.StopTimeout < 0 {
+		v.fail(join(path, "stopTimeout"), &InvalidValueError{Value: c.StopTimeout, Reason: "must not be negative"})
+	}
+}
+
+// checkWAFRules checks that all WAF rules have unique priorities which fit between the built-in, denied
+// IP, and rate limiting rules, and that none allow requests if the app has rate limits.
+func (v *validator) checkWAFRules(config *Config) {
+	priorities := map[int64]string{}
+
+	for i, rule := range config.WAFRules {
+		path := fmt.Sprintf("wafRules[%d].priority", i)
+
+		if rule == nil {
+			continue
+		}
+
+		// The rate limits are evaluated after the WAF rules, so requests a WAF rule allows would skip them.
+		if rule.Action == "allow" && len(config.RateLimits) > 0 {
+			v.fail(fmt.Sprintf("wafRules[%d].action", i), errAllowRuleWithRateLimits)
+		}
+
+		switch p := rule.Priority; {
+		case p < WAFRulePriority || p > MaxWAFRulePriority:
+			v.fail(path, &InvalidValueError{
+				Value: p, Reason: fmt.Sprintf("must be between %d and %d", WAFRulePriority, MaxWAFRulePriority),
+			})
+		default:
+			if other, ok := priorities[p]; ok {
+				v.fail(path, &InvalidValueError{
+					Value: p, Reason: fmt.Sprintf("duplicate priority (also used by %s)", other),
+				})
+				continue
+			}
+
+			priorities[p] = fmt.Sprintf("wafRules[%d]", i)
+		}
+	}
+}
+
+// join returns the path of the given field of the given path.
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
@@ -0,0 +1,150 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse(strings.NewReader(`numReplicas: -1
+machineType: N1 Standard
+iamRoles:
+  - roles/logging.logWriter
+  - logWriter
+container:
+  imag: gcr.io/my-project/my-app
+  env:
+    GOOD_NAME: one
+    bad-name: two
+sidecars:
+  Nginx:
+    image: nginx:1.19
+  envoy:
+    image: Envoy Proxy
+wafRules:
+  - action: deny(403)
+    priority: -1
+  - action: deny(403)
+    priority: 1000
+  - action: deny(403)
+    priority: 1000
+  - action: allow
+    priority: 2147483647
+sessionAffinity: sticky
+drainingTimeout: -1
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+
+	want := `invalid config:
+  line 1: numReplicas: must not be negative: -1
+  line 2: machineType: invalid machine type: "N1 Standard"
+  line 5: iamRoles[1]: invalid IAM role: "logWriter"
+  line 7: container.imag: unknown field
+  line 10: container.env.bad-name: invalid environment variable name: "bad-name"
+  line 12: sidecars.Nginx: invalid name: "Nginx"
+  line 15: sidecars.envoy.image: invalid image: "Envoy Proxy"
+  line 18: wafRules[0].priority: must be between 1002 and 2147482546: -1
+  line 22: wafRules[2].priority: duplicate priority (also used by wafRules[1]): 2002
+  line 24: wafRules[3].priority: must be between 1002 and 2147482546: 2147483647
+  line 25: sessionAffinity: invalid session affinity: sticky
+  line 26: drainingTimeout: must be between 0 and 3600: -1`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
+
+func TestParse_DrainingTimeout(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+drainingTimeout: 0
+container:
+  image: gcr.io/my-project/my-app
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.DrainingTimeout == nil {
+		t.Fatal("DrainingTimeout = nil, want 0")
+	}
+
+	assert.Equal(t, "DrainingTimeout", 0, *config.DrainingTimeout)
+
+	_, err = Parse(strings.NewReader(`
+drainingTimeout: 3601
+container:
+  image: gcr.io/my-project/my-app
+`))
+	if err == nil {
+		t.Fatal("Parse() = nil, want an error")
+	}
+
+	assert.Equal(t, "Parse() error",
+		"invalid config:\n  line 2: drainingTimeout: must be between 0 and 3600: 3601", err.Error())
+}
+
+func TestParse_NoImage(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+sessionAffinity: ip
+sidecars:
+  nginx:
+    env:
+      NGINX_PORT: "8080"
+`))
+
+	got, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Parse() = %v, %v, want a ValidationError", config, err)
+	}
+
+	want := `invalid config:
+  line 4: sidecars.nginx.image: required`
+
+	assert.Equal(t, "Parse() error", want, got.Error())
+}
+
+func TestParse_AppOnly(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+sessionAffinity: ip
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Parse()", "ip", config.SessionAffinity)
+
+	err = config.ReleaseConfig.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want an error")
+	}
+
+	assert.Equal(t, "Validate()", "invalid config:\n  container.image: required", err.Error())
+}
+
+func TestParse_CaseInsensitiveFields(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(`
+container:
+  image: gcr.io/my-project/my-app
+identityAwareProxy:
+  oauth2ClientID: client-id
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Parse()", "client-id", config.IAP.Oauth2ClientId)
+}
@@ -0,0 +1,191 @@
+package belvedere
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+)
+
+const (
+	DiffAdd    = "add"
+	DiffRemove = "remove"
+	DiffChange = "change"
+)
+
+// A ResourceDiff describes how a single resource in a deployment would be added, removed, or
+// changed.
+type ResourceDiff struct {
+	Name   string      `json:"name"`
+	Type   string      `json:"type"`
+	Action string      `json:"action"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// A FieldDiff describes how a single field of a resource's properties would be added, removed, or
+// changed. Paths are dotted, with list indexes in brackets (e.g. metadata.items[3].value).
+type FieldDiff struct {
+	Path   string      `json:"path"`
+	Action string      `json:"action"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// diffResources returns the differences between the current and proposed resources of a deployment,
+// matching resources by name. Resources which are unchanged are omitted.
+func diffResources(current, proposed []deployments.Resource) ([]ResourceDiff, error) {
+	currentProps, err := resourceProperties(current)
+	if err != nil {
+		return nil, err
+	}
+
+	proposedProps, err := resourceProperties(proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []ResourceDiff
+
+	// Find added and changed resources, in the order they would be deployed.
+	for _, r := range proposed {
+		before, ok := currentProps[r.Name]
+
+		d := ResourceDiff{Name: r.Name, Type: r.Type, Action: DiffChange}
+		if !ok {
+			d.Action, before = DiffAdd, map[string]interface{}{}
+		}
+
+		diffValues("", before, proposedProps[r.Name], &d.Fields)
+
+		if len(d.Fields) > 0 || d.Action == DiffAdd {
+			diffs = append(diffs, d)
+		}
+	}
+
+	// Find removed resources.
+	for _, r := range current {
+		if _, ok := proposedProps[r.Name]; ok {
+			continue
+		}
+
+		d := ResourceDiff{Name: r.Name, Type: r.Type, Action: DiffRemove}
+		diffValues("", currentProps[r.Name], map[string]interface{}{}, &d.Fields)
+		diffs = append(diffs, d)
+	}
+
+	return diffs, nil
+}
+
+// resourceProperties returns a map of resource names to their properties as generic JSON values.
+func resourceProperties(resources []deployments.Resource) (map[string]interface{}, error) {
+	props := make(map[string]interface{}, len(resources))
+
+	for i := range resources {
+		var v interface{}
+		if err := resources[i].DecodeProperties(&v); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", resources[i].Name, err)
+		}
+
+		props[resources[i].Name] = v
+	}
+
+	return props, nil
+}
+
+// diffValues appends the differences between the two generic JSON values to the given list.
+// Objects are compared key by key and lists are compared index by index. Strings which contain
+// JSON objects, like cloud-config manifests, are compared structurally.
+func diffValues(path string, before, after interface{}, diffs *[]FieldDiff) {
+	before, after = embeddedJSON(before), embeddedJSON(after)
+
+	switch o := before.(type) {
+	case map[string]interface{}:
+		if n, ok := after.(map[string]interface{}); ok {
+			diffObjects(path, o, n, diffs)
+			return
+		}
+	case []interface{}:
+		if n, ok := after.([]interface{}); ok {
+			diffLists(path, o, n, diffs)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Action: DiffChange, Before: before, After: after})
+	}
+}
+
+func diffObjects(path string, before, after map[string]interface{}, diffs *[]FieldDiff) {
+	keys := make([]string, 0, len(before)+len(after))
+
+	for k := range before {
+		keys = append(keys, k)
+	}
+
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = fmt.Sprintf("%s.%s", path, k)
+		}
+
+		o, inBefore := before[k]
+		n, inAfter := after[k]
+
+		switch {
+		case !inBefore:
+			*diffs = append(*diffs, FieldDiff{Path: p, Action: DiffAdd, After: embeddedJSON(n)})
+		case !inAfter:
+			*diffs = append(*diffs, FieldDiff{Path: p, Action: DiffRemove, Before: embeddedJSON(o)})
+		default:
+			diffValues(p, o, n, diffs)
+		}
+	}
+}
+
+func diffLists(path string, before, after []interface{}, diffs *[]FieldDiff) {
+	for i := 0; i < len(before) || i < len(after); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		switch {
+		case i >= len(before):
+			*diffs = append(*diffs, FieldDiff{Path: p, Action: DiffAdd, After: embeddedJSON(after[i])})
+		case i >= len(after):
+			*diffs = append(*diffs, FieldDiff{Path: p, Action: DiffRemove, Before: embeddedJSON(before[i])})
+		default:
+			diffValues(p, before[i], after[i], diffs)
+		}
+	}
+}
+
+// embeddedJSON returns the JSON object in the given value, if it's a string containing one.
+// Otherwise, it returns the value.
+func embeddedJSON(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+
+	s = strings.TrimPrefix(s, "#cloud-config\n\n")
+	if !strings.HasPrefix(s, "{") {
+		return v
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return v
+	}
+
+	return obj
+}
@@ -0,0 +1,89 @@
+package belvedere
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestDiffResources(t *testing.T) {
+	t.Parallel()
+
+	current := []deployments.Resource{
+		{
+			Name:       "unchanged",
+			Type:       "compute.v1.network",
+			Properties: json.RawMessage(`{"name":"unchanged"}`),
+		},
+		{
+			Name: "changed",
+			Type: "compute.v1.instanceTemplate",
+			Properties: json.RawMessage(`{"tags":["a","b"],"size":1,"metadata":{"user-data":` +
+				`"#cloud-config\n\n{\"runcmd\":[\"one\"]}"},"old":true}`),
+		},
+		{
+			Name:       "removed",
+			Type:       "compute.v1.autoscaler",
+			Properties: json.RawMessage(`{"min":1}`),
+		},
+	}
+
+	proposed := []deployments.Resource{
+		{
+			Name:       "unchanged",
+			Type:       "compute.v1.network",
+			Properties: json.RawMessage(`{"name":"unchanged"}`),
+		},
+		{
+			Name: "changed",
+			Type: "compute.v1.instanceTemplate",
+			Properties: json.RawMessage(`{"tags":["a"],"size":2,"metadata":{"user-data":` +
+				`"#cloud-config\n\n{\"runcmd\":[\"two\"]}"},"new":{"x":"y"}}`),
+		},
+		{
+			Name:       "added",
+			Type:       "compute.v1.firewall",
+			Properties: json.RawMessage(`{"name":"added"}`),
+		},
+	}
+
+	got, err := diffResources(current, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ResourceDiff{
+		{
+			Name:   "changed",
+			Type:   "compute.v1.instanceTemplate",
+			Action: DiffChange,
+			Fields: []FieldDiff{
+				{Path: "metadata.user-data.runcmd[0]", Action: DiffChange, Before: "one", After: "two"},
+				{Path: "new", Action: DiffAdd, After: map[string]interface{}{"x": "y"}},
+				{Path: "old", Action: DiffRemove, Before: true},
+				{Path: "size", Action: DiffChange, Before: 1.0, After: 2.0},
+				{Path: "tags[1]", Action: DiffRemove, Before: "b"},
+			},
+		},
+		{
+			Name:   "added",
+			Type:   "compute.v1.firewall",
+			Action: DiffAdd,
+			Fields: []FieldDiff{
+				{Path: "name", Action: DiffAdd, After: "added"},
+			},
+		},
+		{
+			Name:   "removed",
+			Type:   "compute.v1.autoscaler",
+			Action: DiffRemove,
+			Fields: []FieldDiff{
+				{Path: "min", Action: DiffRemove, Before: 1.0},
+			},
+		},
+	}
+
+	assert.Equal(t, "diffResources()", want, got)
+}
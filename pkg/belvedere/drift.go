@@ -0,0 +1,439 @@
+package belvedere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
+	"go.opencensus.io/trace"
+)
+
+const (
+	// DriftChanged indicates that a field of a live resource differs from its deployment.
+	DriftChanged = "changed"
+	// DriftMissing indicates that a resource in a deployment doesn't exist.
+	DriftMissing = "missing"
+	// DriftUnmanaged indicates that a backend was attached to an app's backend service outside of
+	// Belvedere.
+	DriftUnmanaged = "unmanaged"
+)
+
+// A Drift is a difference between a resource in an app or release's deployment and the live
+// resource. Expected and actual values are strings, or compact JSON for other values.
+type Drift struct {
+	App      string
+	Release  string
+	Resource string
+	Type     string
+	Kind     string
+	Path     string
+	Expected string
+	Actual   string
+}
+
+// writeOnlyFields are the paths of resource properties which can be set but are never returned by
+// the APIs which own them.
+//nolint:gochecknoglobals // can't have non-scalar consts
+var writeOnlyFields = map[string]bool{
+	"iap.oauth2ClientSecret": true,
+}
+
+func (p *project) Drift(ctx context.Context, app string) ([]Drift, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.project.Drift")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+	)
+
+	apps := []string{app}
+
+	// If no app is given, check all the apps in the project.
+	if app == "" {
+		list, err := p.apps.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		apps = make([]string, len(list))
+		for i, a := range list {
+			apps[i] = a.Name
+		}
+	}
+
+	var drifts []Drift
+
+	for _, name := range apps {
+		d, err := p.appDrift(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		drifts = append(drifts, d...)
+	}
+
+	return drifts, nil
+}
+
+// appDrift returns the drift of the given app and its releases.
+func (p *project) appDrift(ctx context.Context, app string) ([]Drift, error) {
+	releases, err := p.releases.List(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+
+	// The instance groups of the app's releases are the only backends Belvedere attaches.
+	instanceGroups := map[string]bool{}
+
+	for _, rel := range releases {
+		res, err := p.dm.Resources(ctx, p.name, resources.Name(app, rel.Release))
+		if err != nil {
+			// Releases which were never successfully created have nothing to compare.
+			var noManifest *deployments.NoManifestError
+			if errors.As(err, &noManifest) {
+				continue
+			}
+
+			return nil, fmt.Errorf("error getting release %s: %w", rel.Release, err)
+		}
+
+		for _, r := range res {
+			if r.Type == "compute.v1.regionInstanceGroupManager" {
+				instanceGroups[r.Name] = true
+			}
+		}
+
+		d, _, err := p.resourceDrift(ctx, app, rel.Release, res)
+		if err != nil {
+			return nil, err
+		}
+
+		drifts = append(drifts, d...)
+	}
+
+	res, err := p.dm.Resources(ctx, p.name, resources.Name(app))
+	if err != nil {
+		return nil, fmt.Errorf("error getting app %s: %w", app, err)
+	}
+
+	d, live, err := p.resourceDrift(ctx, app, "", res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Release drift is listed after app drift.
+	drifts = append(d, drifts...)
+
+	for i := range res {
+		if res[i].Type == "compute.v1.backendService" {
+			drifts = append(drifts, unmanagedBackends(app, &res[i], live[res[i].Name], instanceGroups)...)
+		}
+	}
+
+	return drifts, nil
+}
+
+// resourceDrift fetches the live state of the given deployment resources and returns how the live
+// resources differ from the deployment along with the live resources.
+func (p *project) resourceDrift(
+	ctx context.Context, app, release string, res []deployments.Resource,
+) ([]Drift, map[string]map[string]interface{}, error) {
+	var drifts []Drift
+
+	live, err := p.fetchLive(ctx, res, func(r *deployments.Resource, props interface{}, obj map[string]interface{}) {
+		if obj == nil {
+			drifts = append(drifts, Drift{
+				App: app, Release: release, Resource: r.Name, Type: r.Type, Kind: DriftMissing,
+			})
+
+			return
+		}
+
+		var fields []FieldDiff
+
+		driftValues("", props, obj, &fields)
+
+		for _, f := range fields {
+			drifts = append(drifts, Drift{
+				App: app, Release: release, Resource: r.Name, Type: r.Type, Kind: DriftChanged,
+				Path: f.Path, Expected: driftValue(f.Before), Actual: driftValue(f.After),
+			})
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return drifts, live, nil
+}
+
+// fetchLive fetches the live state of the given deployment resources in dependency order, resolving
+// references between them, and calls f with each resource, its resolved properties, and its live
+// state, which is nil if it doesn't exist. Returns the live resources which exist, by name.
+func (p *project) fetchLive(
+	ctx context.Context, res []deployments.Resource,
+	f func(r *deployments.Resource, props interface{}, obj map[string]interface{}),
+) (map[string]map[string]interface{}, error) {
+	ordered, err := deployments.DependencyOrder(res)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]map[string]interface{}, len(res))
+	lookup := func(name, property string) (interface{}, bool) {
+		if obj := live[name]; obj != nil {
+			return deployments.Lookup(obj, property)
+		}
+
+		return nil, false
+	}
+
+	for i := range ordered {
+		r := &ordered[i]
+
+		var props interface{}
+		if err := r.DecodeProperties(&props); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", r.Name, err)
+		}
+
+		props = deployments.ResolveRefs(props, lookup)
+
+		b, err := json.Marshal(props)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, err := p.live.Fetch(ctx, p.name, &deployments.Resource{
+			Name:       r.Name,
+			Type:       r.Type,
+			Properties: json.RawMessage(b),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if obj != nil {
+			live[r.Name] = obj
+		}
+
+		f(r, props, obj)
+	}
+
+	return live, nil
+}
+
+// unmanagedBackends returns a drift for each backend of the given live backend service which isn't
+// one of the given instance groups.
+func unmanagedBackends(
+	app string, r *deployments.Resource, live map[string]interface{}, instanceGroups map[string]bool,
+) []Drift {
+	backends, _ := live["backends"].([]interface{})
+
+	var drifts []Drift
+
+	for _, b := range backends {
+		backend, _ := b.(map[string]interface{})
+		group, _ := backend["group"].(string)
+
+		if instanceGroups[lastPathComponent(group)] {
+			continue
+		}
+
+		drifts = append(drifts, Drift{
+			App: app, Resource: r.Name, Type: r.Type, Kind: DriftUnmanaged, Path: "backends", Actual: group,
+		})
+	}
+
+	return drifts
+}
+
+// driftValues appends the differences between the expected properties of a resource and the live
+// resource to the given list, with the expected value as Before and the live value as After. Only
+// the fields present in the expected properties are compared, since APIs return many more. Lists
+// of objects with names, keys, or priorities (e.g. WAF rules) are compared by those, other lists of
+// objects are compared index by index, and lists of values are compared as a whole.
+func driftValues(path string, want, got interface{}, diffs *[]FieldDiff) {
+	if writeOnlyFields[path] {
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = fmt.Sprintf("%s.%s", path, k)
+			}
+
+			driftValues(p, w[k], g[k], diffs)
+		}
+
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+
+		if key := listKey(w); key != "" {
+			driftKeyedLists(path, key, w, g, diffs)
+			return
+		}
+
+		if hasObjects(w) {
+			driftIndexedLists(path, w, g, diffs)
+			return
+		}
+	}
+
+	if !driftEqual(want, got) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Action: DiffChange, Before: want, After: got})
+	}
+}
+
+// driftKeyedLists compares two lists of objects by the given key.
+func driftKeyedLists(path, key string, want, got []interface{}, diffs *[]FieldDiff) {
+	byKey := make(map[string]interface{}, len(got))
+
+	for _, e := range got {
+		if obj, ok := e.(map[string]interface{}); ok {
+			byKey[driftValue(obj[key])] = obj
+		}
+	}
+
+	for _, e := range want {
+		k := driftValue(e.(map[string]interface{})[key])
+		driftValues(fmt.Sprintf("%s[%s=%s]", path, key, k), e, byKey[k], diffs)
+		delete(byKey, k)
+	}
+
+	// Report any extra objects, like WAF rules added by hand.
+	extra := make([]string, 0, len(byKey))
+	for k := range byKey {
+		extra = append(extra, k)
+	}
+
+	sort.Strings(extra)
+
+	for _, k := range extra {
+		*diffs = append(*diffs, FieldDiff{
+			Path: fmt.Sprintf("%s[%s=%s]", path, key, k), Action: DiffAdd, After: byKey[k],
+		})
+	}
+}
+
+// driftIndexedLists compares two lists of objects index by index.
+func driftIndexedLists(path string, want, got []interface{}, diffs *[]FieldDiff) {
+	for i, e := range want {
+		var g interface{}
+		if i < len(got) {
+			g = got[i]
+		}
+
+		driftValues(fmt.Sprintf("%s[%d]", path, i), e, g, diffs)
+	}
+
+	for i := len(want); i < len(got); i++ {
+		*diffs = append(*diffs, FieldDiff{Path: fmt.Sprintf("%s[%d]", path, i), Action: DiffAdd, After: got[i]})
+	}
+}
+
+// hasObjects returns true if the list contains any objects.
+func hasObjects(list []interface{}) bool {
+	for _, e := range list {
+		if _, ok := e.(map[string]interface{}); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listKey returns the name of the field which identifies each object in the list, if any.
+func listKey(list []interface{}) string {
+	for _, key := range []string{"name", "key", "priority"} {
+		found := len(list) > 0
+
+		for _, e := range list {
+			obj, ok := e.(map[string]interface{})
+			if !ok || obj[key] == nil {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// driftEqual returns true if the expected value matches the live value. Values with unresolved
+// references are assumed to match. Relative resource URLs (e.g. global/networks/default) and bare
+// resource names match the full URLs returned by the APIs.
+func driftEqual(want, got interface{}) bool {
+	w, g := driftValue(want), driftValue(got)
+
+	if w == g || deployments.Unresolved(w) {
+		return true
+	}
+
+	// Compare lists of strings element by element, so they can contain resource URLs.
+	if list, ok := want.([]interface{}); ok {
+		gl, _ := got.([]interface{})
+		if len(list) != len(gl) {
+			return false
+		}
+
+		for i := range list {
+			if !driftEqual(list[i], gl[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	_, ws := want.(string)
+	_, gs := got.(string)
+
+	return ws && gs && strings.HasPrefix(g, "https://") && strings.HasSuffix(g, "/"+w)
+}
+
+// driftValue returns strings as-is and other values as compact JSON.
+func driftValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(b)
+	}
+}
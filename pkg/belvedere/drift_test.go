@@ -0,0 +1,142 @@
+package belvedere
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+)
+
+//nolint:funlen // it's a big fixture
+func TestProject_Drift(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	lf := NewLiveFetcher(ctrl)
+
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "release" AND labels.belvedere-app eq "my-app"`).
+		Return([]deployments.Deployment{
+			{
+				Name:   "belvedere-my-app-v1",
+				Labels: deployments.Labels{App: "my-app", Release: "v1", Region: "us-central1"},
+			},
+		}, nil)
+
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-v1-ig",
+				Type:       "compute.v1.regionInstanceGroupManager",
+				Properties: json.RawMessage(`{"region":"us-central1","targetSize":"2"}`),
+			},
+		}, nil)
+
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app").
+		Return([]deployments.Resource{
+			{
+				Name: "my-app-bes",
+				Type: "compute.v1.backendService",
+				Properties: json.RawMessage(`{"healthChecks":["$(ref.my-app-hc.selfLink)"],` +
+					`"securityPolicy":"$(ref.my-app-waf.selfLink)","timeoutSec":"30"}`),
+			},
+			{
+				Name:       "my-app-hc",
+				Type:       "compute.v1.healthCheck",
+				Properties: json.RawMessage(`{"type":"HTTP2"}`),
+			},
+			{
+				Name:       "my-app-waf",
+				Type:       "compute.v1.securityPolicy",
+				Properties: json.RawMessage(`{"rules":[{"action":"allow","priority":2147483647}]}`),
+			},
+		}, nil)
+
+	live := map[string]map[string]interface{}{
+		"my-app-v1-ig": {
+			"region":     "https://www.googleapis.com/compute/v1/projects/my-project/regions/us-central1",
+			"targetSize": "2",
+		},
+		"my-app-waf": {
+			"selfLink": "https://www.googleapis.com/compute/v1/projects/my-project/global/securityPolicies/my-app-waf",
+			"rules": []interface{}{
+				map[string]interface{}{"action": "deny(403)", "priority": 1000.0},
+				map[string]interface{}{"action": "allow", "priority": 2147483647.0},
+			},
+		},
+		"my-app-bes": {
+			"healthChecks": []interface{}{"https://example.com/my-app-hc"},
+			"securityPolicy": "https://www.googleapis.com/compute/v1/projects/my-project/global/" +
+				"securityPolicies/my-app-waf",
+			"timeoutSec": "60",
+			"backends": []interface{}{
+				map[string]interface{}{"group": "https://example.com/regions/us-central1/instanceGroups/my-app-v1-ig"},
+				map[string]interface{}{"group": "https://example.com/regions/us-central1/instanceGroups/other"},
+			},
+		},
+	}
+
+	lf.EXPECT().
+		Fetch(gomock.Any(), "my-project", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, r *deployments.Resource) (map[string]interface{}, error) {
+			return live[r.Name], nil
+		}).
+		Times(4)
+
+	p := &project{
+		name:     "my-project",
+		dm:       dm,
+		live:     lf,
+		apps:     &appService{project: "my-project", dm: dm},
+		releases: &releaseService{project: "my-project", dm: dm},
+	}
+
+	got, err := p.Drift(context.Background(), "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Drift{
+		{
+			App:      "my-app",
+			Resource: "my-app-hc",
+			Type:     "compute.v1.healthCheck",
+			Kind:     DriftMissing,
+		},
+		{
+			App:      "my-app",
+			Resource: "my-app-waf",
+			Type:     "compute.v1.securityPolicy",
+			Kind:     DriftChanged,
+			Path:     "rules[priority=1000]",
+			Actual:   `{"action":"deny(403)","priority":1000}`,
+		},
+		{
+			App:      "my-app",
+			Resource: "my-app-bes",
+			Type:     "compute.v1.backendService",
+			Kind:     DriftChanged,
+			Path:     "timeoutSec",
+			Expected: "30",
+			Actual:   "60",
+		},
+		{
+			App:      "my-app",
+			Resource: "my-app-bes",
+			Type:     "compute.v1.backendService",
+			Kind:     DriftUnmanaged,
+			Path:     "backends",
+			Actual:   "https://example.com/regions/us-central1/instanceGroups/other",
+		},
+	}
+
+	assert.Equal(t, "Drift()", want, got)
+}
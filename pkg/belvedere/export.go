@@ -0,0 +1,45 @@
+package belvedere
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/terraform"
+	"go.opencensus.io/trace"
+)
+
+func (p *project) ExportTerraform(ctx context.Context, app, release string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.project.ExportTerraform")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("release", release),
+	)
+
+	name := resources.Name(app)
+	if release != "" {
+		name = resources.Name(app, release)
+	}
+
+	res, err := p.dm.Resources(ctx, p.name, name)
+	if err != nil {
+		return "", fmt.Errorf("error getting deployment %s: %w", name, err)
+	}
+
+	// Find the resources which already exist, with their references resolved, so they can be
+	// imported.
+	existing := map[string]map[string]interface{}{}
+
+	if _, err := p.fetchLive(ctx, res, func(r *deployments.Resource, props interface{}, obj map[string]interface{}) {
+		if m, ok := props.(map[string]interface{}); ok && obj != nil {
+			existing[r.Name] = m
+		}
+	}); err != nil {
+		return "", err
+	}
+
+	return terraform.Render(p.name, res, existing)
+}
@@ -0,0 +1,82 @@
+package belvedere
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+)
+
+func TestProject_ExportTerraform(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	lf := NewLiveFetcher(ctrl)
+
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-v1-ig",
+				Type:       "compute.v1.regionInstanceGroupManager",
+				Properties: json.RawMessage(`{"instanceTemplate":"$(ref.my-app-v1-it.selfLink)","region":"us-central1"}`),
+			},
+			{
+				Name:       "my-app-v1-it",
+				Type:       "compute.v1.instanceTemplate",
+				Properties: json.RawMessage(`{"properties":{"machineType":"n1-standard-1"}}`),
+			},
+		}, nil)
+
+	// Only the instance template exists.
+	lf.EXPECT().
+		Fetch(gomock.Any(), "my-project", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, r *deployments.Resource) (map[string]interface{}, error) {
+			if r.Name == "my-app-v1-it" {
+				return map[string]interface{}{"selfLink": "https://example.com/my-app-v1-it"}, nil
+			}
+
+			return nil, nil
+		}).
+		Times(2)
+
+	p := &project{
+		name: "my-project",
+		dm:   dm,
+		live: lf,
+	}
+
+	got, err := p.ExportTerraform(context.Background(), "my-app", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `resource "google_compute_region_instance_group_manager" "my-app-v1-ig" {
+  name    = "my-app-v1-ig"
+  project = "my-project"
+  region  = "us-central1"
+  version {
+    instance_template = google_compute_instance_template.my-app-v1-it.self_link
+  }
+}
+
+resource "google_compute_instance_template" "my-app-v1-it" {
+  machine_type = "n1-standard-1"
+  name         = "my-app-v1-it"
+  project      = "my-project"
+}
+
+import {
+  to = google_compute_instance_template.my-app-v1-it
+  id = "projects/my-project/global/instanceTemplates/my-app-v1-it"
+}
+`
+
+	assert.Equal(t, "ExportTerraform()", want, got)
+}
@@ -0,0 +1,57 @@
+package belvedere
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
+	"go.opencensus.io/trace"
+)
+
+// ImageService provides methods for working with Docker images. Images hosted in Google Container
+// Registry or Google Artifact Registry are accessed with the project's credentials; images hosted
+// elsewhere must be public.
+type ImageService interface {
+	// Digest returns the SHA-256 digest of the given image reference (e.g. gcr.io/project/app:v1),
+	// resolving its tag via the registry.
+	Digest(ctx context.Context, image string) (string, error)
+
+	// Pin returns the given image reference pinned to the digest its tag currently refers to.
+	Pin(ctx context.Context, image string) (string, error)
+}
+
+type imageService struct {
+	registry registry.Client
+}
+
+var _ ImageService = &imageService{}
+
+func (s *imageService) Digest(ctx context.Context, image string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.images.Digest")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("image", image))
+
+	return s.registry.Digest(ctx, image)
+}
+
+func (s *imageService) Pin(ctx context.Context, image string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.images.Pin")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("image", image))
+
+	digest, err := s.registry.Digest(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	return pinImage(image, digest), nil
+}
+
+// pinImage returns the given image reference pinned to the given digest.
+func pinImage(image, digest string) string {
+	host, repository, _ := registry.Parse(image)
+
+	return fmt.Sprintf("%s/%s@sha256:%s", host, repository, digest)
+}
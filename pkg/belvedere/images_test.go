@@ -0,0 +1,51 @@
+package belvedere
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+)
+
+func TestImageService_Digest(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rc := NewRegistryClient(ctrl)
+	rc.EXPECT().
+		Digest(gomock.Any(), "gcr.io/my-project/my-app:v1").
+		Return("abcdef", nil)
+
+	service := &imageService{registry: rc}
+
+	got, err := service.Digest(context.Background(), "gcr.io/my-project/my-app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Digest()", "abcdef", got)
+}
+
+func TestImageService_Pin(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rc := NewRegistryClient(ctrl)
+	rc.EXPECT().
+		Digest(gomock.Any(), "us-docker.pkg.dev/my-project/repo/nginx:1.19").
+		Return("abcdef", nil)
+
+	service := &imageService{registry: rc}
+
+	got, err := service.Pin(context.Background(), "us-docker.pkg.dev/my-project/repo/nginx:1.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Pin()", "us-docker.pkg.dev/my-project/repo/nginx@sha256:abcdef", got)
+}
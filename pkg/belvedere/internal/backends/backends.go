@@ -113,7 +113,7 @@ func (s *service) Add(
 	return waiter.Poll(ctx, interval, check.GCE(ctx, s.gce, project, op.Name))
 }
 
-//nolint: gocognit // this is complex logic
+//nolint:gocognit // this is complex logic
 func (s *service) Remove(
 	ctx context.Context, project, region, backendService, instanceGroup string, dryRun bool,
 	interval time.Duration,
@@ -0,0 +1,134 @@
+// Package buckets manages the Cloud Storage buckets in which Belvedere stores a project's state.
+// Bucket names are global, so each bucket is checked to belong to the project before it's used,
+// lest anyone who claims a bucket's name first receive (or provide) the project's state.
+package buckets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+// A Manager checks that buckets belong to their projects, and creates them if needed.
+type Manager struct {
+	gcs *storage.Service
+	crm *cloudresourcemanager.Service
+
+	mu    sync.Mutex
+	owned map[string]bool // buckets known to belong to their projects
+}
+
+// NewManager returns a new Manager.
+func NewManager(gcs *storage.Service, crm *cloudresourcemanager.Service) *Manager {
+	return &Manager{gcs: gcs, crm: crm, owned: map[string]bool{}}
+}
+
+type ForeignBucketError struct {
+	Bucket  string
+	Project string
+}
+
+func (e *ForeignBucketError) Error() string {
+	return fmt.Sprintf("bucket %s doesn't belong to project %s", e.Bucket, e.Project)
+}
+
+// Exists returns whether the given bucket exists. If it does, and it doesn't belong to the given
+// project, it returns a ForeignBucketError.
+func (m *Manager) Exists(ctx context.Context, project, bucket string) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.buckets.Exists")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("bucket", bucket),
+	)
+
+	if m.isOwned(bucket) {
+		return true, nil
+	}
+
+	b, err := m.gcs.Buckets.Get(bucket).Fields("projectNumber").Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("error getting bucket: %w", err)
+	}
+
+	p, err := m.crm.Projects.Get(project).Fields("projectNumber").Context(ctx).Do()
+	if err != nil {
+		return false, fmt.Errorf("error getting project: %w", err)
+	}
+
+	if p.ProjectNumber <= 0 || b.ProjectNumber != uint64(p.ProjectNumber) {
+		return false, &ForeignBucketError{Bucket: bucket, Project: project}
+	}
+
+	m.setOwned(bucket)
+
+	return true, nil
+}
+
+// Ensure creates the given bucket in the given project, with uniform bucket-level access and object
+// versioning, unless it already exists. If it does, and it doesn't belong to the given project, it
+// returns a ForeignBucketError.
+func (m *Manager) Ensure(ctx context.Context, project, bucket string) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.buckets.Ensure")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("bucket", bucket),
+	)
+
+	exists, err := m.Exists(ctx, project, bucket)
+	if err != nil || exists {
+		return err
+	}
+
+	// If someone else claims the bucket's name in the meantime, this fails with a 409 Conflict.
+	if _, err := m.gcs.Buckets.Insert(project, &storage.Bucket{
+		Name: bucket,
+		IamConfiguration: &storage.BucketIamConfiguration{
+			UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{
+				Enabled: true,
+			},
+		},
+		Versioning: &storage.BucketVersioning{
+			Enabled: true,
+		},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error creating bucket: %w", err)
+	}
+
+	m.setOwned(bucket)
+
+	return nil
+}
+
+func (m *Manager) isOwned(bucket string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.owned[bucket]
+}
+
+func (m *Manager) setOwned(bucket string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.owned[bucket] = true
+}
+
+func isNotFound(err error) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,126 @@
+package buckets
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+const (
+	bucketURL  = `/b/my-bucket?alt=json&fields=projectNumber&prettyPrint=false`
+	projectURL = `/v1/projects/my-project?alt=json&fields=projectNumber&prettyPrint=false`
+)
+
+func newManager(t *testing.T, srv *httpmock.Server) *Manager {
+	t.Helper()
+
+	opts := []option.ClientOption{
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	}
+
+	gcs, err := storage.NewService(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crm, err := cloudresourcemanager.NewService(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewManager(gcs, crm)
+}
+
+func TestManager_Exists(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	// The bucket is only checked once.
+	srv.Expect(bucketURL, httpmock.RespJSON(storage.Bucket{ProjectNumber: 123456}))
+	srv.Expect(projectURL, httpmock.RespJSON(cloudresourcemanager.Project{ProjectNumber: 123456}))
+
+	m := newManager(t, srv)
+
+	for i := 0; i < 2; i++ {
+		exists, err := m.Exists(context.Background(), "my-project", "my-bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "Exists()", true, exists)
+	}
+}
+
+func TestManager_Exists_Missing(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(bucketURL, httpmock.Status(http.StatusNotFound))
+
+	m := newManager(t, srv)
+
+	exists, err := m.Exists(context.Background(), "my-project", "my-bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Exists()", false, exists)
+}
+
+func TestManager_Exists_Foreign(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(bucketURL, httpmock.RespJSON(storage.Bucket{ProjectNumber: 666}))
+	srv.Expect(projectURL, httpmock.RespJSON(cloudresourcemanager.Project{ProjectNumber: 123456}))
+
+	m := newManager(t, srv)
+
+	_, err := m.Exists(context.Background(), "my-project", "my-bucket")
+	assert.Equal(t, "Exists() error", &ForeignBucketError{Bucket: "my-bucket", Project: "my-project"}, err)
+}
+
+func TestManager_Ensure(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(bucketURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/b?alt=json&prettyPrint=false&project=my-project`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(storage.Bucket{
+			Name: "my-bucket",
+			IamConfiguration: &storage.BucketIamConfiguration{
+				UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{
+					Enabled: true,
+				},
+			},
+			Versioning: &storage.BucketVersioning{
+				Enabled: true,
+			},
+		}),
+		httpmock.RespJSON(storage.Bucket{}))
+
+	m := newManager(t, srv)
+
+	// The bucket isn't checked again once it's created.
+	for i := 0; i < 2; i++ {
+		if err := m.Ensure(context.Background(), "my-project", "my-bucket"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
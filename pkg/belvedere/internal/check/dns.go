@@ -0,0 +1,35 @@
+package check
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/waiter"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/dns/v1"
+)
+
+// DNS returns a waiter.Condition for the given Cloud DNS change completing.
+func DNS(ctx context.Context, ds *dns.Service, project, managedZone, change string) waiter.Condition {
+	return func() (bool, error) {
+		ctx, span := trace.StartSpan(ctx, "belvedere.internal.check.DNS")
+		defer span.End()
+
+		span.AddAttributes(
+			trace.StringAttribute("project", project),
+			trace.StringAttribute("managed_zone", managedZone),
+			trace.StringAttribute("change", change),
+		)
+
+		// Fetch the change's status.
+		c, err := ds.Changes.Get(project, managedZone, change).Context(ctx).Fields("status").Do()
+		if err != nil {
+			return false, fmt.Errorf("error getting change: %w", err)
+		}
+
+		span.AddAttributes(trace.StringAttribute("status", c.Status))
+
+		// Keep waiting unless the change is done.
+		return c.Status == "done", nil
+	}
+}
@@ -0,0 +1,51 @@
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/option"
+)
+
+func TestDNS(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/dns/v1/projects/example/managedZones/belvedere/changes/c1?alt=json&fields=status&prettyPrint=false`,
+		httpmock.RespJSON(dns.Change{
+			Status: "pending",
+		}))
+
+	srv.Expect(`/dns/v1/projects/example/managedZones/belvedere/changes/c1?alt=json&fields=status&prettyPrint=false`,
+		httpmock.RespJSON(dns.Change{
+			Status: "done",
+		}))
+
+	ds, err := dns.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := DNS(context.Background(), ds, "example", "belvedere", "c1")()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "pending", false, done)
+
+	done, err = DNS(context.Background(), ds, "example", "belvedere", "c1")()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "done", true, done)
+}
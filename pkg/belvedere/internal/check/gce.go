@@ -38,3 +38,34 @@ func GCE(ctx context.Context, gce *compute.Service, project, operation string) w
 		return op.Status == "DONE", nil
 	}
 }
+
+// GCERegion returns a waiter.Condition for the given regional Compute Engine operation completing.
+func GCERegion(ctx context.Context, gce *compute.Service, project, region, operation string) waiter.Condition {
+	return func() (bool, error) {
+		ctx, span := trace.StartSpan(ctx, "belvedere.internal.check.GCERegion")
+		defer span.End()
+
+		span.AddAttributes(
+			trace.StringAttribute("project", project),
+			trace.StringAttribute("region", region),
+			trace.StringAttribute("operation", operation),
+		)
+
+		// Fetch the operation's status and any errors.
+		op, err := gce.RegionOperations.Get(project, region, operation).Context(ctx).
+			Fields("status", "error").Do()
+		if err != nil {
+			return false, fmt.Errorf("error getting operation: %w", err)
+		}
+
+		span.AddAttributes(trace.StringAttribute("status", op.Status))
+
+		// Check for errors in the operation.
+		if op.Error != nil {
+			return false, &failedOperationError{Message: op.Error}
+		}
+
+		// Keep waiting unless the operation is done.
+		return op.Status == "DONE", nil
+	}
+}
@@ -84,3 +84,31 @@ func TestGCE(t *testing.T) {
 		})
 	}
 }
+
+func TestGCERegion(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/example/regions/us-central1/operations/op1?alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(compute.Operation{
+			Status: "DONE",
+		}))
+
+	gce, err := compute.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := GCERegion(context.Background(), gce, "example", "us-central1", "op1")()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "done", true, done)
+}
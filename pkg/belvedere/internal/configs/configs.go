@@ -0,0 +1,291 @@
+// Package configs stores the configurations which apps and releases were deployed with, so they can
+// be retrieved later, and the project's named IP lists.
+package configs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/buckets"
+	"github.com/ghodss/yaml"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+// Store stores the configurations of deployments as YAML objects in the project's config bucket,
+// which keeps previous versions.
+type Store interface {
+	// Put records the given configuration for the given deployment, creating the project's config
+	// bucket if needed.
+	Put(ctx context.Context, project, name string, config *cfg.Config) error
+
+	// Get returns the configuration which was last recorded for the given deployment.
+	Get(ctx context.Context, project, name string) (*cfg.Config, error)
+
+	// Delete removes the configuration of the given deployment, if any. Previous versions of it are
+	// kept by the bucket.
+	Delete(ctx context.Context, project, name string) error
+
+	// PutIPList records the given ranges as the project's IP list with the given name, creating the
+	// project's config bucket if needed.
+	PutIPList(ctx context.Context, project, name string, ranges []string) error
+
+	// GetIPList returns the ranges of the project's IP list with the given name.
+	GetIPList(ctx context.Context, project, name string) ([]string, error)
+
+	// ListIPLists returns the names of the project's IP lists, in order.
+	ListIPLists(ctx context.Context, project string) ([]string, error)
+}
+
+// NewStore returns a new Store implementation.
+func NewStore(ctx context.Context, opts ...option.ClientOption) (Store, error) {
+	gcs, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	crm, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{gcs: gcs, buckets: buckets.NewManager(gcs, crm)}, nil
+}
+
+type store struct {
+	gcs     *storage.Service
+	buckets *buckets.Manager
+}
+
+var _ Store = &store{}
+
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no config found for %s", e.Name)
+}
+
+type IPListNotFoundError struct {
+	Name string
+}
+
+func (e *IPListNotFoundError) Error() string {
+	return fmt.Sprintf("no IP list named %s", e.Name)
+}
+
+func (s *store) Put(ctx context.Context, project, name string, config *cfg.Config) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.Put")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	b, err := cfg.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return s.put(ctx, project, object(name), b)
+}
+
+func (s *store) Get(ctx context.Context, project, name string) (*cfg.Config, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.Get")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	exists, err := s.buckets.Exists(ctx, project, bucket(project))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, &NotFoundError{Name: name}
+	}
+
+	resp, err := s.gcs.Objects.Get(bucket(project), object(name)).Context(ctx).Download()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &NotFoundError{Name: name}
+		}
+
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	return cfg.Parse(resp.Body)
+}
+
+func (s *store) Delete(ctx context.Context, project, name string) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.Delete")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	if exists, err := s.buckets.Exists(ctx, project, bucket(project)); err != nil || !exists {
+		return err
+	}
+
+	if err := s.gcs.Objects.Delete(bucket(project), object(name)).Context(ctx).Do(); err != nil &&
+		!isNotFound(err) {
+		return fmt.Errorf("error deleting config: %w", err)
+	}
+
+	return nil
+}
+
+func (s *store) PutIPList(ctx context.Context, project, name string, ranges []string) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.PutIPList")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	b, err := yaml.Marshal(&ipList{Ranges: ranges})
+	if err != nil {
+		return err
+	}
+
+	return s.put(ctx, project, ipListObject(name), b)
+}
+
+func (s *store) GetIPList(ctx context.Context, project, name string) ([]string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.GetIPList")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	exists, err := s.buckets.Exists(ctx, project, bucket(project))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, &IPListNotFoundError{Name: name}
+	}
+
+	resp, err := s.gcs.Objects.Get(bucket(project), ipListObject(name)).Context(ctx).Download()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &IPListNotFoundError{Name: name}
+		}
+
+		return nil, fmt.Errorf("error reading IP list: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading IP list: %w", err)
+	}
+
+	var list ipList
+	if err := yaml.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("error parsing IP list: %w", err)
+	}
+
+	return list.Ranges, nil
+}
+
+func (s *store) ListIPLists(ctx context.Context, project string) ([]string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.configs.ListIPLists")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+	)
+
+	// Projects without a config bucket have no IP lists.
+	exists, err := s.buckets.Exists(ctx, project, bucket(project))
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var names []string
+
+	if err := s.gcs.Objects.List(bucket(project)).Prefix(ipListPrefix).Fields("nextPageToken", "items/name").
+		Pages(ctx, func(objects *storage.Objects) error {
+			for _, o := range objects.Items {
+				names = append(names, strings.TrimSuffix(strings.TrimPrefix(o.Name, ipListPrefix), ".yaml"))
+			}
+
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("error listing IP lists: %w", err)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ipList is the stored representation of an IP list.
+type ipList struct {
+	Ranges []string `json:"ranges"`
+}
+
+// put writes the given YAML object to the project's config bucket, creating the bucket if needed.
+func (s *store) put(ctx context.Context, project, name string, b []byte) error {
+	if err := s.buckets.Ensure(ctx, project, bucket(project)); err != nil {
+		return err
+	}
+
+	if _, err := s.gcs.Objects.Insert(bucket(project), &storage.Object{
+		Name:        name,
+		ContentType: "application/yaml",
+	}).Media(bytes.NewReader(b)).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+
+	return nil
+}
+
+// bucket returns the name of the project's config bucket.
+func bucket(project string) string {
+	return fmt.Sprintf("%s-belvedere-configs", project)
+}
+
+// object returns the name of the deployment's config object.
+func object(name string) string {
+	return fmt.Sprintf("%s.yaml", name)
+}
+
+// ipListPrefix is the prefix of the names of IP list objects.
+const ipListPrefix = "iplists/"
+
+// ipListObject returns the name of the IP list's object.
+func ipListObject(name string) string {
+	return fmt.Sprintf("%s%s.yaml", ipListPrefix, name)
+}
+
+func isNotFound(err error) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound
+}
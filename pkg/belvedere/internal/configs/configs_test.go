@@ -0,0 +1,277 @@
+package configs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/buckets"
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+const (
+	bucketURL = `/b/my-project-belvedere-configs?alt=json&fields=projectNumber&prettyPrint=false`
+	objectURL = `/b/my-project-belvedere-configs/o/my-app.yaml?alt=media&prettyPrint=false`
+	uploadURL = `/upload/storage/v1/b/my-project-belvedere-configs/o?alt=json&prettyPrint=false&uploadType=multipart`
+)
+
+func newStore(t *testing.T, srv *httpmock.Server) Store {
+	t.Helper()
+
+	s, err := NewStore(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// expectBucket expects the project's config bucket to be checked.
+func expectBucket(srv *httpmock.Server) {
+	srv.Expect(bucketURL, httpmock.RespJSON(storage.Bucket{ProjectNumber: 123456}))
+	srv.Expect(`/v1/projects/my-project?alt=json&fields=projectNumber&prettyPrint=false`,
+		httpmock.RespJSON(cloudresourcemanager.Project{ProjectNumber: 123456}))
+}
+
+func TestStore_Put(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	// The config bucket doesn't exist yet.
+	srv.Expect(bucketURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/b?alt=json&prettyPrint=false&project=my-project`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(storage.Bucket{
+			Name: "my-project-belvedere-configs",
+			IamConfiguration: &storage.BucketIamConfiguration{
+				UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{
+					Enabled: true,
+				},
+			},
+			Versioning: &storage.BucketVersioning{
+				Enabled: true,
+			},
+		}),
+		httpmock.RespJSON(storage.Bucket{}))
+	srv.Expect(uploadURL,
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{}))
+
+	s := newStore(t, srv)
+
+	if err := s.Put(context.Background(), "my-project", "my-app", &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 2,
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_Get(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	// YAML is a superset of JSON.
+	srv.Expect(objectURL,
+		httpmock.RespJSON(map[string]interface{}{
+			"apiVersion":  cfg.CurrentVersion,
+			"numReplicas": 2,
+			"container": map[string]interface{}{
+				"image": "gcr.io/my-project/my-app",
+			},
+		}))
+
+	s := newStore(t, srv)
+
+	got, err := s.Get(context.Background(), "my-project", "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Get()",
+		&cfg.Config{
+			APIVersion: cfg.CurrentVersion,
+			ReleaseConfig: cfg.ReleaseConfig{
+				NumReplicas: 2,
+				Container: cfg.Container{
+					Image: "gcr.io/my-project/my-app",
+				},
+			},
+		},
+		got)
+}
+
+func TestStore_Get_NotFound(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(objectURL, httpmock.Status(http.StatusNotFound))
+
+	s := newStore(t, srv)
+
+	_, err := s.Get(context.Background(), "my-project", "my-app")
+
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Get() error = %v, want a NotFoundError", err)
+	}
+
+	assert.Equal(t, "Get() error", "no config found for my-app", err.Error())
+}
+
+func TestStore_Delete(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(`/b/my-project-belvedere-configs/o/my-app.yaml?alt=json&prettyPrint=false`,
+		httpmock.Method(http.MethodDelete),
+		httpmock.Status(http.StatusNotFound))
+
+	s := newStore(t, srv)
+
+	if err := s.Delete(context.Background(), "my-project", "my-app"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_PutIPList(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(uploadURL,
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{}))
+
+	s := newStore(t, srv)
+
+	if err := s.PutIPList(context.Background(), "my-project", "office",
+		[]string{"203.0.113.0/24", "198.51.100.7"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_GetIPList(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(`/b/my-project-belvedere-configs/o/iplists%2Foffice.yaml?alt=media&prettyPrint=false`,
+		httpmock.RespJSON(map[string]interface{}{
+			"ranges": []string{"203.0.113.0/24", "198.51.100.7"},
+		}))
+
+	s := newStore(t, srv)
+
+	got, err := s.GetIPList(context.Background(), "my-project", "office")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "GetIPList()", []string{"203.0.113.0/24", "198.51.100.7"}, got)
+}
+
+func TestStore_GetIPList_NotFound(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(`/b/my-project-belvedere-configs/o/iplists%2Foffice.yaml?alt=media&prettyPrint=false`,
+		httpmock.Status(http.StatusNotFound))
+
+	s := newStore(t, srv)
+
+	_, err := s.GetIPList(context.Background(), "my-project", "office")
+
+	var notFound *IPListNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("GetIPList() error = %v, want an IPListNotFoundError", err)
+	}
+
+	assert.Equal(t, "GetIPList() error", "no IP list named office", err.Error())
+}
+
+func TestStore_ListIPLists(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(`/b/my-project-belvedere-configs/o?alt=json&fields=nextPageToken%2Citems%2Fname`+
+		`&prefix=iplists%2F&prettyPrint=false`,
+		httpmock.RespJSON(storage.Objects{
+			Items: []*storage.Object{
+				{Name: "iplists/office.yaml"},
+				{Name: "iplists/bad-actors.yaml"},
+			},
+		}))
+
+	s := newStore(t, srv)
+
+	got, err := s.ListIPLists(context.Background(), "my-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "ListIPLists()", []string{"bad-actors", "office"}, got)
+}
+
+func TestStore_Get_ForeignBucket(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	// Someone else claimed the config bucket's name.
+	srv.Expect(bucketURL, httpmock.RespJSON(storage.Bucket{ProjectNumber: 666}))
+	srv.Expect(`/v1/projects/my-project?alt=json&fields=projectNumber&prettyPrint=false`,
+		httpmock.RespJSON(cloudresourcemanager.Project{ProjectNumber: 123456}))
+
+	s := newStore(t, srv)
+
+	_, err := s.Get(context.Background(), "my-project", "my-app")
+
+	var foreign *buckets.ForeignBucketError
+	if !errors.As(err, &foreign) {
+		t.Fatalf("Get() error = %v, want a ForeignBucketError", err)
+	}
+}
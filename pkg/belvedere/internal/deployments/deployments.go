@@ -4,14 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"sort"
+	"text/tabwriter"
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere/internal/check"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/waiter"
 	"go.opencensus.io/trace"
+	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/deploymentmanager/v2"
 	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/oauth2/v2"
 	"google.golang.org/api/option"
 )
 
@@ -33,6 +39,16 @@ type Resource struct {
 	Properties json.Marshaler `json:"properties"`
 }
 
+// DecodeProperties decodes the resource's properties into the given value.
+func (r *Resource) DecodeProperties(v interface{}) error {
+	b, err := r.Properties.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
 // ServiceAccount represents an IAM service account. This is its own type because Deployment Manager
 // doesn't accept the standard API representation.
 type ServiceAccount struct {
@@ -86,23 +102,96 @@ func (b *IAMMemberBinding) MarshalJSON() ([]byte, error) {
 
 var _ json.Marshaler = &IAMMemberBinding{}
 
+// SecurityPolicy represents a Cloud Armor security policy. This is its own type because the standard
+// API representation doesn't support rate limiting rules.
+type SecurityPolicy struct {
+	Description string                `json:"description,omitempty"`
+	Rules       []*SecurityPolicyRule `json:"rules"`
+}
+
+// MarshalJSON marshals the security policy as a JSON object.
+func (sp *SecurityPolicy) MarshalJSON() ([]byte, error) {
+	// Cast from a pointer to a raw type to avoid infinite recursion while reusing the standard JSON
+	// marshalling code.
+	type NoMethod SecurityPolicy
+	return json.Marshal(NoMethod(*sp))
+}
+
+var _ json.Marshaler = &SecurityPolicy{}
+
+// SecurityPolicyRule represents a Cloud Armor security policy rule, which may limit the rate of
+// requests it matches.
+type SecurityPolicyRule struct {
+	*compute.SecurityPolicyRule
+	RateLimitOptions *RateLimitOptions `json:"rateLimitOptions,omitempty"`
+}
+
+// MarshalJSON marshals the security policy rule as a JSON object.
+func (r *SecurityPolicyRule) MarshalJSON() ([]byte, error) {
+	b, err := r.SecurityPolicyRule.MarshalJSON()
+	if err != nil || r.RateLimitOptions == nil {
+		return b, err
+	}
+
+	var rule map[string]interface{}
+	if err := json.Unmarshal(b, &rule); err != nil {
+		return nil, err
+	}
+
+	rule["rateLimitOptions"] = r.RateLimitOptions
+
+	return json.Marshal(rule)
+}
+
+var _ json.Marshaler = &SecurityPolicyRule{}
+
+// RateLimitOptions are the options of a throttle or rate_based_ban security policy rule.
+type RateLimitOptions struct {
+	ConformAction      string              `json:"conformAction"`
+	ExceedAction       string              `json:"exceedAction"`
+	EnforceOnKey       string              `json:"enforceOnKey"`
+	EnforceOnKeyName   string              `json:"enforceOnKeyName,omitempty"`
+	RateLimitThreshold *RateLimitThreshold `json:"rateLimitThreshold"`
+	BanDurationSec     int64               `json:"banDurationSec,omitempty"`
+}
+
+// RateLimitThreshold is the number of requests allowed from each client in an interval.
+type RateLimitThreshold struct {
+	Count       int64 `json:"count"`
+	IntervalSec int64 `json:"intervalSec"`
+}
+
 // deploymentConfig is a configuration target for Deployment Manager.
 type deploymentConfig struct {
 	Resources []Resource `json:"resources"`
 }
 
+// Config returns the given resources as a pretty-printed Deployment Manager configuration.
+func Config(resources []Resource) (string, error) {
+	b, err := json.MarshalIndent(deploymentConfig{Resources: resources}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error generating JSON: %w", err)
+	}
+
+	return string(b), nil
+}
+
 // Labels are the set of possible deployment labels in use.
 type Labels struct {
-	Type    string
-	Region  string
-	App     string
-	Release string
-	Hash    string
+	Type      string
+	Region    string
+	App       string
+	Release   string
+	Hash      string
+	BootImage string
 }
 
 // Deployment represents a Belvedere-managed DM deployment.
 type Deployment struct {
-	Name string
+	Name    string
+	Created time.Time
+	// Creator is the account which created the deployment, if it's known.
+	Creator string
 	Labels
 }
 
@@ -111,15 +200,19 @@ type Manager interface {
 	// Get returns the deployment with the given name.
 	Get(ctx context.Context, project, name string) (*Deployment, error)
 
-	// Insert inserts a new deployment with the given name, resources, and labels.
+	// Insert inserts a new deployment with the given name, resources, and labels. If preview is
+	// true, Deployment Manager validates the deployment and plans its changes, which are printed,
+	// and then the preview is cancelled.
 	Insert(
-		ctx context.Context, project, name string, resources []Resource, labels Labels, dryRun bool,
-		interval time.Duration,
+		ctx context.Context, project, name string, resources []Resource, labels Labels,
+		dryRun, preview bool, interval time.Duration,
 	) error
 
-	// Update patches the given deployment to add, remove, or modify resources.
+	// Update patches the given deployment to add, remove, or modify resources. If preview is true,
+	// Deployment Manager validates the changes and plans them, which are printed, and then the
+	// preview is cancelled.
 	Update(
-		ctx context.Context, project, name string, resources []Resource, dryRun bool,
+		ctx context.Context, project, name string, resources []Resource, dryRun, preview bool,
 		interval time.Duration,
 	) error
 
@@ -128,6 +221,58 @@ type Manager interface {
 
 	// List returns a list of deployments in the project which match the given filter.
 	List(ctx context.Context, project, filter string) ([]Deployment, error)
+
+	// Resources returns the resources in the given deployment's current manifest. Their properties
+	// are raw JSON, and should be decoded with DecodeProperties.
+	Resources(ctx context.Context, project, name string) ([]Resource, error)
+
+	// Status returns the state of the given deployment's most recent operation and of any resources
+	// with pending or failed changes.
+	Status(ctx context.Context, project, name string) (*Status, error)
+
+	// Stop stops the given deployment's running operation. Changes which have already been made are
+	// not rolled back.
+	Stop(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error
+
+	// CancelPreview cancels and removes the given deployment's outstanding preview.
+	CancelPreview(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error
+
+	// Restore updates the given deployment with the last manifest which was successfully deployed.
+	Restore(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error
+
+	// Abandon deletes the given deployment but leaves its resources in place.
+	Abandon(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error
+}
+
+// Status describes the state of a deployment.
+type Status struct {
+	// Operation is the type of the deployment's most recent operation (e.g. insert, update).
+	Operation string
+	// State is the state of the most recent operation: PENDING, RUNNING, or DONE.
+	State string
+	// Errors are the errors which caused the most recent operation to fail, if any.
+	Errors []string
+	// Previewing is true if the deployment has an outstanding preview.
+	Previewing bool
+	// Manifest is the name of the last manifest which was successfully deployed, if any.
+	Manifest string
+	// Resources are the deployment's resources which have pending or failed changes.
+	Resources []ResourceStatus
+}
+
+// Healthy returns true if the deployment's most recent operation succeeded and it has no pending or
+// failed changes.
+func (s *Status) Healthy() bool {
+	return s.State == "DONE" && len(s.Errors) == 0 && !s.Previewing && len(s.Resources) == 0
+}
+
+// ResourceStatus describes the state of a deployment resource with pending or failed changes.
+type ResourceStatus struct {
+	Name string
+	Type string
+	// State is the state of the change: PENDING, IN_PROGRESS, IN_PREVIEW, FAILED, or ABORTED.
+	State  string
+	Errors []string
 }
 
 func NewManager(ctx context.Context, opts ...option.ClientOption) (Manager, error) {
@@ -136,11 +281,37 @@ func NewManager(ctx context.Context, opts ...option.ClientOption) (Manager, erro
 		return nil, err
 	}
 
-	return &manager{dm: dm}, nil
+	us, err := oauth2.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{dm: dm, users: us, out: os.Stdout}, nil
 }
 
 type manager struct {
-	dm *deploymentmanager.Service
+	dm    *deploymentmanager.Service
+	users *oauth2.Service
+	out   io.Writer // where previews are printed
+}
+
+// Caller returns the email address of the account the given service is authenticated as, which is
+// recorded as the creator of new deployments. If it can't be found, an empty string is returned with
+// a warning, since that's no reason to fail the deployment.
+func Caller(ctx context.Context, users *oauth2.Service) string {
+	info, err := users.Userinfo.Get().Context(ctx).Do()
+	if err != nil {
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("error", err.Error()),
+			},
+			"Couldn't find the account to record as the deployment's creator",
+		)
+
+		return ""
+	}
+
+	return info.Email
 }
 
 func (m *manager) Get(ctx context.Context, project, name string) (*Deployment, error) {
@@ -157,15 +328,14 @@ func (m *manager) Get(ctx context.Context, project, name string) (*Deployment, e
 		return nil, err
 	}
 
-	return &Deployment{
-		Name:   d.Name,
-		Labels: entriesToLabels(d.Labels),
-	}, nil
+	dep := toDeployment(d)
+
+	return &dep, nil
 }
 
 func (m *manager) Insert(
-	ctx context.Context, project, name string, resources []Resource, labels Labels, dryRun bool,
-	interval time.Duration,
+	ctx context.Context, project, name string, resources []Resource, labels Labels,
+	dryRun, preview bool, interval time.Duration,
 ) error {
 	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Insert")
 	defer span.End()
@@ -174,49 +344,61 @@ func (m *manager) Insert(
 		trace.StringAttribute("project", project),
 		trace.StringAttribute("name", name),
 		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
 	)
 
-	// Create our config target.
-	d := deploymentConfig{Resources: resources}
-
 	// Pretty-print the config and early exit if we don't want side effects.
 	if dryRun {
-		b, err := json.MarshalIndent(d, "", "  ")
+		config, err := Config(resources)
 		if err != nil {
-			return fmt.Errorf("error generating JSON: %w", err)
+			return err
 		}
 
-		fmt.Println(string(b))
+		fmt.Println(config)
 
 		return nil
 	}
 
+	// Create our config target.
+	d := deploymentConfig{Resources: resources}
+
 	// Marshal the config target as JSON, since that's parsable by Deployment Manager.
 	j, err := json.Marshal(d)
 	if err != nil {
 		return fmt.Errorf("error generating JSON: %w", err)
 	}
 
-	// Insert the new deployment.
-	op, err := m.dm.Deployments.Insert(project, &deploymentmanager.Deployment{
-		Labels: labelsToEntries(&labels),
-		Name:   name,
+	// Insert the new deployment. DM only records the user who ran a deployment's most recent
+	// operation, so its creator is recorded in its description, which updates leave as is.
+	call := m.dm.Deployments.Insert(project, &deploymentmanager.Deployment{
+		Description: Caller(ctx, m.users),
+		Labels:      labelsToEntries(&labels),
+		Name:        name,
 		Target: &deploymentmanager.TargetConfiguration{
 			Config: &deploymentmanager.ConfigFile{
 				Content: string(j),
 			},
 		},
-	}).Context(ctx).Do()
+	})
+	if preview {
+		call = call.Preview(true)
+	}
+
+	op, err := call.Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("error inserting deployment: %w", err)
 	}
 
+	if preview {
+		return m.preview(ctx, project, name, op.Name, true, interval)
+	}
+
 	// Wait for the deployment to be created or fail.
 	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
 }
 
 func (m *manager) Update(
-	ctx context.Context, project, name string, resources []Resource, dryRun bool,
+	ctx context.Context, project, name string, resources []Resource, dryRun, preview bool,
 	interval time.Duration,
 ) error {
 	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Update")
@@ -226,23 +408,24 @@ func (m *manager) Update(
 		trace.StringAttribute("project", project),
 		trace.StringAttribute("name", name),
 		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
 	)
 
-	// Create our config target.
-	d := deploymentConfig{Resources: resources}
-
 	// Pretty-print the config and early exit if we don't want side effects.
 	if dryRun {
-		b, err := json.MarshalIndent(d, "", "  ")
+		config, err := Config(resources)
 		if err != nil {
-			return fmt.Errorf("error generating JSON: %w", err)
+			return err
 		}
 
-		fmt.Println(string(b))
+		fmt.Println(config)
 
 		return nil
 	}
 
+	// Create our config target.
+	d := deploymentConfig{Resources: resources}
+
 	// Marshal the config target as JSON, since that's parsable by Deployment Manager.
 	j, err := json.Marshal(d)
 	if err != nil {
@@ -250,21 +433,109 @@ func (m *manager) Update(
 	}
 
 	// Update the deployment.
-	op, err := m.dm.Deployments.Patch(project, name, &deploymentmanager.Deployment{
+	call := m.dm.Deployments.Patch(project, name, &deploymentmanager.Deployment{
 		Target: &deploymentmanager.TargetConfiguration{
 			Config: &deploymentmanager.ConfigFile{
 				Content: string(j),
 			},
 		},
-	}).Context(ctx).Do()
+	})
+	if preview {
+		call = call.Preview(true)
+	}
+
+	op, err := call.Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("error patching deployment: %w", err)
 	}
 
+	if preview {
+		return m.preview(ctx, project, name, op.Name, false, interval)
+	}
+
 	// Wait for the deployment to be updated or fail.
 	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
 }
 
+// preview waits for the given preview operation to complete, prints the planned changes to the
+// deployment's resources, and then cancels the preview. Validation errors cause the preview operation
+// to fail, but the plan is still printed and the preview is still cancelled.
+func (m *manager) preview(
+	ctx context.Context, project, name, operation string, created bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.preview")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("created", created),
+	)
+
+	// Wait for the preview to be planned.
+	previewErr := waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, operation))
+
+	// Print the planned changes.
+	if err := m.printPreview(ctx, project, name); err != nil {
+		return err
+	}
+
+	// Cancel the preview.
+	if err := m.cancelPreview(ctx, project, name, created, interval); err != nil {
+		return err
+	}
+
+	return previewErr
+}
+
+// printPreview prints the planned intent of each resource which would change, plus any errors.
+func (m *manager) printPreview(ctx context.Context, project, name string) error {
+	tw := tabwriter.NewWriter(m.out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "INTENT\tRESOURCE\tTYPE")
+
+	if err := m.dm.Resources.List(project, name).Pages(ctx,
+		func(list *deploymentmanager.ResourcesListResponse) error {
+			for _, r := range list.Resources {
+				// Skip resources which wouldn't change.
+				if r.Update == nil {
+					continue
+				}
+
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Update.Intent, r.Name, r.Type)
+
+				if r.Update.Error != nil {
+					for _, e := range r.Update.Error.Errors {
+						_, _ = fmt.Fprintf(tw, "\t  error: %s\n", e.Message)
+					}
+				}
+			}
+			return nil
+		},
+	); err != nil {
+		return fmt.Errorf("error listing resources: %w", err)
+	}
+
+	return tw.Flush()
+}
+
+// cancelPreview cancels the deployment's preview. Previewing a new deployment leaves behind an empty
+// deployment, which is deleted instead.
+func (m *manager) cancelPreview(
+	ctx context.Context, project, name string, created bool, interval time.Duration,
+) error {
+	if !created {
+		return m.CancelPreview(ctx, project, name, false, interval)
+	}
+
+	op, err := m.dm.Deployments.Delete(project, name).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error cancelling preview: %w", err)
+	}
+
+	// Wait for the preview to be cancelled.
+	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
+}
+
 func (m *manager) Delete(
 	ctx context.Context, project, name string, dryRun, async bool, interval time.Duration,
 ) error {
@@ -313,10 +584,7 @@ func (m *manager) List(ctx context.Context, project, filter string) ([]Deploymen
 		func(list *deploymentmanager.DeploymentsListResponse) error {
 			// Convert labels to maps.
 			for _, d := range list.Deployments {
-				deployments = append(deployments, Deployment{
-					Name:   d.Name,
-					Labels: entriesToLabels(d.Labels),
-				})
+				deployments = append(deployments, toDeployment(d))
 			}
 			return nil
 		},
@@ -331,33 +599,329 @@ func (m *manager) List(ctx context.Context, project, filter string) ([]Deploymen
 	return deployments, nil
 }
 
-func entry(k, v string) *deploymentmanager.DeploymentLabelEntry {
-	return &deploymentmanager.DeploymentLabelEntry{Key: k, Value: v}
+func (m *manager) Resources(ctx context.Context, project, name string) ([]Resource, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Resources")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	// Find the deployment's current manifest.
+	d, err := m.dm.Deployments.Get(project, name).Context(ctx).Fields("manifest").Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Manifest == "" {
+		return nil, &NoManifestError{Name: name}
+	}
+
+	// Get the config from the manifest.
+	manifest, err := m.dm.Manifests.Get(project, name, path.Base(d.Manifest)).Context(ctx).
+		Fields("config").Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting manifest: %w", err)
+	}
+
+	// Decode the config, leaving the properties of each resource as raw JSON.
+	var config struct {
+		Resources []struct {
+			Name       string          `json:"name"`
+			Type       string          `json:"type"`
+			Properties json.RawMessage `json:"properties"`
+		} `json:"resources"`
+	}
+
+	if err := json.Unmarshal([]byte(manifest.Config.Content), &config); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+
+	resources := make([]Resource, len(config.Resources))
+	for i, r := range config.Resources {
+		resources[i] = Resource{Name: r.Name, Type: r.Type, Properties: r.Properties}
+	}
+
+	return resources, nil
 }
 
-func labelsToEntries(l *Labels) []*deploymentmanager.DeploymentLabelEntry {
-	var entries []*deploymentmanager.DeploymentLabelEntry
-	if l.App != "" {
-		entries = append(entries, entry("belvedere-app", l.App))
+func (m *manager) Status(ctx context.Context, project, name string) (*Status, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Status")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	d, err := m.dm.Deployments.Get(project, name).Context(ctx).
+		Fields("manifest", "operation", "update").Do()
+	if err != nil {
+		return nil, err
 	}
 
-	if l.Hash != "" {
-		entries = append(entries, entry("belvedere-hash", l.Hash))
+	status := Status{Previewing: d.Update != nil}
+
+	if d.Manifest != "" {
+		status.Manifest = path.Base(d.Manifest)
+	}
+
+	if op := d.Operation; op != nil {
+		status.Operation = op.OperationType
+		status.State = op.Status
+
+		if op.Error != nil {
+			for _, e := range op.Error.Errors {
+				status.Errors = append(status.Errors, e.Message)
+			}
+		}
+	}
+
+	// Find all the resources with pending or failed changes.
+	if err := m.dm.Resources.List(project, name).Pages(ctx,
+		func(list *deploymentmanager.ResourcesListResponse) error {
+			for _, r := range list.Resources {
+				if r.Update == nil {
+					continue
+				}
+
+				rs := ResourceStatus{Name: r.Name, Type: r.Type, State: r.Update.State}
+
+				if r.Update.Error != nil {
+					for _, e := range r.Update.Error.Errors {
+						rs.Errors = append(rs.Errors, e.Message)
+					}
+				}
+
+				status.Resources = append(status.Resources, rs)
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, fmt.Errorf("error listing resources: %w", err)
 	}
 
-	if l.Region != "" {
-		entries = append(entries, entry("belvedere-region", l.Region))
+	return &status, nil
+}
+
+func (m *manager) Stop(
+	ctx context.Context, project, name string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Stop")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
 	}
 
-	if l.Release != "" {
-		entries = append(entries, entry("belvedere-release", l.Release))
+	// Stopping an operation requires the deployment's current fingerprint.
+	d, err := m.dm.Deployments.Get(project, name).Context(ctx).Fields("fingerprint").Do()
+	if err != nil {
+		return fmt.Errorf("error getting deployment: %w", err)
 	}
 
-	entries = append(entries, entry("belvedere-type", l.Type))
+	op, err := m.dm.Deployments.Stop(project, name, &deploymentmanager.DeploymentsStopRequest{
+		Fingerprint: d.Fingerprint,
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error stopping deployment: %w", err)
+	}
+
+	// Wait for the operation to be stopped.
+	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
+}
+
+func (m *manager) CancelPreview(
+	ctx context.Context, project, name string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.CancelPreview")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
+	}
+
+	// Cancelling a preview requires the deployment's current fingerprint.
+	d, err := m.dm.Deployments.Get(project, name).Context(ctx).Fields("fingerprint").Do()
+	if err != nil {
+		return fmt.Errorf("error getting deployment: %w", err)
+	}
+
+	op, err := m.dm.Deployments.CancelPreview(project, name,
+		&deploymentmanager.DeploymentsCancelPreviewRequest{
+			Fingerprint: d.Fingerprint,
+		}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error cancelling preview: %w", err)
+	}
+
+	// Wait for the preview to be cancelled.
+	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
+}
+
+func (m *manager) Restore(
+	ctx context.Context, project, name string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Restore")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Find the last manifest which was successfully deployed.
+	d, err := m.dm.Deployments.Get(project, name).Context(ctx).Fields("manifest").Do()
+	if err != nil {
+		return fmt.Errorf("error getting deployment: %w", err)
+	}
+
+	if d.Manifest == "" {
+		return &NoManifestError{Name: name}
+	}
+
+	manifest, err := m.dm.Manifests.Get(project, name, path.Base(d.Manifest)).Context(ctx).
+		Fields("config").Do()
+	if err != nil {
+		return fmt.Errorf("error getting manifest: %w", err)
+	}
+
+	// Print the manifest's config and early exit if we don't want side effects.
+	if dryRun {
+		fmt.Println(manifest.Config.Content)
+
+		return nil
+	}
+
+	// Update the deployment with the manifest's config.
+	op, err := m.dm.Deployments.Patch(project, name, &deploymentmanager.Deployment{
+		Target: &deploymentmanager.TargetConfiguration{
+			Config: manifest.Config,
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error patching deployment: %w", err)
+	}
+
+	// Wait for the deployment to be restored or fail.
+	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
+}
+
+func (m *manager) Abandon(
+	ctx context.Context, project, name string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.deployments.Abandon")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
+	}
+
+	// Delete the deployment, leaving its resources in place.
+	op, err := m.dm.Deployments.Delete(project, name).DeletePolicy("ABANDON").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error abandoning deployment: %w", err)
+	}
+
+	// Wait for the deployment to be deleted or fail.
+	return waiter.Poll(ctx, interval, check.DM(ctx, m.dm, project, op.Name))
+}
+
+type NoManifestError struct {
+	Name string
+}
+
+func (e *NoManifestError) Error() string {
+	return fmt.Sprintf("deployment %s has no manifest", e.Name)
+}
+
+// toDeployment converts a DM deployment into a Deployment.
+func toDeployment(d *deploymentmanager.Deployment) Deployment {
+	dep := Deployment{
+		Name:   d.Name,
+		Labels: entriesToLabels(d.Labels),
+	}
+
+	// DM timestamps are RFC 3339, when present.
+	if t, err := time.Parse(time.RFC3339, d.InsertTime); err == nil {
+		dep.Created = t
+	}
+
+	// Deployments created before their creators were recorded in their descriptions may still have
+	// their insert operations.
+	dep.Creator = d.Description
+	if dep.Creator == "" && d.Operation != nil && d.Operation.OperationType == "insert" {
+		dep.Creator = d.Operation.User
+	}
+
+	return dep
+}
+
+func entry(k, v string) *deploymentmanager.DeploymentLabelEntry {
+	return &deploymentmanager.DeploymentLabelEntry{Key: k, Value: v}
+}
+
+func labelsToEntries(l *Labels) []*deploymentmanager.DeploymentLabelEntry {
+	m := l.Map()
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	entries := make([]*deploymentmanager.DeploymentLabelEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = entry(k, m[k])
+	}
 
 	return entries
 }
 
+// Map returns the labels as a map of label keys to values. Empty labels are omitted, except for the
+// deployment type.
+func (l *Labels) Map() map[string]string {
+	m := map[string]string{"belvedere-type": l.Type}
+
+	for k, v := range map[string]string{
+		"belvedere-app":        l.App,
+		"belvedere-boot-image": l.BootImage,
+		"belvedere-hash":       l.Hash,
+		"belvedere-region":     l.Region,
+		"belvedere-release":    l.Release,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
 func entriesToLabels(entries []*deploymentmanager.DeploymentLabelEntry) Labels {
 	var l Labels
 
@@ -365,6 +929,8 @@ func entriesToLabels(entries []*deploymentmanager.DeploymentLabelEntry) Labels {
 		switch e.Key {
 		case "belvedere-app":
 			l.App = e.Value
+		case "belvedere-boot-image":
+			l.BootImage = e.Value
 		case "belvedere-hash":
 			l.Hash = e.Value
 		case "belvedere-region":
@@ -1,6 +1,7 @@
 package deployments
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/codahale/gubbins/httpmock"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/deploymentmanager/v2"
+	"google.golang.org/api/oauth2/v2"
 	"google.golang.org/api/option"
 )
 
@@ -16,11 +18,12 @@ func TestLabelsToEntries(t *testing.T) {
 	t.Parallel()
 
 	labels := Labels{
-		Type:    "release",
-		Region:  "us-west1",
-		App:     "my-app",
-		Release: "v1",
-		Hash:    "12345",
+		Type:      "release",
+		Region:    "us-west1",
+		App:       "my-app",
+		Release:   "v1",
+		Hash:      "12345",
+		BootImage: "cos-stable-89",
 	}
 	got := labelsToEntries(&labels)
 
@@ -29,6 +32,10 @@ func TestLabelsToEntries(t *testing.T) {
 			Key:   "belvedere-app",
 			Value: "my-app",
 		},
+		{
+			Key:   "belvedere-boot-image",
+			Value: "cos-stable-89",
+		},
 		{
 			Key:   "belvedere-hash",
 			Value: "12345",
@@ -74,14 +81,19 @@ func TestEntriesToLabels(t *testing.T) {
 			Key:   "belvedere-hash",
 			Value: "12345",
 		},
+		{
+			Key:   "belvedere-boot-image",
+			Value: "cos-stable-89",
+		},
 	})
 
 	want := Labels{
-		Type:    "release",
-		Region:  "us-west1",
-		App:     "my-app",
-		Release: "v1",
-		Hash:    "12345",
+		Type:      "release",
+		Region:    "us-west1",
+		App:       "my-app",
+		Release:   "v1",
+		Hash:      "12345",
+		BootImage: "cos-stable-89",
 	}
 
 	assert.Equal(t, "entriesToLabels()", want, got)
@@ -93,9 +105,13 @@ func TestManager_Insert(t *testing.T) {
 	srv := httpmock.NewServer(t)
 	defer srv.Finish()
 
+	srv.Expect(`/oauth2/v2/userinfo?alt=json&prettyPrint=false`,
+		httpmock.RespJSON(oauth2.Userinfo{Email: "ops@example.com"}))
+
 	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments?alt=json&prettyPrint=false`,
 		httpmock.ReqJSON(deploymentmanager.Deployment{
-			Name: "my-deployment",
+			Name:        "my-deployment",
+			Description: "ops@example.com",
 			Labels: []*deploymentmanager.DeploymentLabelEntry{
 				{
 					Key:   "belvedere-type",
@@ -141,7 +157,7 @@ func TestManager_Insert(t *testing.T) {
 		Labels{
 			Type: "base",
 		},
-		false, 10*time.Millisecond); err != nil {
+		false, false, 10*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -191,9 +207,106 @@ func TestManager_Update(t *testing.T) {
 				},
 			},
 		},
-		false, 10*time.Millisecond); err != nil {
+		false, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Update_Preview(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&prettyPrint=false&preview=true`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Name: "op1",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/operations/op1?`+
+		`alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Status: "DONE",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment/resources?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.ResourcesListResponse{
+			Resources: []*deploymentmanager.Resource{
+				{
+					Name: "my-instance",
+					Type: "compute.v1.instance",
+					Update: &deploymentmanager.ResourceUpdate{
+						Intent: "UPDATE",
+						Error: &deploymentmanager.ResourceUpdateError{
+							Errors: []*deploymentmanager.ResourceUpdateErrorErrors{
+								{
+									Message: "bad machine type",
+								},
+							},
+						},
+					},
+				},
+				{
+					Name: "my-network",
+					Type: "compute.v1.network",
+				},
+			},
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&fields=fingerprint&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Deployment{
+			Fingerprint: "abcd",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment/cancelPreview?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.ReqJSON(deploymentmanager.DeploymentsCancelPreviewRequest{
+			Fingerprint: "abcd",
+		}),
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Name: "op2",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/operations/op2?`+
+		`alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Status: "DONE",
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	dm.(*manager).out = out
+
+	if err := dm.Update(context.Background(), "my-project", "my-deployment",
+		[]Resource{
+			{
+				Name: "my-instance",
+				Type: "compute.v1.instance",
+				Properties: &compute.Instance{
+					MachineType: "n1-standard-1",
+				},
+			},
+		},
+		false, true, 10*time.Millisecond); err != nil {
 		t.Fatal(err)
 	}
+
+	want := `INTENT  RESOURCE     TYPE
+UPDATE  my-instance  compute.v1.instance
+          error: bad machine type
+`
+	assert.Equal(t, "preview", want, out.String())
 }
 
 func TestManager_Delete(t *testing.T) {
@@ -242,7 +355,13 @@ func TestManager_List(t *testing.T) {
 		httpmock.RespJSON(deploymentmanager.DeploymentsListResponse{
 			Deployments: []*deploymentmanager.Deployment{
 				{
+					// The deployment's creator wasn't recorded in its description, but its insert
+					// operation is still its most recent.
 					Name: "belvedere-base",
+					Operation: &deploymentmanager.Operation{
+						OperationType: "insert",
+						User:          "ops@example.com",
+					},
 					Labels: []*deploymentmanager.DeploymentLabelEntry{
 						{
 							Key:   "belvedere-type",
@@ -251,7 +370,12 @@ func TestManager_List(t *testing.T) {
 					},
 				},
 				{
+					// The deployment's creator wasn't recorded and can't be found.
 					Name: "belvedere-my-app",
+					Operation: &deploymentmanager.Operation{
+						OperationType: "update",
+						User:          "repairs@example.com",
+					},
 					Labels: []*deploymentmanager.DeploymentLabelEntry{
 						{
 							Key:   "belvedere-type",
@@ -286,7 +410,8 @@ func TestManager_List(t *testing.T) {
 
 	want := []Deployment{
 		{
-			Name: "belvedere-base",
+			Name:    "belvedere-base",
+			Creator: "ops@example.com",
 			Labels: Labels{
 				Type: "base",
 			},
@@ -313,7 +438,14 @@ func TestManager_Get(t *testing.T) {
 	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/belvedere-base?`+
 		`alt=json&prettyPrint=false`,
 		httpmock.RespJSON(&deploymentmanager.Deployment{
-			Name: "belvedere-base",
+			Name:        "belvedere-base",
+			Description: "ops@example.com",
+			InsertTime:  "2021-04-01T12:30:00.000-07:00",
+			// The deployment was last updated by someone else.
+			Operation: &deploymentmanager.Operation{
+				OperationType: "update",
+				User:          "repairs@example.com",
+			},
 			Labels: []*deploymentmanager.DeploymentLabelEntry{
 				{
 					Key:   "belvedere-type",
@@ -337,7 +469,9 @@ func TestManager_Get(t *testing.T) {
 	}
 
 	want := &Deployment{
-		Name: "belvedere-base",
+		Name:    "belvedere-base",
+		Created: time.Date(2021, 4, 1, 19, 30, 0, 0, time.UTC),
+		Creator: "ops@example.com",
 		Labels: Labels{
 			Type: "base",
 		},
@@ -345,3 +479,286 @@ func TestManager_Get(t *testing.T) {
 
 	assert.Equal(t, "Get()", want, got)
 }
+
+func TestManager_Resources(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/belvedere-my-app-v1?`+
+		`alt=json&fields=manifest&prettyPrint=false`,
+		httpmock.RespJSON(&deploymentmanager.Deployment{
+			Manifest: "https://www.googleapis.com/deploymentmanager/v2/projects/my-project/global/" +
+				"deployments/belvedere-my-app-v1/manifests/manifest-1234",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/belvedere-my-app-v1/`+
+		`manifests/manifest-1234?alt=json&fields=config&prettyPrint=false`,
+		httpmock.RespJSON(&deploymentmanager.Manifest{
+			Config: &deploymentmanager.ConfigFile{
+				Content: `{"resources":[{"name":"my-instance","type":"compute.v1.instance",` +
+					`"properties":{"name":"my-instance"}}]}`,
+			},
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dm.Resources(context.Background(), "my-project", "belvedere-my-app-v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "len(Resources())", 1, len(got))
+	assert.Equal(t, "Resources()[0].Name", "my-instance", got[0].Name)
+	assert.Equal(t, "Resources()[0].Type", "compute.v1.instance", got[0].Type)
+
+	var instance compute.Instance
+	if err := got[0].DecodeProperties(&instance); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "DecodeProperties()", compute.Instance{Name: "my-instance"}, instance)
+}
+
+func TestManager_Status(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&fields=manifest%2Coperation%2Cupdate&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Deployment{
+			Manifest: "https://www.googleapis.com/deploymentmanager/v2/projects/my-project/global/" +
+				"deployments/my-deployment/manifests/manifest-1",
+			Operation: &deploymentmanager.Operation{
+				OperationType: "update",
+				Status:        "DONE",
+				Error: &deploymentmanager.OperationError{
+					Errors: []*deploymentmanager.OperationErrorErrors{
+						{
+							Message: "resource errors",
+						},
+					},
+				},
+			},
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment/resources?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.ResourcesListResponse{
+			Resources: []*deploymentmanager.Resource{
+				{
+					Name: "my-instance",
+					Type: "compute.v1.instance",
+					Update: &deploymentmanager.ResourceUpdate{
+						State: "FAILED",
+						Error: &deploymentmanager.ResourceUpdateError{
+							Errors: []*deploymentmanager.ResourceUpdateErrorErrors{
+								{
+									Message: "quota exceeded",
+								},
+							},
+						},
+					},
+				},
+				{
+					Name: "my-network",
+					Type: "compute.v1.network",
+				},
+			},
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dm.Status(context.Background(), "my-project", "my-deployment")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Status{
+		Operation: "update",
+		State:     "DONE",
+		Errors:    []string{"resource errors"},
+		Manifest:  "manifest-1",
+		Resources: []ResourceStatus{
+			{
+				Name:   "my-instance",
+				Type:   "compute.v1.instance",
+				State:  "FAILED",
+				Errors: []string{"quota exceeded"},
+			},
+		},
+	}
+
+	assert.Equal(t, "Status()", want, got)
+	assert.Equal(t, "Healthy()", false, got.Healthy())
+}
+
+func TestManager_Stop(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&fields=fingerprint&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Deployment{
+			Fingerprint: "abcd",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment/stop?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.ReqJSON(deploymentmanager.DeploymentsStopRequest{
+			Fingerprint: "abcd",
+		}),
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Name: "op1",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/operations/op1?`+
+		`alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Status: "DONE",
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dm.Stop(context.Background(), "my-project", "my-deployment", false,
+		10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Restore(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&fields=manifest&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Deployment{
+			Manifest: "https://www.googleapis.com/deploymentmanager/v2/projects/my-project/global/" +
+				"deployments/my-deployment/manifests/manifest-1",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment/manifests/`+
+		`manifest-1?alt=json&fields=config&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Manifest{
+			Config: &deploymentmanager.ConfigFile{
+				Content: `{"resources":[]}`,
+			},
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.ReqJSON(deploymentmanager.Deployment{
+			Target: &deploymentmanager.TargetConfiguration{
+				Config: &deploymentmanager.ConfigFile{
+					Content: `{"resources":[]}`,
+				},
+			},
+		}),
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Name: "op1",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/operations/op1?`+
+		`alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Status: "DONE",
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dm.Restore(context.Background(), "my-project", "my-deployment", false,
+		10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Restore_NoManifest(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&fields=manifest&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Deployment{}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dm.Restore(context.Background(), "my-project", "my-deployment", false, 10*time.Millisecond)
+	assert.Equal(t, "Restore() error", &NoManifestError{Name: "my-deployment"}, err)
+}
+
+func TestManager_Abandon(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/deployments/my-deployment?`+
+		`alt=json&deletePolicy=ABANDON&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Name: "op1",
+		}))
+
+	srv.Expect(`/deploymentmanager/v2/projects/my-project/global/operations/op1?`+
+		`alt=json&fields=status%2Cerror&prettyPrint=false`,
+		httpmock.RespJSON(deploymentmanager.Operation{
+			Status: "DONE",
+		}))
+
+	dm, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dm.Abandon(context.Background(), "my-project", "my-deployment", false,
+		10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
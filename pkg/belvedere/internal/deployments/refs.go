@@ -0,0 +1,196 @@
+package deployments
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//nolint:gochecknoglobals // can't have const regexps
+var refRegexp = regexp.MustCompile(`\$\(ref\.([^.)]+)\.([^)]+)\)`)
+
+// References returns the sorted, unique names of the resources referenced by the given generic JSON
+// value.
+func References(v interface{}) []string {
+	names := map[string]bool{}
+
+	walkStrings(v, func(s string) {
+		for _, m := range refRegexp.FindAllStringSubmatch(s, -1) {
+			names[m[1]] = true
+		}
+	})
+
+	refs := make([]string, 0, len(names))
+	for name := range names {
+		refs = append(refs, name)
+	}
+
+	sort.Strings(refs)
+
+	return refs
+}
+
+// ResolveRefs returns a copy of the given generic JSON value with its references replaced by the
+// values returned by lookup. A string which consists entirely of a reference is replaced by the
+// referenced value itself; otherwise, referenced values are formatted into the string. References
+// which can't be resolved are left in place.
+func ResolveRefs(v interface{}, lookup func(name, property string) (interface{}, bool)) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			obj[k] = ResolveRefs(e, lookup)
+		}
+
+		return obj
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			list[i] = ResolveRefs(e, lookup)
+		}
+
+		return list
+	case string:
+		if m := refRegexp.FindStringSubmatch(v); m != nil && m[0] == v {
+			if ref, ok := lookup(m[1], m[2]); ok {
+				return ref
+			}
+
+			return v
+		}
+
+		return refRegexp.ReplaceAllStringFunc(v, func(s string) string {
+			m := refRegexp.FindStringSubmatch(s)
+			if ref, ok := lookup(m[1], m[2]); ok {
+				return fmt.Sprint(ref)
+			}
+
+			return s
+		})
+	default:
+		return v
+	}
+}
+
+// Unresolved returns true if the given string contains a reference.
+func Unresolved(s string) bool {
+	return refRegexp.MatchString(s)
+}
+
+// Lookup returns the value of the given dotted property path in the generic JSON object. Like
+// Deployment Manager, property names are matched case-insensitively.
+func Lookup(obj map[string]interface{}, property string) (interface{}, bool) {
+	var v interface{} = obj
+
+	for _, key := range strings.Split(property, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		v, ok = lookupKey(m, key)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+func lookupKey(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+type CyclicReferenceError struct {
+	Names []string
+}
+
+func (e *CyclicReferenceError) Error() string {
+	return fmt.Sprintf("cyclic references between %s", strings.Join(e.Names, ", "))
+}
+
+// DependencyOrder returns the given resources ordered such that every resource comes after the
+// resources it references. Otherwise, resources stay in their given order. References to resources
+// which aren't in the list are ignored.
+func DependencyOrder(resources []Resource) ([]Resource, error) {
+	deps := make(map[string][]string, len(resources))
+
+	for i := range resources {
+		var v interface{}
+		if err := resources[i].DecodeProperties(&v); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", resources[i].Name, err)
+		}
+
+		deps[resources[i].Name] = References(v)
+	}
+
+	ordered := make([]Resource, 0, len(resources))
+	done := make(map[string]bool, len(resources))
+	pending := resources
+
+	for len(pending) > 0 {
+		var next []Resource
+
+		for _, r := range pending {
+			if ready(deps[r.Name], deps, done) {
+				ordered = append(ordered, r)
+				done[r.Name] = true
+			} else {
+				next = append(next, r)
+			}
+		}
+
+		// If no resources were ready, the rest reference each other.
+		if len(next) == len(pending) {
+			names := make([]string, len(next))
+			for i, r := range next {
+				names[i] = r.Name
+			}
+
+			return nil, &CyclicReferenceError{Names: names}
+		}
+
+		pending = next
+	}
+
+	return ordered, nil
+}
+
+// ready returns true if all the given references are to resources which are done or aren't in the
+// list.
+func ready(refs []string, deps map[string][]string, done map[string]bool) bool {
+	for _, ref := range refs {
+		if _, ok := deps[ref]; ok && !done[ref] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkStrings calls f with every string in the given generic JSON value.
+func walkStrings(v interface{}, f func(string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, e := range v {
+			walkStrings(e, f)
+		}
+	case []interface{}:
+		for _, e := range v {
+			walkStrings(e, f)
+		}
+	case string:
+		f(v)
+	}
+}
@@ -0,0 +1,105 @@
+package deployments
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+)
+
+func TestReferences(t *testing.T) {
+	t.Parallel()
+
+	v := map[string]interface{}{
+		"member": "serviceAccount:$(ref.my-sa.email)",
+		"list":   []interface{}{"$(ref.my-hc.selfLink)", "$(ref.my-sa.email)"},
+	}
+
+	assert.Equal(t, "References()", []string{"my-hc", "my-sa"}, References(v))
+}
+
+func TestResolveRefs(t *testing.T) {
+	t.Parallel()
+
+	v := map[string]interface{}{
+		"member":  "serviceAccount:$(ref.my-sa.email)",
+		"port":    "$(ref.my-ig.namedPorts)",
+		"missing": "$(ref.nope.selfLink)",
+	}
+
+	got := ResolveRefs(v, func(name, property string) (interface{}, bool) {
+		obj := map[string]map[string]interface{}{
+			"my-sa": {"email": "sa@example.com"},
+			"my-ig": {"namedPorts": []interface{}{8443.0}},
+		}[name]
+
+		if obj == nil {
+			return nil, false
+		}
+
+		return Lookup(obj, property)
+	})
+
+	want := map[string]interface{}{
+		"member":  "serviceAccount:sa@example.com",
+		"port":    []interface{}{8443.0},
+		"missing": "$(ref.nope.selfLink)",
+	}
+
+	assert.Equal(t, "ResolveRefs()", want, got)
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	obj := map[string]interface{}{
+		"address": "1.2.3.4",
+		"nested":  map[string]interface{}{"value": "yes"},
+	}
+
+	got, ok := Lookup(obj, "Address")
+	assert.Equal(t, "Lookup(Address)", "1.2.3.4", got)
+	assert.Equal(t, "Lookup(Address) ok", true, ok)
+
+	got, ok = Lookup(obj, "nested.value")
+	assert.Equal(t, "Lookup(nested.value)", "yes", got)
+	assert.Equal(t, "Lookup(nested.value) ok", true, ok)
+
+	_, ok = Lookup(obj, "address.value")
+	assert.Equal(t, "Lookup(address.value) ok", false, ok)
+}
+
+func TestDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	resources := []Resource{
+		{Name: "fr", Properties: json.RawMessage(`{"target":"$(ref.tp.selfLink)","ip":"$(ref.ip.address)"}`)},
+		{Name: "tp", Properties: json.RawMessage(`{"urlMap":"$(ref.um.selfLink)"}`)},
+		{Name: "ip", Properties: json.RawMessage(`{}`)},
+		{Name: "um", Properties: json.RawMessage(`{"default":"$(ref.elsewhere.selfLink)"}`)},
+	}
+
+	got, err := DependencyOrder(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(got))
+	for i, r := range got {
+		names[i] = r.Name
+	}
+
+	assert.Equal(t, "DependencyOrder()", []string{"ip", "um", "tp", "fr"}, names)
+}
+
+func TestDependencyOrder_Cycle(t *testing.T) {
+	t.Parallel()
+
+	resources := []Resource{
+		{Name: "a", Properties: json.RawMessage(`{"b":"$(ref.b.selfLink)"}`)},
+		{Name: "b", Properties: json.RawMessage(`{"a":"$(ref.a.selfLink)"}`)},
+	}
+
+	_, err := DependencyOrder(resources)
+	assert.Equal(t, "DependencyOrder() error", &CyclicReferenceError{Names: []string{"a", "b"}}, err)
+}
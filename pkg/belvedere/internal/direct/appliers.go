@@ -0,0 +1,607 @@
+package direct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/check"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/waiter"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+)
+
+// An applier creates, updates, and deletes resources of a particular type. Properties are generic
+// JSON objects with all references resolved, and live is the resource's current state as returned
+// by the live fetcher.
+type applier interface {
+	create(ctx context.Context, project string, props map[string]interface{}, interval time.Duration) error
+	update(ctx context.Context, project string, props, live map[string]interface{}, interval time.Duration) error
+	delete(ctx context.Context, project string, props map[string]interface{}, interval time.Duration) error
+}
+
+type UnsupportedTypeError struct {
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported resource type: %s", e.Type)
+}
+
+type ImmutableResourceError struct {
+	Name string
+	Type string
+}
+
+func (e *ImmutableResourceError) Error() string {
+	return fmt.Sprintf("%s resources can't be updated, but %s has changed", e.Type, e.Name)
+}
+
+// applier returns the applier for the given resource type.
+func (m *manager) applier(t string) (applier, error) {
+	if kind, ok := computeKinds[t]; ok {
+		ca := &computeApplier{m: m, kind: kind, t: t}
+		if t == "compute.v1.securityPolicy" {
+			return &securityPolicyApplier{computeApplier: ca}, nil
+		}
+
+		return ca, nil
+	}
+
+	switch t {
+	case "dns.v1.managedZone":
+		return &managedZoneApplier{m: m}, nil
+	case "gcp-types/dns-v1:resourceRecordSets":
+		return &resourceRecordSetsApplier{m: m}, nil
+	case "iam.v1.serviceAccount":
+		return &serviceAccountApplier{m: m}, nil
+	case "gcp-types/cloudresourcemanager-v1:virtual.projects.iamMemberBinding":
+		return &iamMemberBindingApplier{m: m}, nil
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+}
+
+// computeKind describes a Compute Engine resource collection.
+type computeKind struct {
+	// collection is the path of the collection, relative to the project or region.
+	collection string
+	// regional is true if the collection is regional, in which case the resource properties must
+	// include a region.
+	regional bool
+	// immutable is true if resources in the collection can't be updated.
+	immutable bool
+}
+
+//nolint:gochecknoglobals // can't have non-scalar consts
+var computeKinds = map[string]computeKind{
+	"compute.v1.globalAddress":              {collection: "global/addresses", immutable: true},
+	"compute.v1.firewall":                   {collection: "global/firewalls"},
+	"compute.v1.healthCheck":                {collection: "global/healthChecks"},
+	"compute.v1.backendService":             {collection: "global/backendServices"},
+	"compute.v1.urlMap":                     {collection: "global/urlMaps"},
+	"compute.v1.sslCertificate":             {collection: "global/sslCertificates", immutable: true},
+	"compute.v1.targetHttpsProxy":           {collection: "global/targetHttpsProxies"},
+	"compute.v1.targetHttpProxy":            {collection: "global/targetHttpProxies"},
+	"compute.v1.globalForwardingRule":       {collection: "global/forwardingRules"},
+	"compute.v1.securityPolicy":             {collection: "global/securityPolicies"},
+	"compute.v1.instanceTemplate":           {collection: "global/instanceTemplates", immutable: true},
+	"compute.v1.regionInstanceGroupManager": {collection: "instanceGroupManagers", regional: true},
+	"compute.v1.regionAutoscaler":           {collection: "autoscalers", regional: true},
+}
+
+// computeApplier applies Compute Engine resources using the REST API directly, since the resource
+// properties are already in the API's representation.
+type computeApplier struct {
+	m    *manager
+	kind computeKind
+	t    string
+}
+
+// collectionURL returns the URL of the resource's collection, plus the resource's name and region.
+func (a *computeApplier) collectionURL(project string, props map[string]interface{}) (string, string, string) {
+	name, _ := props["name"].(string)
+	region, _ := props["region"].(string)
+
+	p := fmt.Sprintf("projects/%s/%s", project, a.kind.collection)
+	if a.kind.regional {
+		p = fmt.Sprintf("projects/%s/regions/%s/%s", project, path.Base(region), a.kind.collection)
+	}
+
+	return googleapi.ResolveRelative(a.m.gce.BasePath, p), name, path.Base(region)
+}
+
+// body returns the request body for the resource, without its region, which is part of the URL.
+func (a *computeApplier) body(props map[string]interface{}) map[string]interface{} {
+	body := make(map[string]interface{}, len(props))
+
+	for k, v := range props {
+		if !(a.kind.regional && k == "region") {
+			body[k] = v
+		}
+	}
+
+	return body
+}
+
+func (a *computeApplier) create(
+	ctx context.Context, project string, props map[string]interface{}, interval time.Duration,
+) error {
+	u, _, region := a.collectionURL(project, props)
+
+	return a.do(ctx, project, region, http.MethodPost, u, a.body(props), interval)
+}
+
+func (a *computeApplier) update(
+	ctx context.Context, project string, props, live map[string]interface{}, interval time.Duration,
+) error {
+	if a.kind.immutable {
+		name, _ := props["name"].(string)
+		return &ImmutableResourceError{Name: name, Type: a.t}
+	}
+
+	u, name, region := a.collectionURL(project, props)
+
+	// Autoscalers are patched via their collection.
+	if a.t == "compute.v1.regionAutoscaler" {
+		u = fmt.Sprintf("%s?autoscaler=%s", u, url.QueryEscape(name))
+	} else {
+		u = fmt.Sprintf("%s/%s", u, name)
+	}
+
+	// Include the fingerprint, if any, to avoid overwriting concurrent writes.
+	body := a.body(props)
+	if fp, ok := live["fingerprint"]; ok {
+		body["fingerprint"] = fp
+	}
+
+	return a.do(ctx, project, region, http.MethodPatch, u, body, interval)
+}
+
+func (a *computeApplier) delete(
+	ctx context.Context, project string, props map[string]interface{}, interval time.Duration,
+) error {
+	u, name, region := a.collectionURL(project, props)
+
+	return a.do(ctx, project, region, http.MethodDelete, fmt.Sprintf("%s/%s", u, name), nil, interval)
+}
+
+// do makes a request which returns a Compute Engine operation and waits for it to complete.
+func (a *computeApplier) do(
+	ctx context.Context, project, region, method, u string, body interface{}, interval time.Duration,
+) error {
+	var op struct {
+		Name string `json:"name"`
+	}
+
+	if err := a.m.request(ctx, method, u, body, &op); err != nil {
+		return err
+	}
+
+	if a.kind.regional {
+		return waiter.Poll(ctx, interval, check.GCERegion(ctx, a.m.gce, project, region, op.Name))
+	}
+
+	return waiter.Poll(ctx, interval, check.GCE(ctx, a.m.gce, project, op.Name))
+}
+
+// request makes a JSON request to a Google API.
+func (m *manager) request(ctx context.Context, method, u string, body, out interface{}) error {
+	var r io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.hc.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// securityPolicyApplier applies Cloud Armor security policies. Their rules can't be patched along
+// with the policy, so they're reconciled individually by priority.
+type securityPolicyApplier struct {
+	*computeApplier
+}
+
+func (a *securityPolicyApplier) update(
+	ctx context.Context, project string, props, live map[string]interface{}, interval time.Duration,
+) error {
+	policy := make(map[string]interface{}, len(props))
+
+	for k, v := range props {
+		if k != "rules" {
+			policy[k] = v
+		}
+	}
+
+	if err := a.computeApplier.update(ctx, project, policy, live, interval); err != nil {
+		return err
+	}
+
+	u, name, _ := a.collectionURL(project, props)
+	u = fmt.Sprintf("%s/%s", u, name)
+
+	want := rulesByPriority(props["rules"])
+	got := rulesByPriority(live["rules"])
+
+	for _, priority := range sortedPriorities(want) {
+		rule, ok := got[priority]
+
+		switch {
+		case !ok:
+			if err := a.do(ctx, project, "", http.MethodPost, u+"/addRule", want[priority], interval); err != nil {
+				return err
+			}
+		case !subset(want[priority], rule):
+			if err := a.do(ctx, project, "", http.MethodPost,
+				fmt.Sprintf("%s/patchRule?priority=%d", u, priority), want[priority], interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, priority := range sortedPriorities(got) {
+		if _, ok := want[priority]; !ok {
+			if err := a.do(ctx, project, "", http.MethodPost,
+				fmt.Sprintf("%s/removeRule?priority=%d", u, priority), nil, interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rulesByPriority returns the given security policy rules indexed by priority.
+func rulesByPriority(v interface{}) map[int64]map[string]interface{} {
+	list, _ := v.([]interface{})
+	rules := make(map[int64]map[string]interface{}, len(list))
+
+	for _, e := range list {
+		if rule, ok := e.(map[string]interface{}); ok {
+			priority, _ := rule["priority"].(float64)
+			rules[int64(priority)] = rule
+		}
+	}
+
+	return rules
+}
+
+// sortedPriorities returns the priorities of the given rules in ascending order.
+func sortedPriorities(rules map[int64]map[string]interface{}) []int64 {
+	priorities := make([]int64, 0, len(rules))
+	for p := range rules {
+		priorities = append(priorities, p)
+	}
+
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] < priorities[j]
+	})
+
+	return priorities
+}
+
+// managedZoneApplier applies Cloud DNS managed zones.
+type managedZoneApplier struct {
+	m *manager
+}
+
+func (a *managedZoneApplier) create(
+	ctx context.Context, project string, props map[string]interface{}, _ time.Duration,
+) error {
+	var mz dns.ManagedZone
+	if err := convert(props, &mz); err != nil {
+		return err
+	}
+
+	_, err := a.m.dns.ManagedZones.Create(project, &mz).Context(ctx).Do()
+
+	return err
+}
+
+func (a *managedZoneApplier) update(
+	ctx context.Context, project string, props, _ map[string]interface{}, _ time.Duration,
+) error {
+	var mz dns.ManagedZone
+	if err := convert(props, &mz); err != nil {
+		return err
+	}
+
+	_, err := a.m.dns.ManagedZones.Patch(project, mz.Name, &mz).Context(ctx).Do()
+
+	return err
+}
+
+func (a *managedZoneApplier) delete(
+	ctx context.Context, project string, props map[string]interface{}, _ time.Duration,
+) error {
+	name, _ := props["name"].(string)
+
+	return a.m.dns.ManagedZones.Delete(project, name).Context(ctx).Do()
+}
+
+// resourceRecordSetsApplier applies Cloud DNS resource record sets by replacing all the records
+// with the given name.
+type resourceRecordSetsApplier struct {
+	m *manager
+}
+
+func (a *resourceRecordSetsApplier) create(
+	ctx context.Context, project string, props map[string]interface{}, interval time.Duration,
+) error {
+	return a.replace(ctx, project, props, true, interval)
+}
+
+func (a *resourceRecordSetsApplier) update(
+	ctx context.Context, project string, props, _ map[string]interface{}, interval time.Duration,
+) error {
+	return a.replace(ctx, project, props, true, interval)
+}
+
+func (a *resourceRecordSetsApplier) delete(
+	ctx context.Context, project string, props map[string]interface{}, interval time.Duration,
+) error {
+	return a.replace(ctx, project, props, false, interval)
+}
+
+// replace deletes any existing records with the given name and, if add is true, adds the given
+// records in a single change.
+func (a *resourceRecordSetsApplier) replace(
+	ctx context.Context, project string, props map[string]interface{}, add bool, interval time.Duration,
+) error {
+	var rrs struct {
+		Name        string                   `json:"name"`
+		ManagedZone string                   `json:"managedZone"`
+		Records     []*dns.ResourceRecordSet `json:"records"`
+	}
+
+	if err := convert(props, &rrs); err != nil {
+		return err
+	}
+
+	existing, err := a.m.dns.ResourceRecordSets.List(project, rrs.ManagedZone).Name(rrs.Name).
+		Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error listing records: %w", err)
+	}
+
+	change := &dns.Change{Deletions: existing.Rrsets}
+
+	if add {
+		for _, r := range rrs.Records {
+			r.Name = rrs.Name
+			change.Additions = append(change.Additions, r)
+		}
+	}
+
+	if len(change.Additions) == 0 && len(change.Deletions) == 0 {
+		return nil
+	}
+
+	c, err := a.m.dns.Changes.Create(project, rrs.ManagedZone, change).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error changing records: %w", err)
+	}
+
+	return waiter.Poll(ctx, interval, check.DNS(ctx, a.m.dns, project, rrs.ManagedZone, c.Id))
+}
+
+// serviceAccountApplier applies IAM service accounts.
+type serviceAccountApplier struct {
+	m *manager
+}
+
+func (a *serviceAccountApplier) create(
+	ctx context.Context, project string, props map[string]interface{}, _ time.Duration,
+) error {
+	accountID, _ := props["accountId"].(string)
+	displayName, _ := props["displayName"].(string)
+
+	_, err := a.m.iam.Projects.ServiceAccounts.Create(fmt.Sprintf("projects/%s", project),
+		&iam.CreateServiceAccountRequest{
+			AccountId: accountID,
+			ServiceAccount: &iam.ServiceAccount{
+				DisplayName: displayName,
+			},
+		}).Context(ctx).Do()
+
+	return err
+}
+
+func (a *serviceAccountApplier) update(
+	ctx context.Context, project string, props, _ map[string]interface{}, _ time.Duration,
+) error {
+	displayName, _ := props["displayName"].(string)
+
+	_, err := a.m.iam.Projects.ServiceAccounts.Patch(serviceAccountName(project, props),
+		&iam.PatchServiceAccountRequest{
+			ServiceAccount: &iam.ServiceAccount{
+				DisplayName: displayName,
+			},
+			UpdateMask: "displayName",
+		}).Context(ctx).Do()
+
+	return err
+}
+
+func (a *serviceAccountApplier) delete(
+	ctx context.Context, project string, props map[string]interface{}, _ time.Duration,
+) error {
+	_, err := a.m.iam.Projects.ServiceAccounts.Delete(serviceAccountName(project, props)).Context(ctx).Do()
+
+	return err
+}
+
+// serviceAccountName returns the resource name of the service account.
+func serviceAccountName(project string, props map[string]interface{}) string {
+	accountID, _ := props["accountId"].(string)
+
+	return fmt.Sprintf("projects/%s/serviceAccounts/%s@%s.iam.gserviceaccount.com", project, accountID, project)
+}
+
+// iamMemberBindingApplier applies bindings of project members to IAM roles.
+type iamMemberBindingApplier struct {
+	m *manager
+}
+
+func (a *iamMemberBindingApplier) create(
+	ctx context.Context, _ string, props map[string]interface{}, _ time.Duration,
+) error {
+	return a.modify(ctx, props, true)
+}
+
+func (a *iamMemberBindingApplier) update(
+	ctx context.Context, _ string, props, _ map[string]interface{}, _ time.Duration,
+) error {
+	return a.modify(ctx, props, true)
+}
+
+func (a *iamMemberBindingApplier) delete(
+	ctx context.Context, _ string, props map[string]interface{}, _ time.Duration,
+) error {
+	return a.modify(ctx, props, false)
+}
+
+// modify adds the member to or removes the member from the role in the project's IAM policy.
+func (a *iamMemberBindingApplier) modify(ctx context.Context, props map[string]interface{}, add bool) error {
+	resource, _ := props["resource"].(string)
+	role, _ := props["role"].(string)
+	member, _ := props["member"].(string)
+
+	return gcp.ModifyLoop(5*time.Second, 2*time.Minute, func() error {
+		policy, err := a.m.crm.Projects.GetIamPolicy(resource, &cloudresourcemanager.GetIamPolicyRequest{}).
+			Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error getting IAM policy: %w", err)
+		}
+
+		if !bindMember(policy, role, member, add) {
+			return nil
+		}
+
+		// Setting the policy includes its etag, which avoids overwriting concurrent writes.
+		if _, err := a.m.crm.Projects.SetIamPolicy(resource, &cloudresourcemanager.SetIamPolicyRequest{
+			Policy: policy,
+		}).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("error setting IAM policy: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// bindMember adds the member to or removes the member from the role in the policy, and returns
+// true if the policy was modified.
+func bindMember(policy *cloudresourcemanager.Policy, role, member string, add bool) bool {
+	for _, binding := range policy.Bindings {
+		if binding.Role != role {
+			continue
+		}
+
+		for i, m := range binding.Members {
+			if m == member {
+				if add {
+					return false
+				}
+
+				binding.Members = append(binding.Members[:i], binding.Members[i+1:]...)
+
+				return true
+			}
+		}
+
+		if add {
+			binding.Members = append(binding.Members, member)
+		}
+
+		return add
+	}
+
+	if add {
+		policy.Bindings = append(policy.Bindings, &cloudresourcemanager.Binding{
+			Role:    role,
+			Members: []string{member},
+		})
+	}
+
+	return add
+}
+
+// convert converts the generic JSON value into the given API type.
+func convert(v, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// subset returns true if every field of want has the same value in got.
+func subset(want, got interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, v := range w {
+			if !subset(v, g[k]) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+
+		for i := range w {
+			if !subset(w[i], g[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return reflect.DeepEqual(want, got) || fmt.Sprint(want) == fmt.Sprint(got)
+	}
+}
@@ -0,0 +1,123 @@
+package direct
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestSecurityPolicyApplier_Update(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	policyURL := `/projects/my-project/global/securityPolicies/my-app-waf`
+
+	srv.Expect(policyURL,
+		httpmock.Method(http.MethodPatch),
+		httpmock.ReqJSON(map[string]interface{}{
+			"name":        "my-app-waf",
+			"description": "new",
+			"fingerprint": "abcd",
+		}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+
+	// New rules are added, changed rules are patched, and removed rules are removed.
+	srv.Expect(policyURL+`/addRule`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(map[string]interface{}{"priority": 1, "action": "deny(403)"}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(policyURL+`/patchRule?priority=2147483647`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(map[string]interface{}{"priority": 2147483647, "action": "deny(403)"}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(policyURL+`/removeRule?priority=1000`,
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+
+	m := newManager(t, srv, nil)
+
+	a, err := m.applier("compute.v1.securityPolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.update(context.Background(), "my-project",
+		map[string]interface{}{
+			"name":        "my-app-waf",
+			"description": "new",
+			"rules": []interface{}{
+				map[string]interface{}{"priority": 1.0, "action": "deny(403)"},
+				map[string]interface{}{"priority": 2147483647.0, "action": "deny(403)"},
+			},
+		},
+		map[string]interface{}{
+			"name":        "my-app-waf",
+			"description": "old",
+			"fingerprint": "abcd",
+			"rules": []interface{}{
+				map[string]interface{}{"priority": 1000.0, "action": "allow"},
+				map[string]interface{}{"priority": 2147483647.0, "action": "allow"},
+			},
+		},
+		10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComputeApplier_Update_Immutable(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	m := newManager(t, srv, nil)
+
+	a, err := m.applier("compute.v1.instanceTemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.update(context.Background(), "my-project",
+		map[string]interface{}{"name": "my-app-v1-it"}, map[string]interface{}{}, 10*time.Millisecond)
+
+	assert.Equal(t, "update()",
+		"compute.v1.instanceTemplate resources can't be updated, but my-app-v1-it has changed", err.Error())
+}
+
+func TestBindMember(t *testing.T) {
+	t.Parallel()
+
+	policy := &cloudresourcemanager.Policy{
+		Bindings: []*cloudresourcemanager.Binding{
+			{Role: "roles/viewer", Members: []string{"user:a"}},
+		},
+	}
+
+	assert.Equal(t, "add existing", false, bindMember(policy, "roles/viewer", "user:a", true))
+	assert.Equal(t, "add member", true, bindMember(policy, "roles/viewer", "user:b", true))
+	assert.Equal(t, "add role", true, bindMember(policy, "roles/editor", "user:a", true))
+	assert.Equal(t, "remove member", true, bindMember(policy, "roles/viewer", "user:a", false))
+	assert.Equal(t, "remove missing", false, bindMember(policy, "roles/owner", "user:a", false))
+
+	want := &cloudresourcemanager.Policy{
+		Bindings: []*cloudresourcemanager.Binding{
+			{Role: "roles/viewer", Members: []string{"user:b"}},
+			{Role: "roles/editor", Members: []string{"user:a"}},
+		},
+	}
+
+	assert.Equal(t, "policy", want, policy)
+}
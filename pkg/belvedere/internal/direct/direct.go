@@ -0,0 +1,783 @@
+// Package direct provides a deployments.Manager implementation which applies deployment resources
+// using the Compute Engine, Cloud DNS, and IAM APIs directly instead of via Deployment Manager. The
+// state of each deployment is stored as a JSON object in a Cloud Storage bucket in the project.
+package direct
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/buckets"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/live"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/oauth2/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// NewManager returns a deployments.Manager which applies resources directly.
+func NewManager(ctx context.Context, opts ...option.ClientOption) (deployments.Manager, error) {
+	gce, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := dns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	crm, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	gcs, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	us, err := oauth2.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lf, err := live.NewFetcher(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute Engine resources are applied via REST, since their properties are already in the API's
+	// representation.
+	hc, _, err := htransport.NewClient(ctx,
+		append([]option.ClientOption{option.WithScopes(compute.CloudPlatformScope)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{
+		gce:     gce,
+		dns:     ds,
+		iam:     is,
+		crm:     crm,
+		gcs:     gcs,
+		buckets: buckets.NewManager(gcs, crm),
+		users:   us,
+		hc:      hc,
+		live:    lf,
+		out:     os.Stdout,
+		now:     time.Now,
+	}, nil
+}
+
+type manager struct {
+	gce     *compute.Service
+	dns     *dns.Service
+	iam     *iam.Service
+	crm     *cloudresourcemanager.Service
+	gcs     *storage.Service
+	buckets *buckets.Manager
+	users   *oauth2.Service
+	hc      *http.Client
+	live    live.Fetcher
+	out     io.Writer // where previews are printed
+	now     func() time.Time
+}
+
+var _ deployments.Manager = &manager{}
+
+// ResourceError is an error applying a particular resource.
+type ResourceError struct {
+	Name string
+	Type string
+	Err  error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("error applying %s: %v", e.Name, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+type UnresolvedReferenceError struct {
+	Refs []string
+}
+
+func (e *UnresolvedReferenceError) Error() string {
+	return fmt.Sprintf("unresolved references to %s", strings.Join(e.Refs, ", "))
+}
+
+type OperationInProgressError struct {
+	Name string
+	Type string
+}
+
+func (e *OperationInProgressError) Error() string {
+	return fmt.Sprintf("deployment %s has a running %s operation", e.Name, e.Type)
+}
+
+func (m *manager) Get(ctx context.Context, project, name string) (*deployments.Deployment, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Get")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deployments.Deployment{Name: s.Name, Created: s.Created, Creator: s.Creator, Labels: s.Labels}, nil
+}
+
+func (m *manager) Insert(
+	ctx context.Context, project, name string, resources []deployments.Resource, labels deployments.Labels,
+	dryRun, preview bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Insert")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
+	)
+
+	// Pretty-print the config and early exit if we don't want side effects.
+	if dryRun {
+		return printConfig(resources)
+	}
+
+	target, err := toStored(resources)
+	if err != nil {
+		return err
+	}
+
+	if preview {
+		return m.printPreview(nil, target)
+	}
+
+	// Make sure we're not clobbering an existing deployment. If another process creates it after
+	// this check, saving the new state fails instead.
+	var notFound *NotFoundError
+	if _, err := m.load(ctx, project, name); err == nil {
+		return &AlreadyExistsError{Name: name}
+	} else if !errors.As(err, &notFound) {
+		return err
+	}
+
+	s := &state{Name: name, Labels: labels, Created: m.now().UTC(), Creator: deployments.Caller(ctx, m.users)}
+
+	return m.apply(ctx, project, s, "insert", target, interval)
+}
+
+func (m *manager) Update(
+	ctx context.Context, project, name string, resources []deployments.Resource, dryRun, preview bool,
+	interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Update")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
+	)
+
+	// Pretty-print the config and early exit if we don't want side effects.
+	if dryRun {
+		return printConfig(resources)
+	}
+
+	target, err := toStored(resources)
+	if err != nil {
+		return err
+	}
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return err
+	}
+
+	if preview {
+		return m.printPreview(s.Manifest, target)
+	}
+
+	return m.apply(ctx, project, s, "update", target, interval)
+}
+
+// Delete deletes the given deployment's resources and then its state. Deletes are always
+// synchronous, since there's no operation to continue them in the background.
+func (m *manager) Delete(
+	ctx context.Context, project, name string, dryRun, async bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Delete")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("async", async),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
+	}
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return err
+	}
+
+	if err := m.apply(ctx, project, s, "delete", nil, interval); err != nil {
+		return err
+	}
+
+	return m.remove(ctx, project, name)
+}
+
+func (m *manager) List(ctx context.Context, project, filter string) ([]deployments.Deployment, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.List")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+	)
+
+	names, err := m.list(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []deployments.Deployment
+
+	for _, name := range names {
+		s, err := m.load(ctx, project, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := matches(filter, &s.Labels)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			list = append(list, deployments.Deployment{
+				Name:    s.Name,
+				Created: s.Created,
+				Creator: s.Creator,
+				Labels:  s.Labels,
+			})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list, nil
+}
+
+func (m *manager) Resources(ctx context.Context, project, name string) ([]deployments.Resource, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Resources")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Manifest == nil {
+		return nil, &deployments.NoManifestError{Name: name}
+	}
+
+	return fromStored(s.Manifest), nil
+}
+
+func (m *manager) Status(ctx context.Context, project, name string) (*deployments.Status, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Status")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+	)
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := deployments.Status{
+		Operation: s.Operation.Type,
+		State:     s.Operation.State,
+		Errors:    s.Operation.Errors,
+	}
+
+	// The manifest is stored in the deployment's state object.
+	if s.Manifest != nil {
+		status.Manifest = object(name)
+	}
+
+	if f := s.Operation.Failed; f != nil {
+		status.Resources = []deployments.ResourceStatus{
+			{Name: f.Name, Type: f.Type, State: "FAILED", Errors: []string{f.Error}},
+		}
+	}
+
+	return &status, nil
+}
+
+// Stop marks the given deployment's running operation as done. The process which is running the
+// operation isn't interrupted, but will fail to record its results.
+func (m *manager) Stop(
+	ctx context.Context, project, name string, dryRun bool, _ time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Stop")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
+	}
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return err
+	}
+
+	if s.Operation.State != "RUNNING" {
+		return nil
+	}
+
+	s.Operation.State = "DONE"
+	s.Operation.Errors = append(s.Operation.Errors, "operation stopped")
+
+	return m.save(ctx, project, s)
+}
+
+// CancelPreview does nothing, since previews don't leave anything behind.
+func (m *manager) CancelPreview(
+	ctx context.Context, project, name string, dryRun bool, _ time.Duration,
+) error {
+	_, span := trace.StartSpan(ctx, "belvedere.internal.direct.CancelPreview")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	return nil
+}
+
+func (m *manager) Restore(
+	ctx context.Context, project, name string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Restore")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	s, err := m.load(ctx, project, name)
+	if err != nil {
+		return fmt.Errorf("error getting deployment: %w", err)
+	}
+
+	if s.Manifest == nil {
+		return &deployments.NoManifestError{Name: name}
+	}
+
+	// Print the manifest's config and early exit if we don't want side effects.
+	if dryRun {
+		return printConfig(fromStored(s.Manifest))
+	}
+
+	return m.apply(ctx, project, s, "update", s.Manifest, interval)
+}
+
+// Abandon deletes the given deployment's state but leaves its resources in place.
+func (m *manager) Abandon(
+	ctx context.Context, project, name string, dryRun bool, _ time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.direct.Abandon")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	// Early exit if we don't want side effects.
+	if dryRun {
+		return nil
+	}
+
+	return m.remove(ctx, project, name)
+}
+
+// apply records a running operation in the deployment's state, applies the target resources, and
+// records the results. Resources in the deployment's manifest or in the target of its last failed
+// operation which aren't in the target resources are deleted.
+func (m *manager) apply(
+	ctx context.Context, project string, s *state, opType string, target []resource, interval time.Duration,
+) error {
+	// Don't clobber another process's operation. Stop can be used if it was interrupted. If another
+	// process starts an operation after the state was loaded, saving it fails instead.
+	if s.Operation.State == "RUNNING" {
+		return &OperationInProgressError{Name: s.Name, Type: s.Operation.Type}
+	}
+
+	applied := [][]resource{s.Manifest, s.Operation.Target}
+
+	s.Operation = operation{Type: opType, State: "RUNNING", Target: target}
+	if err := m.save(ctx, project, s); err != nil {
+		return err
+	}
+
+	applyErr := m.reconcile(ctx, project, applied, target, interval)
+
+	s.Operation.State = "DONE"
+
+	if applyErr != nil {
+		s.Operation.Errors = []string{applyErr.Error()}
+
+		var resErr *ResourceError
+		if errors.As(applyErr, &resErr) {
+			s.Operation.Failed = &failure{Name: resErr.Name, Type: resErr.Type, Error: resErr.Err.Error()}
+		}
+	} else {
+		s.Manifest = target
+		s.Operation.Target = nil
+	}
+
+	if err := m.save(ctx, project, s); err != nil {
+		return err
+	}
+
+	return applyErr
+}
+
+// reconcile creates or updates the target resources in dependency order, and then deletes any
+// previously applied resources which aren't in the target in reverse dependency order. Resources
+// which don't exist are created, and resources whose properties differ from any of their previously
+// applied properties are updated.
+func (m *manager) reconcile(
+	ctx context.Context, project string, applied [][]resource, target []resource, interval time.Duration,
+) error {
+	ordered, err := deployments.DependencyOrder(fromStored(target))
+	if err != nil {
+		return err
+	}
+
+	objects := map[string]map[string]interface{}{}
+	wanted := make(map[string]bool, len(target))
+
+	for i := range ordered {
+		r := &ordered[i]
+		wanted[r.Name] = true
+
+		if err := m.applyResource(ctx, project, r, changed(applied, r), objects, interval); err != nil {
+			return &ResourceError{Name: r.Name, Type: r.Type, Err: err}
+		}
+	}
+
+	var removed []deployments.Resource
+
+	for _, res := range applied {
+		for _, r := range fromStored(res) {
+			if !wanted[r.Name] {
+				wanted[r.Name] = true
+				removed = append(removed, r)
+			}
+		}
+	}
+
+	return m.deleteResources(ctx, project, removed, objects, interval)
+}
+
+// applyResource creates or updates the given resource and records its live state.
+func (m *manager) applyResource(
+	ctx context.Context, project string, r *deployments.Resource, changed bool,
+	objects map[string]map[string]interface{}, interval time.Duration,
+) error {
+	props, obj, err := m.fetch(ctx, project, r, objects)
+	if err != nil {
+		return err
+	}
+
+	a, err := m.applier(r.Type)
+	if err != nil {
+		return err
+	}
+
+	// Don't send references to resources which couldn't be fetched to the API.
+	if refs := deployments.References(props); len(refs) > 0 && (obj == nil || changed) {
+		return &UnresolvedReferenceError{Refs: refs}
+	}
+
+	switch {
+	case obj == nil:
+		err = a.create(ctx, project, props, interval)
+	case changed:
+		err = a.update(ctx, project, props, obj, interval)
+	default:
+		// The resource is up to date.
+		objects[r.Name] = obj
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Fetch the resource again, so resources which reference it can be resolved.
+	_, obj, err = m.fetch(ctx, project, r, objects)
+	if err != nil {
+		return err
+	}
+
+	objects[r.Name] = obj
+
+	return nil
+}
+
+// deleteResources deletes the given resources in reverse dependency order. Their live state is
+// fetched beforehand, so references between them can be resolved.
+func (m *manager) deleteResources(
+	ctx context.Context, project string, res []deployments.Resource,
+	objects map[string]map[string]interface{}, interval time.Duration,
+) error {
+	ordered, err := deployments.DependencyOrder(res)
+	if err != nil {
+		return err
+	}
+
+	props := make([]map[string]interface{}, len(ordered))
+	exists := make([]bool, len(ordered))
+
+	for i := range ordered {
+		p, obj, err := m.fetch(ctx, project, &ordered[i], objects)
+		if err != nil {
+			return &ResourceError{Name: ordered[i].Name, Type: ordered[i].Type, Err: err}
+		}
+
+		objects[ordered[i].Name] = obj
+		props[i] = p
+		exists[i] = obj != nil
+	}
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		r := &ordered[i]
+
+		// Resources which are already gone don't need to be deleted.
+		if !exists[i] {
+			continue
+		}
+
+		a, err := m.applier(r.Type)
+		if err == nil {
+			if refs := deployments.References(props[i]); len(refs) > 0 {
+				err = &UnresolvedReferenceError{Refs: refs}
+			} else {
+				err = a.delete(ctx, project, props[i], interval)
+			}
+		}
+
+		if err != nil {
+			return &ResourceError{Name: r.Name, Type: r.Type, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// fetch resolves the references in the given resource's properties and fetches its live state.
+// Compute Engine resources are named after their deployment resources unless otherwise specified.
+func (m *manager) fetch(
+	ctx context.Context, project string, r *deployments.Resource, objects map[string]map[string]interface{},
+) (map[string]interface{}, map[string]interface{}, error) {
+	var v interface{}
+	if err := r.DecodeProperties(&v); err != nil {
+		return nil, nil, fmt.Errorf("error decoding %s: %w", r.Name, err)
+	}
+
+	props, _ := deployments.ResolveRefs(v, func(name, property string) (interface{}, bool) {
+		if obj := objects[name]; obj != nil {
+			return deployments.Lookup(obj, property)
+		}
+
+		return nil, false
+	}).(map[string]interface{})
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+
+	if _, ok := computeKinds[r.Type]; ok && props["name"] == nil {
+		props["name"] = r.Name
+	}
+
+	b, err := json.Marshal(props)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj, err := m.live.Fetch(ctx, project, &deployments.Resource{
+		Name:       r.Name,
+		Type:       r.Type,
+		Properties: json.RawMessage(b),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return props, obj, nil
+}
+
+// changed returns true if the given resource is missing from or has different properties in any of
+// the given sets of applied resources.
+func changed(applied [][]resource, r *deployments.Resource) bool {
+	for _, res := range applied {
+		for _, a := range res {
+			if a.Name == r.Name && (a.Type != r.Type || !jsonEqual(a.Properties, r.Properties)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// printPreview prints the intent of each resource which would change, like Deployment Manager's
+// previews. Only the deployment's manifest is compared, not the live resources.
+func (m *manager) printPreview(manifest, target []resource) error {
+	tw := tabwriter.NewWriter(m.out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "INTENT\tRESOURCE\tTYPE")
+
+	current := make(map[string]resource, len(manifest))
+	for _, r := range manifest {
+		current[r.Name] = r
+	}
+
+	for _, r := range target {
+		old, ok := current[r.Name]
+
+		switch {
+		case !ok:
+			_, _ = fmt.Fprintf(tw, "CREATE_OR_ACQUIRE\t%s\t%s\n", r.Name, r.Type)
+		case old.Type != r.Type || !jsonEqual(old.Properties, r.Properties):
+			_, _ = fmt.Fprintf(tw, "UPDATE\t%s\t%s\n", r.Name, r.Type)
+		}
+
+		delete(current, r.Name)
+	}
+
+	for _, r := range manifest {
+		if _, ok := current[r.Name]; ok {
+			_, _ = fmt.Fprintf(tw, "DELETE\t%s\t%s\n", r.Name, r.Type)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// printConfig pretty-prints the resources as a Deployment Manager config.
+func printConfig(resources []deployments.Resource) error {
+	config, err := deployments.Config(resources)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(config)
+
+	return nil
+}
+
+// toStored converts the resources to their stored form, with raw JSON properties.
+func toStored(resources []deployments.Resource) ([]resource, error) {
+	stored := make([]resource, len(resources))
+
+	for i, r := range resources {
+		b, err := r.Properties.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("error generating JSON: %w", err)
+		}
+
+		stored[i] = resource{Name: r.Name, Type: r.Type, Properties: b}
+	}
+
+	return stored, nil
+}
+
+// fromStored converts the stored resources to deployment resources.
+func fromStored(stored []resource) []deployments.Resource {
+	resources := make([]deployments.Resource, len(stored))
+	for i, r := range stored {
+		resources[i] = deployments.Resource{Name: r.Name, Type: r.Type, Properties: r.Properties}
+	}
+
+	return resources
+}
+
+// jsonEqual returns true if the two JSON values are semantically equal.
+func jsonEqual(a json.RawMessage, b json.Marshaler) bool {
+	bb, err := b.MarshalJSON()
+	if err != nil {
+		return false
+	}
+
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(bb, &bv) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,632 @@
+package direct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/oauth2/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+func newManager(t *testing.T, srv *httpmock.Server, out *bytes.Buffer) *manager {
+	t.Helper()
+
+	m, err := NewManager(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithHTTPClient(&http.Client{Transport: generationTransport{}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dm := m.(*manager)
+	dm.out = out
+	dm.now = func() time.Time {
+		return time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	return dm
+}
+
+// generationTransport adds the generation header which Cloud Storage sends with object downloads.
+type generationTransport struct{}
+
+func (generationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && req.URL.Query().Get("alt") == "media" {
+		resp.Header.Set("X-Goog-Generation", "1")
+	}
+
+	return resp, err
+}
+
+func resourcesFixture() []deployments.Resource {
+	return []deployments.Resource{
+		{
+			Name: "my-app-bes",
+			Type: "compute.v1.backendService",
+			Properties: &compute.BackendService{
+				HealthChecks: []string{deployments.SelfLink("my-app-hc")},
+				TimeoutSec:   30,
+			},
+		},
+		{
+			Name: "my-app-hc",
+			Type: "compute.v1.healthCheck",
+			Properties: &compute.HealthCheck{
+				CheckIntervalSec: 10,
+			},
+		},
+	}
+}
+
+func stateFixture(props *compute.BackendService) *state {
+	b, _ := json.Marshal(props)
+
+	return &state{
+		Name:    "my-app",
+		Labels:  deployments.Labels{Type: "app", App: "my-app"},
+		Created: time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC),
+		Creator: "creator@example.com",
+		Manifest: []resource{
+			{Name: "my-app-bes", Type: "compute.v1.backendService", Properties: b},
+			{Name: "my-app-hc", Type: "compute.v1.healthCheck", Properties: json.RawMessage(`{"checkIntervalSec":10}`)},
+		},
+		Operation: operation{Type: "insert", State: "DONE"},
+	}
+}
+
+// expectBucket expects the project's state bucket to be checked.
+func expectBucket(srv *httpmock.Server) {
+	srv.Expect(bucketURL, httpmock.RespJSON(storage.Bucket{ProjectNumber: 123456}))
+	srv.Expect(`/v1/projects/my-project?alt=json&fields=projectNumber&prettyPrint=false`,
+		httpmock.RespJSON(cloudresourcemanager.Project{ProjectNumber: 123456}))
+}
+
+// expectCaller expects the account which is recorded as a new deployment's creator to be looked up.
+func expectCaller(srv *httpmock.Server) {
+	srv.Expect(`/oauth2/v2/userinfo?alt=json&prettyPrint=false`,
+		httpmock.RespJSON(oauth2.Userinfo{Email: "creator@example.com"}))
+}
+
+const (
+	bucketURL = `/b/my-project-belvedere-deployments?alt=json&fields=projectNumber&prettyPrint=false`
+	stateURL  = `/b/my-project-belvedere-deployments/o/my-app.json?alt=media&prettyPrint=false`
+	hcURL     = `/projects/my-project/global/healthChecks/my-app-hc?alt=json&prettyPrint=false`
+	besURL    = `/projects/my-project/global/backendServices/my-app-bes?alt=json&prettyPrint=false`
+	opURL     = `/projects/my-project/global/operations/op1?alt=json&fields=status%2Cerror&prettyPrint=false`
+)
+
+// uploadURL returns the URL of a state upload which requires the object to have the given generation.
+func uploadURL(generation int64) string {
+	return fmt.Sprintf(`/upload/storage/v1/b/my-project-belvedere-deployments/o?alt=json&ifGenerationMatch=%d`+
+		`&prettyPrint=false&uploadType=multipart`, generation)
+}
+
+func TestManager_Get(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{})))
+
+	m := newManager(t, srv, nil)
+
+	got, err := m.Get(context.Background(), "my-project", "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &deployments.Deployment{
+		Name:    "my-app",
+		Created: time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC),
+		Creator: "creator@example.com",
+		Labels:  deployments.Labels{Type: "app", App: "my-app"},
+	}
+
+	assert.Equal(t, "Get()", want, got)
+}
+
+func TestManager_Insert(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	// The deployment doesn't exist yet, and nor does the state bucket.
+	srv.Expect(bucketURL, httpmock.Status(http.StatusNotFound))
+	expectCaller(srv)
+	srv.Expect(bucketURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/b?alt=json&prettyPrint=false&project=my-project`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(storage.Bucket{
+			Name: "my-project-belvedere-deployments",
+			IamConfiguration: &storage.BucketIamConfiguration{
+				UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{
+					Enabled: true,
+				},
+			},
+			Versioning: &storage.BucketVersioning{
+				Enabled: true,
+			},
+		}),
+		httpmock.RespJSON(storage.Bucket{}))
+	srv.Expect(uploadURL(0),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 1}))
+
+	// The health check is created first, since the backend service references it.
+	srv.Expect(hcURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/projects/my-project/global/healthChecks`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(map[string]interface{}{
+			"name":             "my-app-hc",
+			"checkIntervalSec": 10,
+		}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(hcURL, httpmock.RespJSON(compute.HealthCheck{
+		Name:             "my-app-hc",
+		CheckIntervalSec: 10,
+		SelfLink:         "https://compute/my-app-hc",
+	}))
+
+	srv.Expect(besURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/projects/my-project/global/backendServices`,
+		httpmock.Method(http.MethodPost),
+		httpmock.ReqJSON(map[string]interface{}{
+			"name":         "my-app-bes",
+			"healthChecks": []string{"https://compute/my-app-hc"},
+			"timeoutSec":   30,
+		}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(besURL, httpmock.RespJSON(compute.BackendService{
+		Name:         "my-app-bes",
+		HealthChecks: []string{"https://compute/my-app-hc"},
+		TimeoutSec:   30,
+	}))
+
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 2}))
+
+	m := newManager(t, srv, nil)
+	if err := m.Insert(context.Background(), "my-project", "my-app", resourcesFixture(),
+		deployments.Labels{Type: "app", App: "my-app"}, false, false, 10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Insert_AlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{})))
+
+	m := newManager(t, srv, nil)
+	err := m.Insert(context.Background(), "my-project", "my-app", resourcesFixture(),
+		deployments.Labels{Type: "app", App: "my-app"}, false, false, 10*time.Millisecond,
+	)
+
+	assert.Equal(t, "Insert()", "deployment my-app already exists", err.Error())
+}
+
+func TestManager_Insert_CreatedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	// Another process creates the deployment after it's checked.
+	srv.Expect(stateURL, httpmock.Status(http.StatusNotFound))
+	expectCaller(srv)
+	srv.Expect(uploadURL(0),
+		httpmock.Method(http.MethodPost),
+		httpmock.Status(http.StatusPreconditionFailed))
+
+	m := newManager(t, srv, nil)
+	err := m.Insert(context.Background(), "my-project", "my-app", resourcesFixture(),
+		deployments.Labels{Type: "app", App: "my-app"}, false, false, 10*time.Millisecond,
+	)
+
+	assert.Equal(t, "Insert()", &AlreadyExistsError{Name: "my-app"}, err)
+}
+
+func TestManager_Update(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{
+		HealthChecks: []string{deployments.SelfLink("my-app-hc")},
+		TimeoutSec:   60,
+	})))
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 2}))
+
+	// The health check is unchanged.
+	srv.Expect(hcURL, httpmock.RespJSON(compute.HealthCheck{
+		Name:             "my-app-hc",
+		CheckIntervalSec: 10,
+		SelfLink:         "https://compute/my-app-hc",
+	}))
+
+	// The backend service is patched with its fingerprint.
+	srv.Expect(besURL, httpmock.RespJSON(compute.BackendService{
+		Name:         "my-app-bes",
+		HealthChecks: []string{"https://compute/my-app-hc"},
+		TimeoutSec:   60,
+		Fingerprint:  "abcd",
+	}))
+	srv.Expect(`/projects/my-project/global/backendServices/my-app-bes`,
+		httpmock.Method(http.MethodPatch),
+		httpmock.ReqJSON(map[string]interface{}{
+			"name":         "my-app-bes",
+			"healthChecks": []string{"https://compute/my-app-hc"},
+			"timeoutSec":   30,
+			"fingerprint":  "abcd",
+		}),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(besURL, httpmock.RespJSON(compute.BackendService{
+		Name:         "my-app-bes",
+		HealthChecks: []string{"https://compute/my-app-hc"},
+		TimeoutSec:   30,
+	}))
+
+	srv.Expect(uploadURL(2),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 3}))
+
+	m := newManager(t, srv, nil)
+	if err := m.Update(context.Background(), "my-project", "my-app", resourcesFixture(),
+		false, false, 10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Update_OperationInProgress(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	s := stateFixture(&compute.BackendService{})
+	s.Operation = operation{Type: "update", State: "RUNNING"}
+
+	srv.Expect(stateURL, httpmock.RespJSON(s))
+
+	m := newManager(t, srv, nil)
+	err := m.Update(context.Background(), "my-project", "my-app", resourcesFixture(),
+		false, false, 10*time.Millisecond,
+	)
+
+	assert.Equal(t, "Update()", &OperationInProgressError{Name: "my-app", Type: "update"}, err)
+}
+
+func TestManager_Update_StartedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	// Another process starts an operation after the state is loaded.
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{})))
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.Status(http.StatusPreconditionFailed))
+
+	s := stateFixture(&compute.BackendService{})
+	s.Operation = operation{Type: "delete", State: "RUNNING"}
+
+	srv.Expect(stateURL, httpmock.RespJSON(s))
+
+	m := newManager(t, srv, nil)
+	err := m.Update(context.Background(), "my-project", "my-app", resourcesFixture(),
+		false, false, 10*time.Millisecond,
+	)
+
+	assert.Equal(t, "Update()", &OperationInProgressError{Name: "my-app", Type: "delete"}, err)
+}
+
+func TestManager_Update_UnresolvedReference(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{
+		HealthChecks: []string{deployments.SelfLink("my-app-hc")},
+	})))
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 2}))
+
+	// The backend service refers to a health check which isn't part of the deployment.
+	srv.Expect(besURL, httpmock.Status(http.StatusNotFound))
+	srv.Expect(uploadURL(2),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 3}))
+
+	m := newManager(t, srv, nil)
+	err := m.Update(context.Background(), "my-project", "my-app", []deployments.Resource{
+		{
+			Name: "my-app-bes",
+			Type: "compute.v1.backendService",
+			Properties: &compute.BackendService{
+				HealthChecks: []string{deployments.SelfLink("other-hc")},
+			},
+		},
+	}, false, false, 10*time.Millisecond)
+
+	var refErr *UnresolvedReferenceError
+	if !errors.As(err, &refErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "Update()", []string{"other-hc"}, refErr.Refs)
+}
+
+func TestManager_Update_Preview(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{
+		HealthChecks: []string{deployments.SelfLink("my-app-hc")},
+		TimeoutSec:   60,
+	})))
+
+	out := bytes.NewBuffer(nil)
+	m := newManager(t, srv, out)
+
+	res := append(resourcesFixture()[:1], deployments.Resource{
+		Name:       "my-app-ip",
+		Type:       "compute.v1.globalAddress",
+		Properties: &compute.Address{},
+	})
+
+	if err := m.Update(context.Background(), "my-project", "my-app", res,
+		false, true, 10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Update()",
+		`INTENT             RESOURCE    TYPE
+UPDATE             my-app-bes  compute.v1.backendService
+CREATE_OR_ACQUIRE  my-app-ip   compute.v1.globalAddress
+DELETE             my-app-hc   compute.v1.healthCheck
+`, out.String())
+}
+
+func TestManager_Delete(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{
+		HealthChecks: []string{deployments.SelfLink("my-app-hc")},
+	})))
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 2}))
+
+	// Live resources are fetched in dependency order.
+	srv.Expect(hcURL, httpmock.RespJSON(compute.HealthCheck{
+		Name:     "my-app-hc",
+		SelfLink: "https://compute/my-app-hc",
+	}))
+	srv.Expect(besURL, httpmock.RespJSON(compute.BackendService{
+		Name: "my-app-bes",
+	}))
+
+	// And deleted in reverse dependency order.
+	srv.Expect(`/projects/my-project/global/backendServices/my-app-bes`,
+		httpmock.Method(http.MethodDelete),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+	srv.Expect(`/projects/my-project/global/healthChecks/my-app-hc`,
+		httpmock.Method(http.MethodDelete),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{Status: "DONE"}))
+
+	srv.Expect(uploadURL(2),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 3}))
+	srv.Expect(`/b/my-project-belvedere-deployments/o/my-app.json?alt=json&prettyPrint=false`,
+		httpmock.Method(http.MethodDelete))
+
+	m := newManager(t, srv, nil)
+	if err := m.Delete(context.Background(), "my-project", "my-app", false, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_Delete_Failed(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{})))
+	srv.Expect(uploadURL(1),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 2}))
+	srv.Expect(besURL, httpmock.RespJSON(compute.BackendService{Name: "my-app-bes"}))
+	srv.Expect(hcURL, httpmock.RespJSON(compute.HealthCheck{Name: "my-app-hc"}))
+	srv.Expect(`/projects/my-project/global/healthChecks/my-app-hc`,
+		httpmock.Method(http.MethodDelete),
+		httpmock.RespJSON(compute.Operation{Name: "op1"}))
+	srv.Expect(opURL, httpmock.RespJSON(compute.Operation{
+		Status: "DONE",
+		Error: &compute.OperationError{
+			Errors: []*compute.OperationErrorErrors{{Message: "in use"}},
+		},
+	}))
+	srv.Expect(uploadURL(2),
+		httpmock.Method(http.MethodPost),
+		httpmock.RespJSON(storage.Object{Generation: 3}))
+
+	m := newManager(t, srv, nil)
+	err := m.Delete(context.Background(), "my-project", "my-app", false, false, 10*time.Millisecond)
+
+	var resErr *ResourceError
+	if !errors.As(err, &resErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "Delete()", "my-app-hc", resErr.Name)
+}
+
+func TestManager_List(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	srv.Expect(`/b/my-project-belvedere-deployments/o?alt=json&fields=items%2Fname%2CnextPageToken&prettyPrint=false`,
+		httpmock.RespJSON(storage.Objects{
+			Items: []*storage.Object{
+				{Name: "my-app.json"},
+				{Name: "belvedere.json"},
+			},
+		}))
+	srv.Expect(stateURL, httpmock.RespJSON(stateFixture(&compute.BackendService{})))
+	srv.Expect(`/b/my-project-belvedere-deployments/o/belvedere.json?alt=media&prettyPrint=false`,
+		httpmock.RespJSON(state{
+			Name:   "belvedere",
+			Labels: deployments.Labels{Type: "base"},
+		}))
+
+	m := newManager(t, srv, nil)
+
+	got, err := m.List(context.Background(), "my-project", `labels.belvedere-type eq "app"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []deployments.Deployment{
+		{
+			Name:    "my-app",
+			Created: time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC),
+			Creator: "creator@example.com",
+			Labels:  deployments.Labels{Type: "app", App: "my-app"},
+		},
+	}
+
+	assert.Equal(t, "List()", want, got)
+}
+
+func TestManager_Status(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	expectBucket(srv)
+
+	s := stateFixture(&compute.BackendService{})
+	s.Operation = operation{
+		Type:   "update",
+		State:  "DONE",
+		Errors: []string{"error applying my-app-bes: bad"},
+		Failed: &failure{Name: "my-app-bes", Type: "compute.v1.backendService", Error: "bad"},
+	}
+
+	srv.Expect(stateURL, httpmock.RespJSON(s))
+
+	m := newManager(t, srv, nil)
+
+	got, err := m.Status(context.Background(), "my-project", "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &deployments.Status{
+		Operation: "update",
+		State:     "DONE",
+		Errors:    []string{"error applying my-app-bes: bad"},
+		Manifest:  "my-app.json",
+		Resources: []deployments.ResourceStatus{
+			{Name: "my-app-bes", Type: "compute.v1.backendService", State: "FAILED", Errors: []string{"bad"}},
+		},
+	}
+
+	assert.Equal(t, "Status()", want, got)
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+
+	labels := &deployments.Labels{Type: "release", App: "my-app", Release: "v1"}
+
+	tests := []struct {
+		filter string
+		want   bool
+		errMsg string
+	}{
+		{filter: "", want: true},
+		{filter: `labels.belvedere-type eq "release"`, want: true},
+		{filter: `labels.belvedere-type eq "release" AND labels.belvedere-app eq "my-app"`, want: true},
+		{filter: `labels.belvedere-type eq "release" AND labels.belvedere-app eq "other"`, want: false},
+		{filter: `name eq "my-app"`, errMsg: `unsupported filter: name eq "my-app"`},
+	}
+
+	for _, testCase := range tests {
+		got, err := matches(testCase.filter, labels)
+
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+
+		assert.Equal(t, testCase.filter, testCase.want, got)
+		assert.Equal(t, testCase.filter+" error", testCase.errMsg, errMsg)
+	}
+}
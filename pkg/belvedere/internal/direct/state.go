@@ -0,0 +1,265 @@
+package direct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+// state is the recorded state of a deployment, stored as a JSON object in the project's state
+// bucket.
+type state struct {
+	Name    string             `json:"name"`
+	Labels  deployments.Labels `json:"labels"`
+	Created time.Time          `json:"created"`
+	// Creator is the account which created the deployment, if it's known.
+	Creator string `json:"creator,omitempty"`
+	// Manifest is the list of resources which was last applied successfully, if any.
+	Manifest []resource `json:"manifest,omitempty"`
+	// Operation is the deployment's most recent operation.
+	Operation operation `json:"operation"`
+
+	// generation is the generation of the state object which was loaded or last saved, or zero if
+	// the deployment is new.
+	generation int64
+}
+
+// operation is the state of an operation on a deployment.
+type operation struct {
+	// Type is the type of operation: insert, update, or delete.
+	Type string `json:"type"`
+	// State is either RUNNING or DONE.
+	State  string   `json:"state"`
+	Errors []string `json:"errors,omitempty"`
+	// Target is the list of resources being applied. It's kept after a failure, since some of its
+	// resources may have been created.
+	Target []resource `json:"target,omitempty"`
+	// Failed is the resource which failed to apply, if any.
+	Failed *failure `json:"failed,omitempty"`
+}
+
+// failure is a resource which failed to apply.
+type failure struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// resource is a deployment resource with raw JSON properties, as stored.
+type resource struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("deployment %s not found", e.Name)
+}
+
+type AlreadyExistsError struct {
+	Name string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("deployment %s already exists", e.Name)
+}
+
+type ChangedError struct {
+	Name string
+}
+
+func (e *ChangedError) Error() string {
+	return fmt.Sprintf("deployment %s was changed by another process", e.Name)
+}
+
+// bucket returns the name of the project's state bucket.
+func bucket(project string) string {
+	return fmt.Sprintf("%s-belvedere-deployments", project)
+}
+
+// object returns the name of the deployment's state object.
+func object(name string) string {
+	return fmt.Sprintf("%s.json", name)
+}
+
+// load returns the state of the given deployment.
+func (m *manager) load(ctx context.Context, project, name string) (*state, error) {
+	exists, err := m.buckets.Exists(ctx, project, bucket(project))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, &NotFoundError{Name: name}
+	}
+
+	resp, err := m.gcs.Objects.Get(bucket(project), object(name)).Context(ctx).Download()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &NotFoundError{Name: name}
+		}
+
+		return nil, fmt.Errorf("error reading state: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var s state
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("error parsing state: %w", err)
+	}
+
+	// Keep the object's generation, so it's only overwritten if no other process has changed it.
+	s.generation, err = strconv.ParseInt(resp.Header.Get("X-Goog-Generation"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state generation: %w", err)
+	}
+
+	return &s, nil
+}
+
+// save records the state of the given deployment, creating the project's state bucket if needed.
+// The state is only written if its object hasn't changed since it was loaded or last saved, or, for
+// new deployments, if it doesn't exist yet.
+func (m *manager) save(ctx context.Context, project string, s *state) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := m.buckets.Ensure(ctx, project, bucket(project)); err != nil {
+		return err
+	}
+
+	obj, err := m.gcs.Objects.Insert(bucket(project), &storage.Object{
+		Name:        object(s.Name),
+		ContentType: "application/json",
+	}).Media(bytes.NewReader(b)).IfGenerationMatch(s.generation).Context(ctx).Do()
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return m.conflict(ctx, project, s)
+		}
+
+		return fmt.Errorf("error writing state: %w", err)
+	}
+
+	s.generation = obj.Generation
+
+	return nil
+}
+
+// conflict returns the reason the given state couldn't be saved: either the deployment was created
+// by another process, another process is running an operation on it, or another process changed it.
+func (m *manager) conflict(ctx context.Context, project string, s *state) error {
+	if s.generation == 0 {
+		return &AlreadyExistsError{Name: s.Name}
+	}
+
+	current, err := m.load(ctx, project, s.Name)
+	if err != nil {
+		return err
+	}
+
+	if current.Operation.State == "RUNNING" {
+		return &OperationInProgressError{Name: s.Name, Type: current.Operation.Type}
+	}
+
+	return &ChangedError{Name: s.Name}
+}
+
+// remove deletes the state of the given deployment.
+func (m *manager) remove(ctx context.Context, project, name string) error {
+	if exists, err := m.buckets.Exists(ctx, project, bucket(project)); err != nil || !exists {
+		return err
+	}
+
+	if err := m.gcs.Objects.Delete(bucket(project), object(name)).Context(ctx).Do(); err != nil &&
+		!isNotFound(err) {
+		return fmt.Errorf("error deleting state: %w", err)
+	}
+
+	return nil
+}
+
+// list returns the names of all deployments in the project.
+func (m *manager) list(ctx context.Context, project string) ([]string, error) {
+	// A project without a state bucket has no deployments.
+	exists, err := m.buckets.Exists(ctx, project, bucket(project))
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var names []string
+
+	if err := m.gcs.Objects.List(bucket(project)).Fields("items/name", "nextPageToken").Pages(ctx,
+		func(objects *storage.Objects) error {
+			for _, o := range objects.Items {
+				names = append(names, strings.TrimSuffix(o.Name, ".json"))
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, fmt.Errorf("error listing state: %w", err)
+	}
+
+	return names, nil
+}
+
+func isNotFound(err error) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound
+}
+
+func isPreconditionFailed(err error) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == http.StatusPreconditionFailed
+}
+
+//nolint:gochecknoglobals // can't have const regexps
+var labelFilterRegexp = regexp.MustCompile(`^labels\.([\w-]+) eq "([^"]*)"$`)
+
+type UnsupportedFilterError struct {
+	Filter string
+}
+
+func (e *UnsupportedFilterError) Error() string {
+	return fmt.Sprintf("unsupported filter: %s", e.Filter)
+}
+
+// matches returns true if the labels match the given filter. Only the subset of Deployment Manager
+// filter expressions which Belvedere uses is supported: label equality, joined with AND.
+func matches(filter string, labels *deployments.Labels) (bool, error) {
+	if filter == "" {
+		return true, nil
+	}
+
+	m := labels.Map()
+
+	for _, term := range strings.Split(filter, " AND ") {
+		parts := labelFilterRegexp.FindStringSubmatch(strings.TrimSpace(term))
+		if parts == nil {
+			return false, &UnsupportedFilterError{Filter: filter}
+		}
+
+		if m[parts[1]] != parts[2] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,278 @@
+// Package live fetches the live state of the resources managed by Deployment Manager from the APIs
+// which own them.
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// Fetcher fetches the live state of Deployment Manager resources.
+type Fetcher interface {
+	// Fetch returns the live state of the given resource as a generic JSON object, in the same
+	// shape as the resource's properties. Any references in the resource's properties must already
+	// be resolved. If the resource doesn't exist, returns nil.
+	Fetch(ctx context.Context, project string, r *deployments.Resource) (map[string]interface{}, error)
+}
+
+// NewFetcher returns a new Fetcher implementation.
+func NewFetcher(ctx context.Context, opts ...option.ClientOption) (Fetcher, error) {
+	gce, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := dns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	crm, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Security policies are fetched via REST, since the API client drops their rate limiting options.
+	hc, _, err := htransport.NewClient(ctx,
+		append([]option.ClientOption{option.WithScopes(compute.CloudPlatformScope)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fetcher{gce: gce, dns: ds, iam: is, crm: crm, hc: hc}, nil
+}
+
+type fetcher struct {
+	gce *compute.Service
+	dns *dns.Service
+	iam *iam.Service
+	crm *cloudresourcemanager.Service
+	hc  *http.Client
+}
+
+var _ Fetcher = &fetcher{}
+
+type UnsupportedTypeError struct {
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported resource type: %s", e.Type)
+}
+
+// properties are the fields of resource properties which are needed to find the live resource.
+type properties struct {
+	Name        string `json:"name"`
+	Region      string `json:"region"`
+	AccountID   string `json:"accountId"`
+	ManagedZone string `json:"managedZone"`
+	Resource    string `json:"resource"`
+	Role        string `json:"role"`
+	Member      string `json:"member"`
+}
+
+func (f *fetcher) Fetch(
+	ctx context.Context, project string, r *deployments.Resource,
+) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.live.Fetch")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("project", project),
+		trace.StringAttribute("name", r.Name),
+		trace.StringAttribute("type", r.Type),
+	)
+
+	var props properties
+	if err := r.DecodeProperties(&props); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", r.Name, err)
+	}
+
+	// Resources are named after their Deployment Manager resources unless otherwise specified.
+	if props.Name == "" {
+		props.Name = r.Name
+	}
+
+	v, err := f.fetch(ctx, project, r.Type, &props)
+	if err != nil {
+		// Resources which don't exist have no live state.
+		var googleErr *googleapi.Error
+		if errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error fetching %s: %w", r.Name, err)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	return toObject(v)
+}
+
+//nolint:gocyclo,cyclop // it's just a big switch
+func (f *fetcher) fetch(ctx context.Context, project, t string, p *properties) (interface{}, error) {
+	switch t {
+	case "compute.v1.globalAddress":
+		return f.gce.GlobalAddresses.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.firewall":
+		return f.gce.Firewalls.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.healthCheck":
+		return f.gce.HealthChecks.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.backendService":
+		return f.gce.BackendServices.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.urlMap":
+		return f.gce.UrlMaps.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.sslCertificate":
+		return f.gce.SslCertificates.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.targetHttpsProxy":
+		return f.gce.TargetHttpsProxies.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.targetHttpProxy":
+		return f.gce.TargetHttpProxies.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.globalForwardingRule":
+		return f.gce.GlobalForwardingRules.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.securityPolicy":
+		return f.securityPolicy(ctx, project, p)
+	case "compute.v1.instanceTemplate":
+		return f.gce.InstanceTemplates.Get(project, p.Name).Context(ctx).Do()
+	case "compute.v1.regionInstanceGroupManager":
+		return f.gce.RegionInstanceGroupManagers.Get(project, p.Region, p.Name).Context(ctx).Do()
+	case "compute.v1.regionAutoscaler":
+		return f.gce.RegionAutoscalers.Get(project, p.Region, p.Name).Context(ctx).Do()
+	case "dns.v1.managedZone":
+		return f.dns.ManagedZones.Get(project, p.Name).Context(ctx).Do()
+	case "iam.v1.serviceAccount":
+		return f.serviceAccount(ctx, project, p)
+	case "gcp-types/dns-v1:resourceRecordSets":
+		return f.resourceRecordSets(ctx, project, p)
+	case "gcp-types/cloudresourcemanager-v1:virtual.projects.iamMemberBinding":
+		return f.iamMemberBinding(ctx, p)
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+}
+
+// serviceAccount returns the live service account in the shape of a Deployment Manager service
+// account, plus its email address.
+func (f *fetcher) serviceAccount(ctx context.Context, project string, p *properties) (interface{}, error) {
+	sa, err := f.iam.Projects.ServiceAccounts.Get(
+		fmt.Sprintf("projects/%s/serviceAccounts/%s@%s.iam.gserviceaccount.com", project, p.AccountID, project),
+	).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"accountId":   strings.Split(sa.Email, "@")[0],
+		"displayName": sa.DisplayName,
+		"email":       sa.Email,
+	}, nil
+}
+
+// securityPolicy returns the live security policy as it's returned by the API, including any fields
+// which the API client doesn't support.
+func (f *fetcher) securityPolicy(ctx context.Context, project string, p *properties) (interface{}, error) {
+	u := googleapi.ResolveRelative(f.gce.BasePath,
+		fmt.Sprintf("projects/%s/global/securityPolicies/%s", project, p.Name))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var policy map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// resourceRecordSets returns the live resource record sets with the given name in the shape of
+// Deployment Manager resource record sets.
+func (f *fetcher) resourceRecordSets(ctx context.Context, project string, p *properties) (interface{}, error) {
+	rrs, err := f.dns.ResourceRecordSets.List(project, p.ManagedZone).Name(p.Name).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rrs.Rrsets) == 0 {
+		return nil, nil
+	}
+
+	return &deployments.ResourceRecordSets{
+		Name:        p.Name,
+		ManagedZone: p.ManagedZone,
+		Records:     rrs.Rrsets,
+	}, nil
+}
+
+// iamMemberBinding returns the binding if the member is bound to the role in the project's IAM
+// policy.
+func (f *fetcher) iamMemberBinding(ctx context.Context, p *properties) (interface{}, error) {
+	policy, err := f.crm.Projects.GetIamPolicy(p.Resource, &cloudresourcemanager.GetIamPolicyRequest{}).
+		Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, binding := range policy.Bindings {
+		if binding.Role != p.Role {
+			continue
+		}
+
+		for _, member := range binding.Members {
+			if member == p.Member {
+				return &deployments.IAMMemberBinding{Resource: p.Resource, Role: p.Role, Member: p.Member}, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// toObject converts the given API object into a generic JSON object.
+func toObject(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,225 @@
+package live
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+)
+
+func newFetcher(t *testing.T, srv *httpmock.Server) Fetcher {
+	t.Helper()
+
+	f, err := NewFetcher(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/global/backendServices/my-app-bes?alt=json&prettyPrint=false`,
+		httpmock.RespJSON(compute.BackendService{
+			Name:       "my-app-bes",
+			TimeoutSec: 30,
+		}))
+
+	got, err := newFetcher(t, srv).Fetch(context.Background(), "my-project", &deployments.Resource{
+		Name:       "my-app-bes",
+		Type:       "compute.v1.backendService",
+		Properties: &compute.BackendService{TimeoutSec: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"name":       "my-app-bes",
+		"timeoutSec": 30.0,
+	}
+
+	assert.Equal(t, "Fetch()", want, got)
+}
+
+func TestFetcher_Fetch_Missing(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/regions/us-central1/autoscalers/my-app-v1?alt=json&prettyPrint=false`,
+		httpmock.Status(http.StatusNotFound))
+
+	got, err := newFetcher(t, srv).Fetch(context.Background(), "my-project", &deployments.Resource{
+		Name: "my-app-v1-as",
+		Type: "compute.v1.regionAutoscaler",
+		Properties: &compute.Autoscaler{
+			Name:   "my-app-v1",
+			Region: "us-central1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Fetch()", map[string]interface{}(nil), got)
+}
+
+func TestFetcher_Fetch_SecurityPolicy(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/global/securityPolicies/my-app-waf`,
+		httpmock.RespJSON(map[string]interface{}{
+			"name": "my-app-waf",
+			"rules": []interface{}{
+				map[string]interface{}{
+					"action":           "throttle",
+					"priority":         2147483547,
+					"rateLimitOptions": map[string]interface{}{"enforceOnKey": "IP"},
+				},
+			},
+		}))
+
+	got, err := newFetcher(t, srv).Fetch(context.Background(), "my-project", &deployments.Resource{
+		Name:       "my-app-waf",
+		Type:       "compute.v1.securityPolicy",
+		Properties: &deployments.SecurityPolicy{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"name": "my-app-waf",
+		"rules": []interface{}{
+			map[string]interface{}{
+				"action":           "throttle",
+				"priority":         2147483547.0,
+				"rateLimitOptions": map[string]interface{}{"enforceOnKey": "IP"},
+			},
+		},
+	}
+
+	assert.Equal(t, "Fetch()", want, got)
+}
+
+func TestFetcher_Fetch_ServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/v1/projects/my-project/serviceAccounts/app-my-app@my-project.iam.gserviceaccount.com?`+
+		`alt=json&prettyPrint=false`,
+		httpmock.RespJSON(iam.ServiceAccount{
+			DisplayName: "my-app",
+			Email:       "app-my-app@my-project.iam.gserviceaccount.com",
+		}))
+
+	got, err := newFetcher(t, srv).Fetch(context.Background(), "my-project", &deployments.Resource{
+		Name: "my-app-sa",
+		Type: "iam.v1.serviceAccount",
+		Properties: &deployments.ServiceAccount{
+			AccountID:   "app-my-app",
+			DisplayName: "my-app",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"accountId":   "app-my-app",
+		"displayName": "my-app",
+		"email":       "app-my-app@my-project.iam.gserviceaccount.com",
+	}
+
+	assert.Equal(t, "Fetch()", want, got)
+}
+
+func TestFetcher_Fetch_IAMMemberBinding(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/v1/projects/my-project:getIamPolicy?alt=json&prettyPrint=false`,
+		httpmock.RespJSON(cloudresourcemanager.Policy{
+			Bindings: []*cloudresourcemanager.Binding{
+				{
+					Role:    "roles/logging.logWriter",
+					Members: []string{"serviceAccount:app-my-app@my-project.iam.gserviceaccount.com"},
+				},
+			},
+		}))
+
+	srv.Expect(`/v1/projects/my-project:getIamPolicy?alt=json&prettyPrint=false`,
+		httpmock.RespJSON(cloudresourcemanager.Policy{}))
+
+	f := newFetcher(t, srv)
+	binding := &deployments.Resource{
+		Name: "my-app-sa-roles/logging.logWriter",
+		Type: "gcp-types/cloudresourcemanager-v1:virtual.projects.iamMemberBinding",
+		Properties: &deployments.IAMMemberBinding{
+			Resource: "my-project",
+			Role:     "roles/logging.logWriter",
+			Member:   "serviceAccount:app-my-app@my-project.iam.gserviceaccount.com",
+		},
+	}
+
+	got, err := f.Fetch(context.Background(), "my-project", binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"resource": "my-project",
+		"role":     "roles/logging.logWriter",
+		"member":   "serviceAccount:app-my-app@my-project.iam.gserviceaccount.com",
+	}
+
+	assert.Equal(t, "Fetch()", want, got)
+
+	got, err = f.Fetch(context.Background(), "my-project", binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Fetch() after removal", map[string]interface{}(nil), got)
+}
+
+func TestFetcher_Fetch_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	_, err := newFetcher(t, srv).Fetch(context.Background(), "my-project", &deployments.Resource{
+		Name:       "my-bucket",
+		Type:       "storage.v1.bucket",
+		Properties: &deployments.ServiceAccount{},
+	})
+
+	assert.Equal(t, "Fetch() error",
+		"error fetching my-bucket: unsupported resource type: storage.v1.bucket", err.Error())
+}
@@ -0,0 +1,302 @@
+// Package registry provides functions for working with Docker image references and the Google
+// container registries which host them.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// dockerHub is the registry host for images without an explicit registry.
+const dockerHub = "docker.io"
+
+// timeout is the maximum time allowed for each request to a registry.
+const timeout = 30 * time.Second
+
+// Host returns the registry host for the given image reference.
+func Host(image string) string {
+	host, _, _ := Parse(image)
+	return host
+}
+
+// Parse splits the given image reference into its registry host, its repository, and either its
+// digest (e.g. sha256:abcdef) or its tag. If the reference has neither, the tag is "latest".
+func Parse(image string) (host, repository, reference string) {
+	repository, reference = image, "latest"
+
+	if idx := strings.LastIndex(repository, "@"); idx >= 0 {
+		repository, reference = repository[:idx], repository[idx+1:]
+		// Ignore any tag which accompanies a digest.
+		if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+			repository = repository[:idx]
+		}
+	} else if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:idx], repository[idx+1:]
+	}
+
+	idx := strings.Index(repository, "/")
+	if idx < 0 {
+		return dockerHub, repository, reference
+	}
+
+	// Per the Docker reference grammar, the first component is a registry host only if it contains a
+	// dot or a port or is localhost.
+	host = repository[:idx]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHub, repository, reference
+	}
+
+	return host, repository[idx+1:], reference
+}
+
+// IsGoogle returns true if the given registry host is either Google Container Registry or Google
+// Artifact Registry.
+func IsGoogle(host string) bool {
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")
+}
+
+// Client resolves image tags to digests using the Docker Registry HTTP API V2.
+type Client interface {
+	// Digest returns the SHA-256 digest of the manifest of the given image reference, as a hex
+	// string. Requests to Google registries are authenticated with Google credentials; requests to
+	// all other registries are anonymous.
+	Digest(ctx context.Context, image string) (string, error)
+
+	// Exists returns true if the manifest of the given image reference exists in its registry. Unlike
+	// Digest, images which are pinned to a digest are looked up.
+	Exists(ctx context.Context, image string) (bool, error)
+}
+
+// NewClient returns a new Client using the given options for authentication.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (Client, error) {
+	hc, endpoint, err := htransport.NewClient(ctx,
+		append([]option.ClientOption{
+			option.WithScopes("https://www.googleapis.com/auth/cloud-platform"),
+		}, opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hc.Timeout = timeout
+
+	return &client{google: hc, anonymous: &http.Client{Timeout: timeout}, endpoint: endpoint}, nil
+}
+
+type client struct {
+	google    *http.Client
+	anonymous *http.Client
+	endpoint  string // only used in tests, in lieu of the registry host
+}
+
+type ManifestError struct {
+	Image      string
+	Status     string
+	StatusCode int
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("error getting manifest for %s: %s", e.Image, e.Status)
+}
+
+// manifestTypes are the media types of all the manifests we accept. Multi-arch images resolve to the
+// digest of their manifest list.
+//nolint:gochecknoglobals // can't have non-scalar consts
+var manifestTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+func (c *client) Digest(ctx context.Context, image string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.registry.Digest")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("image", image))
+
+	// Already pinned images don't need resolving.
+	if _, _, reference := Parse(image); strings.HasPrefix(reference, "sha256:") {
+		return strings.TrimPrefix(reference, "sha256:"), nil
+	}
+
+	digest, err := c.manifestDigest(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	span.AddAttributes(trace.StringAttribute("digest", digest))
+
+	return digest, nil
+}
+
+func (c *client) Exists(ctx context.Context, image string) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.internal.registry.Exists")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("image", image))
+
+	_, err := c.manifestDigest(ctx, image)
+
+	var manifestErr *ManifestError
+	if errors.As(err, &manifestErr) && manifestErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// manifestDigest fetches the manifest of the given image reference and returns its digest.
+func (c *client) manifestDigest(ctx context.Context, image string) (string, error) {
+	host, repository, reference := Parse(image)
+
+	// Only send Google credentials to Google registries.
+	hc, base := c.google, fmt.Sprintf("https://%s", host)
+	if !IsGoogle(host) {
+		hc = c.anonymous
+	}
+
+	// Docker Hub's API lives elsewhere, and official images are in the library namespace.
+	if host == dockerHub {
+		base = "https://registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = fmt.Sprintf("library/%s", repository)
+		}
+	}
+
+	if c.endpoint != "" {
+		base = c.endpoint
+	}
+
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", base, repository, reference)
+
+	resp, err := c.getManifest(ctx, hc, url, "")
+	if err != nil {
+		return "", fmt.Errorf("error getting manifest for %s: %w", image, err)
+	}
+
+	// Public registries require an anonymous token, even for public images.
+	if resp.StatusCode == http.StatusUnauthorized && hc == c.anonymous {
+		_ = resp.Body.Close()
+
+		token, err := c.anonymousToken(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("error getting token for %s: %w", image, err)
+		}
+
+		resp, err = c.getManifest(ctx, hc, url, token)
+		if err != nil {
+			return "", fmt.Errorf("error getting manifest for %s: %w", image, err)
+		}
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &ManifestError{Image: image, Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+
+	// Use the registry-provided digest if there is one; otherwise, hash the manifest ourselves.
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		h := sha256.New()
+		if _, err := io.Copy(h, resp.Body); err != nil {
+			return "", fmt.Errorf("error reading manifest for %s: %w", image, err)
+		}
+
+		digest = fmt.Sprintf("sha256:%s", hex.EncodeToString(h.Sum(nil)))
+	}
+
+	return strings.TrimPrefix(digest, "sha256:"), nil
+}
+
+func (c *client) getManifest(ctx context.Context, hc *http.Client, url, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", strings.Join(manifestTypes, ","))
+
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	return hc.Do(req)
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+type BadChallengeError struct {
+	Challenge string
+}
+
+func (e *BadChallengeError) Error() string {
+	return fmt.Sprintf("bad authentication challenge: %q", e.Challenge)
+}
+
+// anonymousToken returns an anonymous bearer token from the token service described in the given
+// WWW-Authenticate challenge. https://docs.docker.com/registry/spec/auth/token/
+func (c *client) anonymousToken(ctx context.Context, challenge string) (string, error) {
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	if !strings.HasPrefix(challenge, "Bearer ") || params["realm"] == "" {
+		return "", &BadChallengeError{Challenge: challenge}
+	}
+
+	q := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s?%s", params["realm"], q.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.anonymous.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &BadChallengeError{Challenge: challenge}
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"` //nolint:tagliatelle // defined by the spec
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"google.golang.org/api/option"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image, host, repository, reference string
+		google                             bool
+	}{
+		{
+			image: "nginx", host: "docker.io", repository: "nginx", reference: "latest",
+		},
+		{
+			image: "library/nginx:1.19", host: "docker.io", repository: "library/nginx", reference: "1.19",
+		},
+		{
+			image: "localhost:5000/nginx", host: "localhost:5000", repository: "nginx", reference: "latest",
+		},
+		{
+			image: "gcr.io/my-project/my-app", host: "gcr.io", repository: "my-project/my-app",
+			reference: "latest", google: true,
+		},
+		{
+			image: "eu.gcr.io/my-project/my-app:v1", host: "eu.gcr.io", repository: "my-project/my-app",
+			reference: "v1", google: true,
+		},
+		{
+			image: "us-docker.pkg.dev/my-project/repo/my-app:v1@sha256:abcdef", host: "us-docker.pkg.dev",
+			repository: "my-project/repo/my-app", reference: "sha256:abcdef", google: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		testCase := testCase
+		t.Run(testCase.image, func(t *testing.T) {
+			t.Parallel()
+
+			host, repository, reference := Parse(testCase.image)
+			assert.Equal(t, "Parse() host", testCase.host, host)
+			assert.Equal(t, "Parse() repository", testCase.repository, repository)
+			assert.Equal(t, "Parse() reference", testCase.reference, reference)
+			assert.Equal(t, "IsGoogle()", testCase.google, IsGoogle(host))
+		})
+	}
+}
+
+func TestClient_Digest(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	manifest := map[string]interface{}{
+		"schemaVersion": 2,
+	}
+
+	srv.Expect(`/v2/my-project/my-app/manifests/v1.2.3`,
+		httpmock.RespJSON(manifest))
+
+	c, err := NewClient(context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.Digest(context.Background(), "gcr.io/my-project/my-app:v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.Sum256([]byte(`{"schemaVersion":2}`))
+	assert.Equal(t, "Digest()", hex.EncodeToString(h[:]), got)
+}
+
+func TestClient_Digest_Anonymous(t *testing.T) {
+	t.Parallel()
+
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if got, want := r.URL.RawQuery, "scope=repository%3Alibrary%2Fnginx%3Apull&service=registry.docker.io"; got != want {
+				t.Errorf("query was %q, expected %q", got, want)
+			}
+
+			_, _ = w.Write([]byte(`{"token":"anonymous"}`))
+		case "/v2/library/nginx/manifests/1.19":
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/token",service="registry.docker.io",scope="repository:library/nginx:pull"`,
+					srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			w.Header().Set("Docker-Content-Digest", "sha256:abcdef")
+			_, _ = w.Write([]byte(`{"schemaVersion":2}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &client{google: nil, anonymous: srv.Client(), endpoint: srv.URL}
+
+	got, err := c.Digest(context.Background(), "nginx:1.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Digest()", "abcdef", got)
+}
+
+func TestClient_Exists(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/v2/my-project/my-app/manifests/sha256:abcdef`,
+		httpmock.RespJSON(map[string]interface{}{
+			"schemaVersion": 2,
+		}))
+	srv.Expect(`/v2/my-project/my-app/manifests/sha256:123456`,
+		httpmock.Status(http.StatusNotFound))
+
+	c, err := NewClient(context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := c.Exists(context.Background(), "gcr.io/my-project/my-app@sha256:abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Exists()", true, exists)
+
+	exists, err = c.Exists(context.Background(), "gcr.io/my-project/my-app@sha256:123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Exists()", false, exists)
+}
@@ -2,7 +2,8 @@ package resources
 
 import (
 	"fmt"
-	"math"
+	"sort"
+	"strings"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
@@ -12,7 +13,7 @@ import (
 
 //nolint:funlen // not worth splitting this up
 func (*builder) App(
-	project, app string, managedZone *dns.ManagedZone, config *cfg.Config,
+	project, app string, managedZone *dns.ManagedZone, config *cfg.AppConfig, ipLists map[string][]string,
 ) []deployments.Resource {
 	firewall := fmt.Sprintf("belvedere-allow-%s-lb", app)
 	healthcheck := fmt.Sprintf("%s-hc", app)
@@ -30,6 +31,11 @@ func (*builder) App(
 	dnsName := fmt.Sprintf("%s.%s", app, managedZone.DnsName)
 	securityPolicy := fmt.Sprintf("%s-waf", app)
 
+	drainingTimeout := int64(defaultDrainingTimeout)
+	if config.DrainingTimeout != nil {
+		drainingTimeout = int64(*config.DrainingTimeout)
+	}
+
 	resources := []deployments.Resource{
 		// A global, static IP address for the app.
 		{
@@ -80,7 +86,7 @@ func (*builder) App(
 			Properties: &compute.BackendService{
 				CdnPolicy: config.CDNPolicy,
 				ConnectionDraining: &compute.ConnectionDraining{
-					DrainingTimeoutSec: 60,
+					DrainingTimeoutSec: drainingTimeout,
 				},
 				EnableCDN: config.CDNPolicy != nil,
 				HealthChecks: []string{
@@ -169,31 +175,9 @@ func (*builder) App(
 		{
 			Name: securityPolicy,
 			Type: "compute.v1.securityPolicy",
-			Properties: &compute.SecurityPolicy{
+			Properties: &deployments.SecurityPolicy{
 				Description: fmt.Sprintf("WAF rules for Belvedere app %s.", app),
-				Rules: append(config.WAFRules,
-					&compute.SecurityPolicyRule{
-						Action:      "deny(404)",
-						Description: "Deny external access to healthchecks.",
-						Match: &compute.SecurityPolicyRuleMatcher{
-							Expr: &compute.Expr{
-								Expression: "request.path.matches('^/healthz')",
-							},
-						},
-						Priority: 1,
-					},
-					&compute.SecurityPolicyRule{
-						Action:      "allow",
-						Description: "Allow all access by default.",
-						Match: &compute.SecurityPolicyRuleMatcher{
-							Config: &compute.SecurityPolicyRuleMatcherConfig{
-								SrcIpRanges: []string{"*"},
-							},
-							VersionedExpr: "SRC_IPS_V1",
-						},
-						Priority: math.MaxInt32,
-					},
-				),
+				Rules:       securityPolicyRules(config, ipLists),
 			},
 		},
 		// A URL map which redirects HTTP requests to their HTTPS eqiuvalents.
@@ -239,9 +223,14 @@ func (*builder) App(
 	return resources
 }
 
+// defaultDrainingTimeout is the number of seconds the load balancer waits for in-flight requests to
+// complete before removing an instance, unless otherwise specified.
+const defaultDrainingTimeout = 60
+
 // requiresRoles is a list of IAM role which are added to application service accounts by default.
 //nolint:gochecknoglobals // can't have non-scalar consts
 var requiredRoles = []string{
+	"roles/artifactregistry.reader",
 	"roles/clouddebugger.agent",
 	"roles/cloudprofiler.agent",
 	"roles/cloudtrace.agent",
@@ -276,3 +265,187 @@ func sessionAffinityEnum(s string) string {
 		return "NONE"
 	}
 }
+
+// securityPolicyRules returns the built-in health check rule, the rules for the denied IPs, the app's WAF
+// rules, a throttle or rate_based_ban rule for each rate limit, the rules for the allowed IPs, and the
+// built-in default rule, in order of priority. The default rule allows all access unless the app has
+// allowed IPs, in which case it denies all access.
+func securityPolicyRules(config *cfg.AppConfig, ipLists map[string][]string) []*deployments.SecurityPolicyRule {
+	rules := make([]*deployments.SecurityPolicyRule, 0, len(config.WAFRules)+len(config.RateLimits)+2)
+
+	rules = append(rules, &deployments.SecurityPolicyRule{
+		SecurityPolicyRule: &compute.SecurityPolicyRule{
+			Action:      "deny(404)",
+			Description: "Deny external access to healthchecks.",
+			Match: &compute.SecurityPolicyRuleMatcher{
+				Expr: &compute.Expr{
+					Expression: "request.path.matches('^/healthz')",
+				},
+			},
+			Priority: cfg.HealthCheckRulePriority,
+		},
+	})
+
+	rules = append(rules, ipRules("deny(403)", "Deny", config.DenyIPs, ipLists, cfg.DenyIPRulePriority)...)
+
+	for _, rule := range config.WAFRules {
+		rules = append(rules, &deployments.SecurityPolicyRule{SecurityPolicyRule: rule})
+	}
+
+	// Requests under a rate limit are allowed, so the rate limits follow the WAF rules.
+	for i, rl := range sortedRateLimits(config.RateLimits) {
+		rules = append(rules, rateLimitRule(rl, cfg.RateLimitRulePriority+int64(i)))
+	}
+
+	rules = append(rules, ipRules("allow", "Allow", config.AllowIPs, ipLists, cfg.AllowIPRulePriority)...)
+
+	// If the app has allowed IPs, deny everyone else.
+	action, description := "allow", "Allow all access by default."
+	if config.AllowIPs != nil {
+		action, description = "deny(403)", "Deny all other access by default."
+	}
+
+	return append(rules, &deployments.SecurityPolicyRule{
+		SecurityPolicyRule: &compute.SecurityPolicyRule{
+			Action:      action,
+			Description: description,
+			Match:       allSources(),
+			Priority:    cfg.DefaultRulePriority,
+		},
+	})
+}
+
+// ipRules returns rules with the given action for the given IPs, with priorities in order from the
+// given one. The ranges and each IP list are split across as many rules as needed to keep each rule
+// within Cloud Armor's limit on ranges per rule.
+func ipRules(
+	action, verb string, ips *cfg.IPs, ipLists map[string][]string, priority int64,
+) []*deployments.SecurityPolicyRule {
+	if ips == nil {
+		return nil
+	}
+
+	var rules []*deployments.SecurityPolicyRule
+
+	add := func(description string, ranges []string) {
+		for i := 0; i < len(ranges); i += cfg.MaxRangesPerRule {
+			end := i + cfg.MaxRangesPerRule
+			if end > len(ranges) {
+				end = len(ranges)
+			}
+
+			d := description + "."
+			if len(ranges) > cfg.MaxRangesPerRule {
+				d = fmt.Sprintf("%s (%d of %d).", description, i/cfg.MaxRangesPerRule+1,
+					(len(ranges)+cfg.MaxRangesPerRule-1)/cfg.MaxRangesPerRule)
+			}
+
+			rules = append(rules, &deployments.SecurityPolicyRule{
+				SecurityPolicyRule: &compute.SecurityPolicyRule{
+					Action:      action,
+					Description: d,
+					Match: &compute.SecurityPolicyRuleMatcher{
+						Config: &compute.SecurityPolicyRuleMatcherConfig{
+							SrcIpRanges: ranges[i:end],
+						},
+						VersionedExpr: "SRC_IPS_V1",
+					},
+					Priority: priority + int64(len(rules)),
+				},
+			})
+		}
+	}
+
+	add(fmt.Sprintf("%s listed IPs", verb), ips.Ranges)
+
+	for _, name := range ips.Lists {
+		add(fmt.Sprintf("%s IPs in list %s", verb, name), ipLists[name])
+	}
+
+	return rules
+}
+
+// sortedRateLimits returns the given rate limits with those restricted to some paths first. A request
+// which conforms to a rate limit is allowed without being checked against the rest, so a rate limit
+// for all paths would otherwise keep any which follow it from applying.
+func sortedRateLimits(rateLimits []cfg.RateLimit) []*cfg.RateLimit {
+	sorted := make([]*cfg.RateLimit, len(rateLimits))
+	for i := range rateLimits {
+		sorted[i] = &rateLimits[i]
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Paths) > 0 && len(sorted[j].Paths) == 0
+	})
+
+	return sorted
+}
+
+// rateLimitRule returns a security policy rule with the given priority which enforces the given rate
+// limit.
+func rateLimitRule(rl *cfg.RateLimit, priority int64) *deployments.SecurityPolicyRule {
+	action, ban := "throttle", int64(0)
+	if rl.BanDurationSec > 0 {
+		action, ban = "rate_based_ban", rl.BanDurationSec
+	}
+
+	exceedAction := rl.ExceedAction
+	if exceedAction == "" {
+		exceedAction = "deny(429)"
+	}
+
+	key, keyName := "IP", ""
+	if rl.Key == cfg.RateLimitKeyHeader {
+		key, keyName = "HTTP_HEADER", rl.Header
+	}
+
+	// Match all requests unless the rate limit is restricted to some paths.
+	match := allSources()
+	if len(rl.Paths) > 0 {
+		exprs := make([]string, len(rl.Paths))
+		for i, p := range rl.Paths {
+			exprs[i] = fmt.Sprintf("request.path.matches(%s)", celString(p))
+		}
+
+		match = &compute.SecurityPolicyRuleMatcher{
+			Expr: &compute.Expr{
+				Expression: strings.Join(exprs, " || "),
+			},
+		}
+	}
+
+	return &deployments.SecurityPolicyRule{
+		SecurityPolicyRule: &compute.SecurityPolicyRule{
+			Action:      action,
+			Description: rl.Description,
+			Match:       match,
+			Priority:    priority,
+		},
+		RateLimitOptions: &deployments.RateLimitOptions{
+			ConformAction:    "allow",
+			ExceedAction:     exceedAction,
+			EnforceOnKey:     key,
+			EnforceOnKeyName: keyName,
+			RateLimitThreshold: &deployments.RateLimitThreshold{
+				Count:       rl.Requests,
+				IntervalSec: rl.IntervalSec,
+			},
+			BanDurationSec: ban,
+		},
+	}
+}
+
+// allSources returns a security policy rule matcher which matches all requests.
+func allSources() *compute.SecurityPolicyRuleMatcher {
+	return &compute.SecurityPolicyRuleMatcher{
+		Config: &compute.SecurityPolicyRuleMatcherConfig{
+			SrcIpRanges: []string{"*"},
+		},
+		VersionedExpr: "SRC_IPS_V1",
+	}
+}
+
+// celString returns the given string as a single-quoted CEL string literal.
+func celString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
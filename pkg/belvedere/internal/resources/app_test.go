@@ -2,6 +2,7 @@ package resources
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
@@ -17,8 +18,9 @@ func TestAppResources(t *testing.T) {
 		Name:    "belvedere",
 		DnsName: "horse.club",
 	}
+	drainingTimeout := 120
 	resources := NewBuilder().App("my-project", "my-app", zone,
-		&cfg.Config{
+		&cfg.AppConfig{
 			CDNPolicy: &compute.BackendServiceCdnPolicy{
 				SignedUrlCacheMaxAgeSec: 200,
 			},
@@ -30,6 +32,7 @@ func TestAppResources(t *testing.T) {
 			IAMRoles: []string{
 				"roles/dogWalker.dog",
 			},
+			DrainingTimeout: &drainingTimeout,
 			WAFRules: []*compute.SecurityPolicyRule{
 				{
 					Action:      "deny(403)",
@@ -39,9 +42,35 @@ func TestAppResources(t *testing.T) {
 							Expression: "evaluatePreconfiguredExpr('xss-stable')",
 						},
 					},
-					Priority: 1,
+					Priority: 1003,
 				},
 			},
+			RateLimits: []cfg.RateLimit{
+				{
+					Key:          cfg.RateLimitKeyHeader,
+					Header:       "X-API-Key",
+					Requests:     1000,
+					IntervalSec:  10,
+					ExceedAction: "deny(403)",
+				},
+				{
+					Description:    "Ban credential stuffing.",
+					Paths:          []string{"^/login$", `^/users/[^/]+/pass'word$`},
+					Requests:       10,
+					IntervalSec:    60,
+					BanDurationSec: 600,
+				},
+			},
+			DenyIPs: &cfg.IPs{
+				Ranges: []string{"192.0.2.1"},
+				Lists:  []string{"bad-actors"},
+			},
+		},
+		map[string][]string{
+			"bad-actors": {
+				"198.51.100.1", "198.51.100.2", "198.51.100.3", "198.51.100.4", "198.51.100.5", "198.51.100.6",
+				"198.51.100.7", "198.51.100.8", "198.51.100.9", "198.51.100.10", "198.51.100.11", "198.51.100.12",
+			},
 		},
 	)
 
@@ -54,3 +83,175 @@ func TestAppResources(t *testing.T) {
 
 	assert.EqualFixture(t, "App()", "app.json", got)
 }
+
+func TestAppResources_DrainingTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		drainingTimeout *int
+		want            int64
+	}{
+		{
+			name: "default",
+			want: 60,
+		},
+		{
+			name:            "zero",
+			drainingTimeout: new(int),
+			want:            0,
+		},
+	}
+	for _, testCase := range tests {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			resources := NewBuilder().App("my-project", "my-app", &dns.ManagedZone{DnsName: "horse.club"},
+				&cfg.AppConfig{DrainingTimeout: testCase.drainingTimeout}, nil)
+
+			for _, r := range resources {
+				if bs, ok := r.Properties.(*compute.BackendService); ok {
+					assert.Equal(t, "DrainingTimeoutSec", testCase.want, bs.ConnectionDraining.DrainingTimeoutSec)
+					return
+				}
+			}
+
+			t.Fatal("no backend service")
+		})
+	}
+}
+
+func TestSecurityPolicyRules_RateLimitsAfterWAFRules(t *testing.T) {
+	t.Parallel()
+
+	rules := securityPolicyRules(&cfg.AppConfig{
+		WAFRules: []*compute.SecurityPolicyRule{
+			{
+				Action:      "deny(403)",
+				Description: "Prevent XSS attacks.",
+				Match: &compute.SecurityPolicyRuleMatcher{
+					Expr: &compute.Expr{
+						Expression: "evaluatePreconfiguredExpr('xss-stable')",
+					},
+				},
+				Priority: 2000,
+			},
+		},
+		RateLimits: []cfg.RateLimit{
+			{
+				Description: "Limit everything.",
+				Requests:    1000,
+				IntervalSec: 60,
+			},
+		},
+	}, nil)
+
+	got := make([]string, len(rules))
+	for i, rule := range rules {
+		got[i] = fmt.Sprintf("%d %s %s", rule.Priority, rule.Action, rule.Description)
+	}
+
+	// Requests under the rate limit are allowed, so the WAF rule must be evaluated first to deny them.
+	want := []string{
+		"1 deny(404) Deny external access to healthchecks.",
+		"2000 deny(403) Prevent XSS attacks.",
+		"2147482547 throttle Limit everything.",
+		"2147483647 allow Allow all access by default.",
+	}
+
+	assert.Equal(t, "securityPolicyRules()", want, got)
+}
+
+func TestSecurityPolicyRules_OverlappingRateLimits(t *testing.T) {
+	t.Parallel()
+
+	rules := securityPolicyRules(&cfg.AppConfig{
+		RateLimits: []cfg.RateLimit{
+			{
+				Description: "Limit everything.",
+				Requests:    1000,
+				IntervalSec: 60,
+			},
+			{
+				Description: "Limit logins.",
+				Paths:       []string{"^/login$"},
+				Requests:    10,
+				IntervalSec: 60,
+			},
+		},
+	}, nil)
+
+	got := make([]string, len(rules))
+	for i, rule := range rules {
+		got[i] = fmt.Sprintf("%d %s %s", rule.Priority, rule.Action, rule.Description)
+	}
+
+	// The login rate limit is evaluated first.
+	want := []string{
+		"1 deny(404) Deny external access to healthchecks.",
+		"2147482547 throttle Limit logins.",
+		"2147482548 throttle Limit everything.",
+		"2147483647 allow Allow all access by default.",
+	}
+
+	assert.Equal(t, "securityPolicyRules()", want, got)
+}
+
+func TestSecurityPolicyRules_DenyIPs(t *testing.T) {
+	t.Parallel()
+
+	rules := securityPolicyRules(&cfg.AppConfig{
+		WAFRules: []*compute.SecurityPolicyRule{
+			{
+				Action:      "allow",
+				Description: "Allow webhooks.",
+				Priority:    1002,
+			},
+		},
+		DenyIPs: &cfg.IPs{
+			Lists: []string{"bad-actors"},
+		},
+	}, map[string][]string{"bad-actors": {"192.0.2.0/24"}})
+
+	got := make([]string, len(rules))
+	for i, rule := range rules {
+		got[i] = fmt.Sprintf("%d %s %s", rule.Priority, rule.Action, rule.Description)
+	}
+
+	// The denied IPs are evaluated before the app's allow rule.
+	want := []string{
+		"1 deny(404) Deny external access to healthchecks.",
+		"2 deny(403) Deny IPs in list bad-actors.",
+		"1002 allow Allow webhooks.",
+		"2147483647 allow Allow all access by default.",
+	}
+
+	assert.Equal(t, "securityPolicyRules()", want, got)
+}
+
+func TestSecurityPolicyRules_AllowIPs(t *testing.T) {
+	t.Parallel()
+
+	rules := securityPolicyRules(&cfg.AppConfig{
+		AllowIPs: &cfg.IPs{
+			Ranges: []string{"198.51.100.7"},
+			Lists:  []string{"office"},
+		},
+	}, map[string][]string{"office": {"203.0.113.0/24"}})
+
+	got := make([]string, len(rules))
+	for i, rule := range rules {
+		got[i] = fmt.Sprintf("%d %s %s", rule.Priority, rule.Action, rule.Description)
+	}
+
+	// Everyone other than the allowed IPs is denied.
+	want := []string{
+		"1 deny(404) Deny external access to healthchecks.",
+		"2147482647 allow Allow listed IPs.",
+		"2147482648 allow Allow IPs in list office.",
+		"2147483647 deny(403) Deny all other access by default.",
+	}
+
+	assert.Equal(t, "securityPolicyRules()", want, got)
+}
@@ -0,0 +1,482 @@
+package resources
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"google.golang.org/api/compute/v1"
+)
+
+// A DecodedRelease is the configuration of a release, as recovered from its resources.
+type DecodedRelease struct {
+	Config      *cfg.ReleaseConfig
+	ImageSHA256 string
+	// Images maps the names of the release's containers to their pinned image references.
+	Images map[string]string
+}
+
+type MalformedReleaseError struct {
+	Reason string
+}
+
+func (e *MalformedReleaseError) Error() string {
+	return fmt.Sprintf("malformed release: %s", e.Reason)
+}
+
+// DecodeRelease recovers the configuration of the given app's release from the release's resources.
+// This is the inverse of Builder.Release, with two caveats: only the parts of the configuration which
+// apply to releases are recovered, and a container's command is indistinguishable from the first of
+// its arguments.
+func DecodeRelease(app string, resources []deployments.Resource) (*DecodedRelease, error) {
+	d := &DecodedRelease{Config: &cfg.ReleaseConfig{}}
+
+	for i := range resources {
+		r := &resources[i]
+
+		var err error
+
+		switch r.Type {
+		case "compute.v1.instanceTemplate":
+			var it compute.InstanceTemplate
+			if err = r.DecodeProperties(&it); err == nil {
+				err = d.decodeInstanceTemplate(app, &it)
+			}
+		case "compute.v1.regionInstanceGroupManager":
+			var igm compute.InstanceGroupManager
+			if err = r.DecodeProperties(&igm); err == nil {
+				d.Config.NumReplicas = int(igm.TargetSize)
+			}
+		case "compute.v1.regionAutoscaler":
+			var as compute.Autoscaler
+			if err = r.DecodeProperties(&as); err == nil {
+				d.Config.AutoscalingPolicy = as.AutoscalingPolicy
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", r.Name, err)
+		}
+	}
+
+	if d.Images == nil {
+		return nil, &MalformedReleaseError{Reason: "no instance template"}
+	}
+
+	return d, nil
+}
+
+func (d *DecodedRelease) decodeInstanceTemplate(app string, it *compute.InstanceTemplate) error {
+	if it.Properties == nil {
+		return &MalformedReleaseError{Reason: "no instance properties"}
+	}
+
+	p := it.Properties
+	d.Config.MachineType = p.MachineType
+
+	if len(p.NetworkInterfaces) > 0 {
+		if n := p.NetworkInterfaces[0].Network; n != defaultNetwork {
+			d.Config.Network = n
+		}
+
+		d.Config.Subnetwork = p.NetworkInterfaces[0].Subnetwork
+	}
+
+	metadata := map[string]string{}
+
+	if p.Metadata != nil {
+		for _, item := range p.Metadata.Items {
+			if item.Value != nil {
+				metadata[item.Key] = *item.Value
+			}
+		}
+	}
+
+	d.Images = map[string]string{}
+	if s, ok := metadata[ImagesMetadataKey]; ok {
+		if err := json.Unmarshal([]byte(s), &d.Images); err != nil {
+			return fmt.Errorf("error parsing images: %w", err)
+		}
+	}
+
+	mounts, err := d.decodeCloudConfig(app, metadata[UserDataMetadataKey])
+	if err != nil {
+		return err
+	}
+
+	d.decodeDisks(p.Disks, mounts)
+
+	return nil
+}
+
+// decodeDisks recovers the boot image and disks from the instance template's disks, using the given
+// map of devices to mount points.
+func (d *DecodedRelease) decodeDisks(attached []*compute.AttachedDisk, mounts map[string]string) {
+	disks := &cfg.Disks{}
+	localSSDs := 0
+
+	for _, ad := range attached {
+		params := ad.InitializeParams
+		if params == nil {
+			params = &compute.AttachedDiskInitializeParams{}
+		}
+
+		switch {
+		case ad.Boot:
+			d.Config.BootImage = params.SourceImage
+			disks.BootSizeGB = params.DiskSizeGb
+			disks.BootType = params.DiskType
+		case ad.Type == "SCRATCH":
+			// Local SSDs aren't named, so their names are recovered from their mount points.
+			mnt := mounts[fmt.Sprintf("/dev/disk/by-id/google-local-ssd-%d", localSSDs)]
+			disks.Extra = append(disks.Extra, mountedDisk(fmt.Sprintf("local-ssd-%d", localSSDs),
+				cfg.DiskTypeLocalSSD, 0, mnt))
+			localSSDs++
+		default:
+			mnt := mounts[fmt.Sprintf("/dev/disk/by-id/google-%s", ad.DeviceName)]
+			disks.Extra = append(disks.Extra, mountedDisk(ad.DeviceName, params.DiskType,
+				params.DiskSizeGb, mnt))
+		}
+	}
+
+	if disks.BootSizeGB != 0 || disks.BootType != "" || len(disks.Extra) > 0 {
+		d.Config.Disks = disks
+	}
+
+	// Volumes refer to disks by name, not by mount point.
+	names := map[string]string{}
+	for _, disk := range disks.Extra {
+		disk := disk
+		names[disk.MountPoint()] = disk.Name
+	}
+
+	d.Config.Container.Volumes = volumeSources(d.Config.Container.Volumes, names)
+	for name, sidecar := range d.Config.Sidecars {
+		sidecar.Volumes = volumeSources(sidecar.Volumes, names)
+		d.Config.Sidecars[name] = sidecar
+	}
+}
+
+// mountedDisk returns a disk with the given properties. If the mount point is in the default
+// location, the disk's name is taken from it and its mount path is left unset.
+func mountedDisk(name, diskType string, sizeGB int64, mnt string) cfg.Disk {
+	disk := cfg.Disk{Name: name, Type: diskType, SizeGB: sizeGB}
+
+	switch {
+	case path.Dir(mnt) == "/mnt/disks" && (diskType == cfg.DiskTypeLocalSSD || path.Base(mnt) == name):
+		disk.Name = path.Base(mnt)
+	case mnt != "":
+		disk.MountPath = mnt
+	}
+
+	return disk
+}
+
+// volumeSources replaces the sources of any volumes which are disk mount points with the disks'
+// names.
+func volumeSources(volumes []cfg.Volume, names map[string]string) []cfg.Volume {
+	for i, v := range volumes {
+		if name, ok := names[v.Source]; ok {
+			volumes[i].Source = name
+		}
+	}
+
+	return volumes
+}
+
+// decodeCloudConfig recovers the containers and files from the given cloud-config manifest. It
+// returns a map of disk devices to their mount points.
+func (d *DecodedRelease) decodeCloudConfig(app, userData string) (map[string]string, error) {
+	var cc struct {
+		WriteFiles []struct {
+			Path        string `json:"path"`
+			Permissions string `json:"permissions"`
+			Owner       string `json:"owner"`
+			Encoding    string `json:"encoding"`
+			Content     string `json:"content"`
+		} `json:"write_files"`
+		RunCommands []string `json:"runcmd"`
+	}
+
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(userData, "#cloud-config\n\n")), &cc); err != nil {
+		return nil, fmt.Errorf("error parsing cloud-config: %w", err)
+	}
+
+	// Releases created before images were recorded have only their services to go on.
+	recorded := len(d.Images) > 0
+
+	for _, f := range cc.WriteFiles {
+		// Systemd services for containers are generated, not configured.
+		name := strings.TrimSuffix(strings.TrimPrefix(f.Path, "/etc/systemd/system/docker-"), ".service")
+		if image, ok := d.Images[name]; name != f.Path && (ok || !recorded) {
+			c, err := decodeService(f.Content, image)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding service for %s: %w", name, err)
+			}
+
+			d.addContainer(app, name, c)
+
+			continue
+		}
+
+		if err := d.addFile(f.Path, f.Permissions, f.Owner, f.Encoding, f.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return decodeMounts(cc.RunCommands)
+}
+
+func (d *DecodedRelease) addContainer(app, name string, c *cfg.Container) {
+	d.Images[name] = c.Image
+
+	if name == app {
+		// The app's image is pinned to the release's digest, which is kept separately.
+		if idx := strings.LastIndex(c.Image, "@sha256:"); idx >= 0 {
+			c.Image, d.ImageSHA256 = c.Image[:idx], c.Image[idx+len("@sha256:"):]
+		}
+
+		// The release's name is always passed to the app.
+		delete(c.Env, "RELEASE")
+
+		if len(c.Env) == 0 {
+			c.Env = nil
+		}
+
+		d.Config.Container = *c
+
+		return
+	}
+
+	if d.Config.Sidecars == nil {
+		d.Config.Sidecars = map[string]cfg.Container{}
+	}
+
+	d.Config.Sidecars[name] = *c
+}
+
+func (d *DecodedRelease) addFile(path, permissions, owner, encoding, content string) error {
+	f := cfg.File{Path: path, Content: content}
+
+	// Omit the defaults.
+	if permissions != "0644" {
+		f.Mode = permissions
+	}
+
+	if owner != "root" {
+		f.Owner = owner
+	}
+
+	if encoding == "b64" {
+		b, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return fmt.Errorf("error decoding %s: %w", path, err)
+		}
+
+		f.Content = string(b)
+	}
+
+	d.Config.Files = append(d.Config.Files, f)
+
+	return nil
+}
+
+// decodeMounts returns a map of disk devices to mount points from the given mount commands.
+func decodeMounts(cmds []string) (map[string]string, error) {
+	mounts := map[string]string{}
+
+	for _, cmd := range cmds {
+		if !strings.HasPrefix(cmd, "mount ") {
+			continue
+		}
+
+		// mount -o OPTIONS DEVICE MOUNTPOINT
+		words, err := splitWords(cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(words) != 5 {
+			return nil, &MalformedReleaseError{Reason: fmt.Sprintf("bad mount command: %q", cmd)}
+		}
+
+		mounts[words[3]] = words[4]
+	}
+
+	return mounts, nil
+}
+
+// decodeService recovers a container from the given systemd service file.
+func decodeService(service, image string) (*cfg.Container, error) {
+	c := &cfg.Container{}
+
+	for _, line := range strings.Split(service, "\n") {
+		if !strings.HasPrefix(line, "ExecStart=") && !strings.HasPrefix(line, "ExecStop=") {
+			continue
+		}
+
+		words, err := splitWords(line[strings.Index(line, "=")+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(words) > 3 && words[0] == "-/usr/bin/docker" && words[1] == "exec":
+			// ExecStop=-/usr/bin/docker exec NAME PRESTOP...
+			c.PreStop = words[3:]
+		case len(words) > 4 && words[1] == "stop" && words[2] == "--time":
+			// ExecStop=/usr/bin/docker stop --time N NAME
+			if c.StopTimeout, err = strconv.Atoi(words[3]); err != nil {
+				return nil, &MalformedReleaseError{Reason: fmt.Sprintf("bad stop timeout: %q", words[3])}
+			}
+		case len(words) > 2 && words[1] == "run":
+			// ExecStart=/usr/bin/docker run --rm ARGS...
+			if err := decodeDockerArgs(c, words[3:], image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.Image == "" {
+		return nil, &MalformedReleaseError{Reason: "no image"}
+	}
+
+	return c, nil
+}
+
+// decodeDockerArgs recovers a container from the arguments returned by dockerArgs.
+func decodeDockerArgs(c *cfg.Container, args []string, image string) error {
+	// Skip the generated options and collect the env vars and volumes.
+	for i := 0; i < len(args); i++ {
+		switch flag := args[i]; flag {
+		case "--oom-kill-disable":
+		case "--log-driver", "--log-opt", "--name", "--network", "--label", "--env", "--volume":
+			i++
+			if i == len(args) {
+				return &MalformedReleaseError{Reason: fmt.Sprintf("no value for %s", flag)}
+			}
+
+			switch flag {
+			case "--env":
+				decodeEnv(c, args[i])
+			case "--volume":
+				c.Volumes = append(c.Volumes, decodeVolume(args[i]))
+			}
+		default:
+			decodeRest(c, args[i:], image)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func decodeEnv(c *cfg.Container, s string) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return
+	}
+
+	if c.Env == nil {
+		c.Env = map[string]string{}
+	}
+
+	c.Env[parts[0]] = parts[1]
+}
+
+func decodeVolume(s string) cfg.Volume {
+	parts := strings.Split(s, ":")
+	v := cfg.Volume{Source: parts[0]}
+
+	if len(parts) > 1 {
+		v.Target = parts[1]
+	}
+
+	v.ReadOnly = len(parts) > 2 && parts[2] == "ro"
+
+	return v
+}
+
+// decodeRest recovers the Docker options, image, command, and arguments from the remaining arguments.
+// The image is found by its recorded reference, if any, or assumed to be the first non-option
+// argument.
+func decodeRest(c *cfg.Container, args []string, image string) {
+	idx := -1
+
+	for j, arg := range args {
+		if (image != "" && arg == image) || (image == "" && !strings.HasPrefix(arg, "-")) {
+			idx = j
+			break
+		}
+	}
+
+	if idx < 0 {
+		return
+	}
+
+	if idx > 0 {
+		c.DockerOptions = args[:idx]
+	}
+
+	c.Image = args[idx]
+
+	if rest := args[idx+1:]; len(rest) > 0 {
+		c.Command = rest[0]
+		if len(rest) > 1 {
+			c.Args = rest[1:]
+		}
+	}
+}
+
+// splitWords splits the given shell command into words, removing any quoting.
+func splitWords(s string) ([]string, error) {
+	var (
+		words           []string
+		word            strings.Builder
+		quote           rune
+		inWord, escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+
+			escaped = false
+		case quote == '\'' && r != '\'', quote == '"' && r != '"' && r != '\\':
+			word.WriteRune(r)
+		case quote != 0 && r == quote:
+			quote = 0
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == 0 && (r == '\'' || r == '"'):
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, &MalformedReleaseError{Reason: fmt.Sprintf("unterminated quote: %q", s)}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
@@ -0,0 +1,147 @@
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDecodeRelease(t *testing.T) {
+	t.Parallel()
+
+	config := &cfg.ReleaseConfig{
+		NumReplicas: 2,
+		MachineType: "n1-standard-1",
+		Network:     "network",
+		Subnetwork:  "subnetwork",
+		BootImage:   "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89",
+		Container: cfg.Container{
+			Image:   "gcr.io/example/helloworld",
+			Command: "/usr/bin/helloworld",
+			Args:    []string{"one", "two's"},
+			Env: map[string]string{
+				"ONE": "1 or 2",
+			},
+			DockerOptions: []string{"--turbo"},
+			StopTimeout:   60,
+			PreStop:       []string{"/usr/bin/helloworld", "drain"},
+			Volumes: []cfg.Volume{
+				{
+					Source: "data",
+					Target: "/var/data",
+				},
+				{
+					Source:   "/etc/ssl",
+					Target:   "/etc/ssl",
+					ReadOnly: true,
+				},
+			},
+		},
+		Sidecars: map[string]cfg.Container{
+			"nginx": {
+				Image: "docker.io/nginx@sha256:abcdef",
+				Volumes: []cfg.Volume{
+					{
+						Source: "scratch",
+						Target: "/var/cache",
+					},
+				},
+			},
+		},
+		Disks: &cfg.Disks{
+			BootSizeGB: 20,
+			BootType:   "pd-ssd",
+			Extra: []cfg.Disk{
+				{
+					Name:      "data",
+					Type:      "pd-balanced",
+					SizeGB:    100,
+					MountPath: "/data",
+				},
+				{
+					Name: "scratch",
+					Type: "local-ssd",
+				},
+			},
+		},
+		Files: []cfg.File{
+			{
+				Path:    "/etc/helloworld.conf",
+				Content: "hello = world\n",
+			},
+			{
+				Path:    "/etc/helloworld.bin",
+				Mode:    "0600",
+				Owner:   "nobody",
+				Content: "\xff\xfe",
+			},
+		},
+		AutoscalingPolicy: &compute.AutoscalingPolicy{
+			MinNumReplicas: 2,
+			MaxNumReplicas: 10,
+		},
+	}
+
+	// Round-trip the resources through JSON, as Deployment Manager does.
+	var res []deployments.Resource
+
+	for _, r := range NewBuilder().Release("my-project", "us-central1", "my-app", "v1", "123456", config) {
+		b, err := r.Properties.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res = append(res, deployments.Resource{
+			Name:       r.Name,
+			Type:       r.Type,
+			Properties: json.RawMessage(b),
+		})
+	}
+
+	got, err := DecodeRelease("my-app", res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &DecodedRelease{
+		Config:      config,
+		ImageSHA256: "123456",
+		Images: map[string]string{
+			"my-app": "gcr.io/example/helloworld@sha256:123456",
+			"nginx":  "docker.io/nginx@sha256:abcdef",
+		},
+	}
+
+	assert.Equal(t, "DecodeRelease()", want, got)
+}
+
+func TestDecodeRelease_NoInstanceTemplate(t *testing.T) {
+	t.Parallel()
+
+	_, err := DecodeRelease("my-app", nil)
+	assert.Equal(t, "DecodeRelease() error", &MalformedReleaseError{Reason: "no instance template"}, err)
+}
+
+func TestDecodeService_TrailingFlag(t *testing.T) {
+	t.Parallel()
+
+	_, err := decodeService("ExecStart=/usr/bin/docker run --rm --name my-app --env", "")
+	assert.Equal(t, "decodeService() error", &MalformedReleaseError{Reason: "no value for --env"}, err)
+}
+
+func TestSplitWords(t *testing.T) {
+	t.Parallel()
+
+	got, err := splitWords(`docker run 'one two' "three \"four\"" five\ six 'seven'"'"'s'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"docker", "run", "one two", `three "four"`, "five six", "seven's"}
+
+	assert.Equal(t, "splitWords()", want, got)
+}
@@ -1,18 +1,23 @@
 package resources
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alessio/shellescape"
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
 	"google.golang.org/api/compute/v1"
 )
 
-func (*builder) Release(project, region, app, release, imageSHA256 string, config *cfg.Config) []deployments.Resource {
+func (*builder) Release(
+	project, region, app, release, imageSHA256 string, config *cfg.ReleaseConfig,
+) []deployments.Resource {
 	instanceTemplate := fmt.Sprintf("%s-%s-it", app, release)
 	instanceGroupManager := fmt.Sprintf("%s-%s-ig", app, release)
 	autoscaler := fmt.Sprintf("%s-%s-as", app, release)
@@ -29,18 +34,8 @@ func (*builder) Release(project, region, app, release, imageSHA256 string, confi
 			Type: "compute.v1.instanceTemplate",
 			Properties: &compute.InstanceTemplate{
 				Properties: &compute.InstanceProperties{
-					// Use Google Container-Optimized OS with a default disk size.
-					Disks: []*compute.AttachedDisk{
-						{
-							AutoDelete: true,
-							Boot:       true,
-							DeviceName: "boot",
-							Type:       "PERSISTENT",
-							InitializeParams: &compute.AttachedDiskInitializeParams{
-								SourceImage: cosStable,
-							},
-						},
-					},
+					// Use the release's boot image plus any additional disks.
+					Disks: attachedDisks(config),
 					Labels: map[string]string{
 						"belvedere-app":     app,
 						"belvedere-release": release,
@@ -55,7 +50,9 @@ func (*builder) Release(project, region, app, release, imageSHA256 string, confi
 							// Enable the Stackdriver Logging Agent for the instance.
 							metaData("google-logging-enable", "true"),
 							// Inject the cloud-init metadata.
-							metaData("user-data", cloudConfig(config, app, release, imageSHA256)),
+							metaData(UserDataMetadataKey, cloudConfig(config, app, release, imageSHA256)),
+							// Record the pinned image of each container.
+							metaData(ImagesMetadataKey, imageRefs(config, app, imageSHA256)),
 						},
 					},
 					// Enable outbound internet access for the instances.
@@ -134,10 +131,31 @@ func (*builder) Release(project, region, app, release, imageSHA256 string, confi
 
 const (
 	defaultNetwork = "global/networks/default"
-	// https://cloud.google.com/container-optimized-os/docs/
-	cosStable = "https://www.googleapis.com/compute/v1/projects/gce-uefi-images/global/images/family/cos-stable"
+
+	// ImagesMetadataKey is the instance template metadata key for a JSON object mapping the names
+	// of a release's containers to their pinned image references.
+	ImagesMetadataKey = "belvedere-images"
+
+	// UserDataMetadataKey is the instance template metadata key for a release's cloud-config.
+	UserDataMetadataKey = "user-data"
 )
 
+// imageRefs returns a JSON object mapping the names of the app's container and its sidecars to
+// their image references.
+func imageRefs(c *cfg.ReleaseConfig, app, imageSHA256 string) string {
+	images := map[string]string{
+		app: fmt.Sprintf("%s@sha256:%s", c.Container.Image, imageSHA256),
+	}
+
+	for name, sidecar := range c.Sidecars {
+		images[name] = sidecar.Image
+	}
+
+	b, _ := json.Marshal(images)
+
+	return string(b)
+}
+
 // metaData returns a GCE metadata item with the given key and value.
 func metaData(key, value string) *compute.MetadataItems {
 	return &compute.MetadataItems{
@@ -146,15 +164,108 @@ func metaData(key, value string) *compute.MetadataItems {
 	}
 }
 
+// attachedDisks returns the boot disk (using Container-Optimized OS unless another boot image is
+// specified) and any additional persistent disks or local SSDs for the given release.
+func attachedDisks(c *cfg.ReleaseConfig) []*compute.AttachedDisk {
+	image := cfg.DefaultBootImage
+	if c.BootImage != "" {
+		image = c.BootImage
+	}
+
+	boot := &compute.AttachedDisk{
+		AutoDelete: true,
+		Boot:       true,
+		DeviceName: "boot",
+		Type:       "PERSISTENT",
+		InitializeParams: &compute.AttachedDiskInitializeParams{
+			SourceImage: image,
+		},
+	}
+
+	disks := []*compute.AttachedDisk{boot}
+
+	if c.Disks == nil {
+		return disks
+	}
+
+	boot.InitializeParams.DiskSizeGb = c.Disks.BootSizeGB
+	boot.InitializeParams.DiskType = c.Disks.BootType
+
+	for _, d := range c.Disks.Extra {
+		if d.Type == cfg.DiskTypeLocalSSD {
+			// Local SSDs are always 375GB and can't be named, so they're identified by index.
+			disks = append(disks, &compute.AttachedDisk{
+				AutoDelete: true,
+				Interface:  "SCSI",
+				Type:       "SCRATCH",
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskType: cfg.DiskTypeLocalSSD,
+				},
+			})
+		} else {
+			disks = append(disks, &compute.AttachedDisk{
+				AutoDelete: true,
+				DeviceName: d.Name,
+				Type:       "PERSISTENT",
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskSizeGb: d.SizeGB,
+					DiskType:   d.Type,
+				},
+			})
+		}
+	}
+
+	return disks
+}
+
+// mountCommands returns a list of commands which format (if necessary) and mount each additional
+// disk, plus a map of disk names to their mount points.
+func mountCommands(c *cfg.ReleaseConfig) ([]string, map[string]string) {
+	if c.Disks == nil {
+		return nil, nil
+	}
+
+	cmds := make([]string, 0, len(c.Disks.Extra)*3)
+	mounts := make(map[string]string, len(c.Disks.Extra))
+	localSSDs := 0
+
+	for i := range c.Disks.Extra {
+		d := &c.Disks.Extra[i]
+
+		device := fmt.Sprintf("/dev/disk/by-id/google-%s", d.Name)
+		if d.Type == cfg.DiskTypeLocalSSD {
+			device = fmt.Sprintf("/dev/disk/by-id/google-local-ssd-%d", localSSDs)
+			localSSDs++
+		}
+
+		dev, mnt := shellescape.Quote(device), shellescape.Quote(d.MountPoint())
+		cmds = append(cmds,
+			// Only format the disk if it doesn't already have a filesystem, since cloud-init runs on
+			// every boot.
+			fmt.Sprintf("blkid %s || mkfs.ext4 -F -m 0 -E lazy_itable_init=0,lazy_journal_init=0,discard %s",
+				dev, dev),
+			fmt.Sprintf("mkdir -p %s", mnt),
+			fmt.Sprintf("mount -o discard,defaults %s %s", dev, mnt),
+		)
+		mounts[d.Name] = d.MountPoint()
+	}
+
+	return cmds, mounts
+}
+
 // cloudConfig returns a cloud-config manifest for the given release.
-func cloudConfig(c *cfg.Config, app, release, imageSHA256 string) string {
+func cloudConfig(c *cfg.ReleaseConfig, app, release, imageSHA256 string) string {
 	type file struct {
 		Path        string `json:"path,omitempty"`
 		Permissions string `json:"permissions,omitempty"`
 		Owner       string `json:"owner,omitempty"`
+		Encoding    string `json:"encoding,omitempty"`
 		Content     string `json:"content,omitempty"`
 	}
 
+	mountCmds, mounts := mountCommands(c)
+	registries := googleRegistries(c)
+
 	cc := struct {
 		WriteFiles  []file   `json:"write_files,omitempty"`
 		RunCommands []string `json:"runcmd,omitempty"`
@@ -162,12 +273,12 @@ func cloudConfig(c *cfg.Config, app, release, imageSHA256 string) string {
 		WriteFiles: []file{
 			// Write a systemd service for running the app's container in Docker.
 			{
-				Content: systemdService(app,
+				Content: systemdService(app, &c.Container, registries,
 					dockerArgs(&c.Container, app, release, imageSHA256,
 						map[string]string{
 							"app":     app,
 							"release": release,
-						}),
+						}, mounts),
 				),
 				Owner:       "root",
 				Path:        fmt.Sprintf("/etc/systemd/system/docker-%s.service", app),
@@ -177,25 +288,38 @@ func cloudConfig(c *cfg.Config, app, release, imageSHA256 string) string {
 		RunCommands: []string{
 			// Enable service traffic through the host firewall.
 			"iptables -w -A INPUT -p tcp --dport 8443 -j ACCEPT",
-			// Load all new systemd services.
-			"systemctl daemon-reload",
-			// Start the app's systemd service.
-			fmt.Sprintf("systemctl start docker-%s.service", app),
 		},
 	}
 
-	for name, sidecar := range c.Sidecars {
-		sidecar := sidecar
+	// Format and mount any additional disks before starting any containers.
+	cc.RunCommands = append(cc.RunCommands, mountCmds...)
+	cc.RunCommands = append(cc.RunCommands,
+		// Load all new systemd services.
+		"systemctl daemon-reload",
+		// Start the app's systemd service.
+		fmt.Sprintf("systemctl start docker-%s.service", app),
+	)
+
+	// Add sidecars in a stable order.
+	sidecars := make([]string, 0, len(c.Sidecars))
+	for name := range c.Sidecars {
+		sidecars = append(sidecars, name)
+	}
+
+	sort.Strings(sidecars)
+
+	for _, name := range sidecars {
+		sidecar := c.Sidecars[name]
 		// Add a systemd service for running the sidecar in Docker.
 		cc.WriteFiles = append(cc.WriteFiles,
 			file{
-				Content: systemdService(name,
+				Content: systemdService(name, &sidecar, registries,
 					dockerArgs(&sidecar, name, "", "",
 						map[string]string{
 							"app":     app,
 							"release": release,
 							"sidecar": name,
-						}),
+						}, mounts),
 				),
 				Owner:       "root",
 				Path:        fmt.Sprintf("/etc/systemd/system/docker-%s.service", name),
@@ -205,35 +329,120 @@ func cloudConfig(c *cfg.Config, app, release, imageSHA256 string) string {
 		cc.RunCommands = append(cc.RunCommands, fmt.Sprintf("systemctl start docker-%s.service", name))
 	}
 
+	// Add any configured files, using base64 for any contents which aren't valid UTF-8.
+	for _, f := range c.Files {
+		wf := file{
+			Content:     f.Content,
+			Owner:       "root",
+			Path:        f.Path,
+			Permissions: "0644",
+		}
+
+		if f.Owner != "" {
+			wf.Owner = f.Owner
+		}
+
+		if f.Mode != "" {
+			wf.Permissions = f.Mode
+		}
+
+		if !utf8.ValidString(f.Content) {
+			wf.Encoding = "b64"
+			wf.Content = base64.StdEncoding.EncodeToString([]byte(f.Content))
+		}
+
+		cc.WriteFiles = append(cc.WriteFiles, wf)
+	}
+
 	b, _ := json.Marshal(cc)
 
 	return fmt.Sprintf("#cloud-config\n\n%s", b)
 }
 
 // systemdTemplate is a template for starting a container in Docker. It includes authenticating
-// Docker with GCR, which needs to be done here b/c the credentials are not preserved across
-// instance reboots.
+// Docker with GCR and Artifact Registry, which needs to be done here b/c the credentials are not
+// preserved across instance reboots. It's ordered after Docker so that it's stopped before Docker on
+// shutdown.
 const systemdTemplate = `[Unit]
 Description=Start the %s container
 Wants=gcr-online.target
-After=gcr-online.target
+After=gcr-online.target docker.service
 
 [Service]
 Environment="HOME=/var/lib/docker"
-ExecStartPre=/usr/bin/docker-credential-gcr configure-docker
+ExecStartPre=/usr/bin/docker-credential-gcr configure-docker%s
 ExecStart=/usr/bin/docker run --rm %s
-ExecStop=/usr/bin/docker stop %s
-ExecStopPost=/usr/bin/docker rm %s
+%sExecStopPost=/usr/bin/docker rm %s
 `
 
+// stopTimeoutMargin is the amount of time, in seconds, systemd allows for a container's pre-stop
+// hook and Docker's own cleanup on top of the container's stop timeout.
+const stopTimeoutMargin = 30
+
 // systemdService returns a systemd service file with the given Docker arguments. All Docker
 // arguments are escaped, if necessary.
-func systemdService(name string, srgs []string) string {
-	return fmt.Sprintf(systemdTemplate, name, shellescape.QuoteCommand(srgs), name, name)
+func systemdService(name string, c *cfg.Container, registries, args []string) string {
+	var registriesFlag string
+	if len(registries) > 0 {
+		registriesFlag = fmt.Sprintf(" --registries=%s", strings.Join(registries, ","))
+	}
+
+	return fmt.Sprintf(systemdTemplate, name, registriesFlag, shellescape.QuoteCommand(args),
+		stopCommands(name, c), name)
 }
 
-// dockerArgs returns a list of arguments to `docker run` for running the given container.
-func dockerArgs(c *cfg.Container, app, release, sha256 string, labels map[string]string) []string {
+// googleRegistries returns a sorted list of the unique Google registry hosts used by the app's
+// container and its sidecars.
+func googleRegistries(c *cfg.ReleaseConfig) []string {
+	images := []string{c.Container.Image}
+	for _, sidecar := range c.Sidecars {
+		images = append(images, sidecar.Image)
+	}
+
+	hosts := map[string]bool{}
+
+	for _, image := range images {
+		if host := registry.Host(image); registry.IsGoogle(host) {
+			hosts[host] = true
+		}
+	}
+
+	registries := make([]string, 0, len(hosts))
+	for host := range hosts {
+		registries = append(registries, host)
+	}
+
+	sort.Strings(registries)
+
+	return registries
+}
+
+// stopCommands returns the systemd directives for gracefully stopping the given container: running
+// its pre-stop hook, if any, then stopping it with its stop timeout, if any.
+func stopCommands(name string, c *cfg.Container) string {
+	var sb strings.Builder
+
+	if len(c.PreStop) > 0 {
+		// Prefix with - so a failing hook doesn't prevent the container from being stopped.
+		_, _ = fmt.Fprintf(&sb, "ExecStop=-/usr/bin/docker exec %s\n",
+			shellescape.QuoteCommand(append([]string{name}, c.PreStop...)))
+	}
+
+	if c.StopTimeout > 0 {
+		_, _ = fmt.Fprintf(&sb, "ExecStop=/usr/bin/docker stop --time %d %s\n", c.StopTimeout, name)
+		_, _ = fmt.Fprintf(&sb, "TimeoutStopSec=%d\n", c.StopTimeout+stopTimeoutMargin)
+	} else {
+		_, _ = fmt.Fprintf(&sb, "ExecStop=/usr/bin/docker stop %s\n", name)
+	}
+
+	return sb.String()
+}
+
+// dockerArgs returns a list of arguments to `docker run` for running the given container. Volumes
+// which refer to disks are bound to the disks' mount points.
+func dockerArgs(
+	c *cfg.Container, app, release, sha256 string, labels, mounts map[string]string,
+) []string {
 	labelNames := make([]string, 0, len(labels))
 	for k := range labels {
 		labelNames = append(labelNames, k)
@@ -274,6 +483,22 @@ func dockerArgs(c *cfg.Container, app, release, sha256 string, labels map[string
 		}...)
 	}
 
+	for _, v := range c.Volumes {
+		source := v.Source
+		if mnt, ok := mounts[v.Source]; ok {
+			source = mnt
+		}
+
+		volume := fmt.Sprintf("%s:%s", source, v.Target)
+		if v.ReadOnly {
+			volume += ":ro"
+		}
+
+		args = append(args, []string{
+			"--volume", volume,
+		}...)
+	}
+
 	args = append(args, c.DockerOptions...)
 	url := c.Image
 
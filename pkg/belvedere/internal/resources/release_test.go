@@ -14,11 +14,26 @@ func TestReleaseResources(t *testing.T) {
 
 	resources := NewBuilder().Release(
 		"my-project", "us-central1", "my-app", "v43", "echo woo",
-		&cfg.Config{
+		&cfg.ReleaseConfig{
 			Network:     "network",
 			Subnetwork:  "subnetwork",
 			MachineType: "n1-standard-1",
 			NumReplicas: 20,
+			Disks: &cfg.Disks{
+				BootSizeGB: 20,
+				BootType:   "pd-ssd",
+				Extra: []cfg.Disk{
+					{
+						Name:   "data",
+						Type:   "pd-balanced",
+						SizeGB: 100,
+					},
+					{
+						Name: "scratch",
+						Type: "local-ssd",
+					},
+				},
+			},
 			AutoscalingPolicy: &compute.AutoscalingPolicy{
 				MinNumReplicas: 10,
 				MaxNumReplicas: 100,
@@ -72,7 +87,7 @@ func TestDockerArgs(t *testing.T) {
 		map[string]string{
 			"env":      "qa",
 			"alphabet": "latin",
-		})
+		}, nil)
 
 	assert.Equal(t, "dockerArgs()", want, got)
 }
@@ -109,6 +124,42 @@ func TestDockerArgsEmptyCommand(t *testing.T) {
 		map[string]string{
 			"env":      "qa",
 			"alphabet": "latin",
+		}, nil)
+
+	assert.Equal(t, "dockerArgs()", want, got)
+}
+
+func TestDockerArgsVolumes(t *testing.T) {
+	t.Parallel()
+
+	container := &cfg.Container{
+		Image: "gcr.io/example/example",
+		Volumes: []cfg.Volume{
+			{
+				Source: "scratch",
+				Target: "/var/cache",
+			},
+			{
+				Source:   "/etc/ssl",
+				Target:   "/etc/ssl",
+				ReadOnly: true,
+			},
+		},
+	}
+
+	want := []string{
+		"--log-driver", "gcplogs",
+		"--log-opt", "labels=",
+		"--name", "my-example",
+		"--network", "host",
+		"--oom-kill-disable",
+		"--volume", "/mnt/disks/scratch:/var/cache",
+		"--volume", "/etc/ssl:/etc/ssl:ro",
+		"gcr.io/example/example",
+	}
+	got := dockerArgs(container, "my-example", "", "", nil,
+		map[string]string{
+			"scratch": "/mnt/disks/scratch",
 		})
 
 	assert.Equal(t, "dockerArgs()", want, got)
@@ -117,7 +168,7 @@ func TestDockerArgsEmptyCommand(t *testing.T) {
 func TestCloudConfig(t *testing.T) {
 	t.Parallel()
 
-	config := &cfg.Config{
+	config := &cfg.ReleaseConfig{
 		Container: cfg.Container{
 			Image:   "gcr.io/example/helloworld",
 			Command: "/usr/bin/helloworld",
@@ -127,10 +178,18 @@ func TestCloudConfig(t *testing.T) {
 				"TWO": "2 or 3",
 			},
 			DockerOptions: []string{"--turbo"},
+			StopTimeout:   60,
+			PreStop:       []string{"/usr/bin/helloworld", "drain"},
+			Volumes: []cfg.Volume{
+				{
+					Source: "scratch",
+					Target: "/var/cache",
+				},
+			},
 		},
 		Sidecars: map[string]cfg.Container{
 			"nginx": {
-				Image: "gcr.io/example/nginx",
+				Image: "us-docker.pkg.dev/example/frontends/nginx",
 				Args:  []string{"three", "four"},
 				Env: map[string]string{
 					"THREE": "3 or 4",
@@ -141,6 +200,27 @@ func TestCloudConfig(t *testing.T) {
 		},
 		NumReplicas: 20,
 		MachineType: "n1-standard-1",
+		Disks: &cfg.Disks{
+			Extra: []cfg.Disk{
+				{
+					Name:      "scratch",
+					Type:      "local-ssd",
+					MountPath: "/mnt/disks/my scratch",
+				},
+			},
+		},
+		Files: []cfg.File{
+			{
+				Path:    "/etc/nginx/nginx.conf",
+				Content: "worker_processes 1;\n",
+			},
+			{
+				Path:    "/var/lib/belvedere/key.bin",
+				Mode:    "0600",
+				Owner:   "nobody",
+				Content: "\xff\xfe",
+			},
+		},
 		AutoscalingPolicy: &compute.AutoscalingPolicy{
 			MinNumReplicas: 10,
 			MaxNumReplicas: 100,
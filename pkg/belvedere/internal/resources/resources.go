@@ -16,11 +16,14 @@ type Builder interface {
 	// Base returns a list of resources for the base deployment.
 	Base(dnsZone string) []deployments.Resource
 
-	// App returns a list of resources for an app deployment.
-	App(project, app string, managedZone *dns.ManagedZone, config *cfg.Config) []deployments.Resource
+	// App returns a list of resources for an app deployment. The ranges of the project IP lists which
+	// the config refers to are given by name.
+	App(
+		project, app string, managedZone *dns.ManagedZone, config *cfg.AppConfig, ipLists map[string][]string,
+	) []deployments.Resource
 
 	// Release returns a list of resources for a release deployment.
-	Release(project, region, app, release, imageSHA256 string, config *cfg.Config) []deployments.Resource
+	Release(project, region, app, release, imageSHA256 string, config *cfg.ReleaseConfig) []deployments.Resource
 }
 
 func NewBuilder() Builder {
@@ -0,0 +1,259 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// blockNames are the Terraform block names of lists of objects, which are singular.
+//nolint:gochecknoglobals // can't have non-scalar consts
+var blockNames = map[string]string{
+	"accessConfigs":     "access_config",
+	"backends":          "backend",
+	"disks":             "disk",
+	"hostRules":         "host_rule",
+	"namedPorts":        "named_port",
+	"networkInterfaces": "network_interface",
+	"pathMatchers":      "path_matcher",
+	"pathRules":         "path_rule",
+	"rules":             "rule",
+	"serviceAccounts":   "service_account",
+}
+
+// mapAttributes are the fields whose objects are Terraform maps rather than blocks.
+//nolint:gochecknoglobals // can't have non-scalar consts
+var mapAttributes = map[string]bool{
+	"labels":   true,
+	"metadata": true,
+}
+
+//nolint:gochecknoglobals // can't have const regexps
+var refRegexp = regexp.MustCompile(`\$\(ref\.([^.)]+)\.([^)]+)\)`)
+
+// writeBody writes the attributes and then the nested blocks of the given object at the given
+// indentation level. Attributes are aligned like `terraform fmt` does.
+func writeBody(sb *strings.Builder, props map[string]interface{}, addrs map[string]string, level int) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	indent := strings.Repeat("  ", level)
+
+	var attrs, maps, blocks []string
+
+	width := 0
+
+	for _, k := range keys {
+		v := props[k]
+		list, _ := v.([]interface{})
+		_, isMap := v.(map[string]interface{})
+
+		switch {
+		case v == nil:
+			continue
+		case isMap && mapAttributes[k]:
+			maps = append(maps, k)
+		case isMap || hasObjects(list):
+			blocks = append(blocks, k)
+		default:
+			attrs = append(attrs, k)
+			if n := len(snake(k)); n > width {
+				width = n
+			}
+		}
+	}
+
+	for _, k := range attrs {
+		_, _ = fmt.Fprintf(sb, "%s%-*s = %s\n", indent, width, snake(k), value(props[k], addrs))
+	}
+
+	for _, k := range maps {
+		writeMap(sb, snake(k), props[k].(map[string]interface{}), addrs, indent)
+	}
+
+	for _, k := range blocks {
+		writeBlocks(sb, k, props[k], addrs, level)
+	}
+}
+
+// writeMap writes a map attribute.
+func writeMap(sb *strings.Builder, name string, m map[string]interface{}, addrs map[string]string, indent string) {
+	keys := make([]string, 0, len(m))
+	width := 0
+
+	for k := range m {
+		keys = append(keys, k)
+		if n := len(quote(k)); n > width {
+			width = n
+		}
+	}
+
+	sort.Strings(keys)
+
+	_, _ = fmt.Fprintf(sb, "%s%s = {\n", indent, name)
+
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(sb, "%s  %-*s = %s\n", indent, width, quote(k), value(m[k], addrs))
+	}
+
+	_, _ = fmt.Fprintf(sb, "%s}\n", indent)
+}
+
+// writeBlocks writes an object as a nested block, or a list of objects as repeated nested blocks.
+func writeBlocks(sb *strings.Builder, key string, v interface{}, addrs map[string]string, level int) {
+	name, ok := blockNames[key]
+	if !ok {
+		name = snake(key)
+	}
+
+	objects, ok := v.([]interface{})
+	if !ok {
+		objects = []interface{}{v}
+	}
+
+	indent := strings.Repeat("  ", level)
+
+	for _, e := range objects {
+		obj, _ := e.(map[string]interface{})
+		if len(obj) == 0 {
+			_, _ = fmt.Fprintf(sb, "%s%s {}\n", indent, name)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(sb, "%s%s {\n", indent, name)
+		writeBody(sb, obj, addrs, level+1)
+		_, _ = fmt.Fprintf(sb, "%s}\n", indent)
+	}
+}
+
+// value returns the HCL representation of the given scalar or list of scalars.
+func value(v interface{}, addrs map[string]string) string {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v, addrs)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = value(e, addrs)
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(values, ", "))
+	default:
+		return "null"
+	}
+}
+
+// stringValue returns the HCL representation of the given string, with references to other
+// resources translated into Terraform references. A string which consists entirely of a reference
+// becomes a bare reference; otherwise, references are interpolated.
+func stringValue(s string, addrs map[string]string) string {
+	if m := refRegexp.FindStringSubmatch(s); m != nil && m[0] == s {
+		if ref, ok := reference(m[1], m[2], addrs); ok {
+			return ref
+		}
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(`"`)
+
+	last := 0
+
+	for _, loc := range refRegexp.FindAllStringSubmatchIndex(s, -1) {
+		ref, ok := reference(s[loc[2]:loc[3]], s[loc[4]:loc[5]], addrs)
+		if !ok {
+			continue
+		}
+
+		sb.WriteString(escape(s[last:loc[0]]))
+		_, _ = fmt.Fprintf(&sb, "${%s}", ref)
+		last = loc[1]
+	}
+
+	sb.WriteString(escape(s[last:]))
+	sb.WriteString(`"`)
+
+	return sb.String()
+}
+
+// reference returns the Terraform reference for the given resource's property.
+func reference(name, property string, addrs map[string]string) (string, bool) {
+	addr, ok := addrs[name]
+	if !ok {
+		return "", false
+	}
+
+	parts := strings.Split(property, ".")
+	for i, p := range parts {
+		parts[i] = snake(p)
+	}
+
+	return fmt.Sprintf("%s.%s", addr, strings.Join(parts, ".")), true
+}
+
+// quote returns the given string as a quoted HCL string.
+func quote(s string) string {
+	return fmt.Sprintf(`"%s"`, escape(s))
+}
+
+//nolint:gochecknoglobals // can't have non-scalar consts
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"${", "$${",
+	"%{", "%%{",
+)
+
+// escape escapes the given string for inclusion in a quoted HCL string, including template
+// sequences.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// snake converts the given camelCase API field name into a snake_case Terraform name. Runs of
+// capitals are treated as a single word (e.g. IPAddress becomes ip_address).
+func snake(s string) string {
+	runes := []rune(s)
+
+	var sb strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				sb.WriteRune('_')
+			}
+		}
+
+		sb.WriteRune(unicode.ToLower(r))
+	}
+
+	return sb.String()
+}
+
+// hasObjects returns true if the list contains any objects.
+func hasObjects(list []interface{}) bool {
+	for _, e := range list {
+		if _, ok := e.(map[string]interface{}); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,345 @@
+package terraform
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A kind describes how a Deployment Manager resource type is translated into Terraform resources.
+type kind struct {
+	tfType string
+	// named is true if the resource is named after its Deployment Manager resource unless
+	// otherwise specified.
+	named bool
+	// convert returns the Terraform resources for the given resource name and properties.
+	convert func(name string, props map[string]interface{}) ([]tfResource, error)
+}
+
+//nolint:gochecknoglobals // can't have non-scalar consts
+var kinds = map[string]kind{
+	"compute.v1.globalAddress": {
+		tfType: "google_compute_global_address", named: true,
+		convert: single(global("addresses"), nil),
+	},
+	"compute.v1.firewall": {
+		tfType: "google_compute_firewall", named: true,
+		convert: single(global("firewalls"), firewall),
+	},
+	"compute.v1.healthCheck": {
+		tfType: "google_compute_health_check", named: true,
+		convert: single(global("healthChecks"), without("type")),
+	},
+	"compute.v1.backendService": {
+		tfType: "google_compute_backend_service", named: true,
+		convert: single(global("backendServices"), backendService),
+	},
+	"compute.v1.urlMap": {
+		tfType: "google_compute_url_map", named: true,
+		convert: single(global("urlMaps"), urlMap),
+	},
+	"compute.v1.sslCertificate": {
+		tfType: "google_compute_managed_ssl_certificate", named: true,
+		convert: single(global("sslCertificates"), without("type")),
+	},
+	"compute.v1.targetHttpsProxy": {
+		tfType: "google_compute_target_https_proxy", named: true,
+		convert: single(global("targetHttpsProxies"), nil),
+	},
+	"compute.v1.targetHttpProxy": {
+		tfType: "google_compute_target_http_proxy", named: true,
+		convert: single(global("targetHttpProxies"), nil),
+	},
+	"compute.v1.globalForwardingRule": {
+		tfType: "google_compute_global_forwarding_rule", named: true,
+		convert: single(global("forwardingRules"), nil),
+	},
+	"compute.v1.securityPolicy": {
+		tfType: "google_compute_security_policy", named: true,
+		convert: single(global("securityPolicies"), nil),
+	},
+	"compute.v1.instanceTemplate": {
+		tfType: "google_compute_instance_template", named: true,
+		convert: single(global("instanceTemplates"), instanceTemplate),
+	},
+	"compute.v1.regionInstanceGroupManager": {
+		tfType: "google_compute_region_instance_group_manager", named: true,
+		convert: single(regional("instanceGroupManagers"), instanceGroupManager),
+	},
+	"compute.v1.regionAutoscaler": {
+		tfType: "google_compute_region_autoscaler", named: true,
+		convert: single(regional("autoscalers"), autoscaler),
+	},
+	"dns.v1.managedZone": {
+		tfType: "google_dns_managed_zone",
+		convert: single(func(project string, p map[string]interface{}) string {
+			return fmt.Sprintf("projects/%s/managedZones/%s", project, str(p["name"]))
+		}, nil),
+	},
+	"iam.v1.serviceAccount": {
+		tfType: "google_service_account",
+		convert: single(func(project string, p map[string]interface{}) string {
+			return fmt.Sprintf("projects/%s/serviceAccounts/%s@%s.iam.gserviceaccount.com",
+				project, str(p["accountId"]), project)
+		}, nil),
+	},
+	"gcp-types/dns-v1:resourceRecordSets": {
+		tfType:  "google_dns_record_set",
+		convert: recordSets,
+	},
+	"gcp-types/cloudresourcemanager-v1:virtual.projects.iamMemberBinding": {
+		tfType: "google_project_iam_member",
+		convert: single(func(_ string, p map[string]interface{}) string {
+			return fmt.Sprintf("%s %s %s", str(p["resource"]), str(p["role"]), str(p["member"]))
+		}, iamMemberBinding),
+	},
+}
+
+// single returns a conversion into a single Terraform resource with the given import ID function,
+// after applying the given type-specific changes, if any.
+func single(
+	id func(project string, resolved map[string]interface{}) string, fix func(props map[string]interface{}) error,
+) func(string, map[string]interface{}) ([]tfResource, error) {
+	return func(name string, props map[string]interface{}) ([]tfResource, error) {
+		if fix != nil {
+			if err := fix(props); err != nil {
+				return nil, err
+			}
+		}
+
+		return []tfResource{{label: label(name), props: props, id: id}}, nil
+	}
+}
+
+// global returns an import ID function for resources in the given global collection.
+func global(collection string) func(string, map[string]interface{}) string {
+	return func(project string, p map[string]interface{}) string {
+		return fmt.Sprintf("projects/%s/global/%s/%s", project, collection, str(p["name"]))
+	}
+}
+
+// regional returns an import ID function for resources in the given regional collection.
+func regional(collection string) func(string, map[string]interface{}) string {
+	return func(project string, p map[string]interface{}) string {
+		return fmt.Sprintf("projects/%s/regions/%s/%s/%s",
+			project, path.Base(str(p["region"])), collection, str(p["name"]))
+	}
+}
+
+// without returns a fix which removes the given fields, which have no Terraform equivalent.
+func without(fields ...string) func(map[string]interface{}) error {
+	return func(props map[string]interface{}) error {
+		for _, f := range fields {
+			delete(props, f)
+		}
+
+		return nil
+	}
+}
+
+// firewall converts allowed rules into allow blocks. Terraform requires a network, so the default
+// network is used if none is given, like the API.
+func firewall(props map[string]interface{}) error {
+	if allowed, ok := props["allowed"].([]interface{}); ok {
+		for _, a := range allowed {
+			if rule, ok := a.(map[string]interface{}); ok {
+				rename(rule, "IPProtocol", "protocol")
+			}
+		}
+
+		rename(props, "allowed", "allow")
+	}
+
+	if props["network"] == nil {
+		props["network"] = "global/networks/default"
+	}
+
+	return nil
+}
+
+// backendService flattens connection draining and drops IAP's enabled flag, which Terraform infers
+// from the presence of the IAP block.
+func backendService(props map[string]interface{}) error {
+	if cd, ok := props["connectionDraining"].(map[string]interface{}); ok {
+		props["connectionDrainingTimeoutSec"] = cd["drainingTimeoutSec"]
+		delete(props, "connectionDraining")
+	}
+
+	if iap, ok := props["iap"].(map[string]interface{}); ok {
+		delete(iap, "enabled")
+	}
+
+	return nil
+}
+
+// urlMap adds the redirect's strip query flag, which Terraform requires.
+func urlMap(props map[string]interface{}) error {
+	if redirect, ok := props["defaultUrlRedirect"].(map[string]interface{}); ok && redirect["stripQuery"] == nil {
+		redirect["stripQuery"] = false
+	}
+
+	return nil
+}
+
+// instanceTemplate flattens the template's instance properties, metadata, tags, and disk
+// initialization parameters.
+func instanceTemplate(props map[string]interface{}) error {
+	if ip, ok := props["properties"].(map[string]interface{}); ok {
+		delete(props, "properties")
+
+		for k, v := range ip {
+			props[k] = v
+		}
+	}
+
+	if md, ok := props["metadata"].(map[string]interface{}); ok {
+		items, _ := md["items"].([]interface{})
+		metadata := make(map[string]interface{}, len(items))
+
+		for _, e := range items {
+			if item, ok := e.(map[string]interface{}); ok {
+				metadata[str(item["key"])] = item["value"]
+			}
+		}
+
+		props["metadata"] = metadata
+	}
+
+	if tags, ok := props["tags"].(map[string]interface{}); ok {
+		props["tags"] = tags["items"]
+	}
+
+	disks, _ := props["disks"].([]interface{})
+	for _, e := range disks {
+		if disk, ok := e.(map[string]interface{}); ok {
+			flatten(disk, "initializeParams")
+		}
+	}
+
+	// Access configs are identified by their properties, and their names and types are implied.
+	nics, _ := props["networkInterfaces"].([]interface{})
+	for _, e := range nics {
+		if nic, ok := e.(map[string]interface{}); ok {
+			acs, _ := nic["accessConfigs"].([]interface{})
+			for i, e := range acs {
+				ac, ok := e.(map[string]interface{})
+				if !ok {
+					return &MalformedPropertyError{Property: fmt.Sprintf("accessConfigs[%d]", i), Value: e}
+				}
+
+				delete(ac, "name")
+				delete(ac, "type")
+			}
+		}
+	}
+
+	return nil
+}
+
+// instanceGroupManager moves the instance template into a version block.
+func instanceGroupManager(props map[string]interface{}) error {
+	if it, ok := props["instanceTemplate"]; ok {
+		props["version"] = map[string]interface{}{"instanceTemplate": it}
+		delete(props, "instanceTemplate")
+	}
+
+	return nil
+}
+
+// autoscaler renames the autoscaling policy's fields to their Terraform equivalents.
+func autoscaler(props map[string]interface{}) error {
+	policy, ok := props["autoscalingPolicy"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	rename(policy, "minNumReplicas", "minReplicas")
+	rename(policy, "maxNumReplicas", "maxReplicas")
+	rename(policy, "coolDownPeriodSec", "cooldownPeriod")
+
+	for _, k := range []string{"cpuUtilization", "loadBalancingUtilization"} {
+		if u, ok := policy[k].(map[string]interface{}); ok {
+			rename(u, "utilizationTarget", "target")
+		}
+	}
+
+	if metrics, ok := policy["customMetricUtilizations"].([]interface{}); ok {
+		for _, e := range metrics {
+			if m, ok := e.(map[string]interface{}); ok {
+				rename(m, "metric", "name")
+				rename(m, "utilizationTarget", "target")
+				rename(m, "utilizationTargetType", "type")
+			}
+		}
+
+		rename(policy, "customMetricUtilizations", "metric")
+	}
+
+	return nil
+}
+
+// iamMemberBinding renames the binding's resource to its project.
+func iamMemberBinding(props map[string]interface{}) error {
+	rename(props, "resource", "project")
+
+	return nil
+}
+
+// recordSets converts each record in a set of resource records into a Terraform record set.
+func recordSets(name string, props map[string]interface{}) ([]tfResource, error) {
+	records, _ := props["records"].([]interface{})
+	resources := make([]tfResource, 0, len(records))
+
+	for _, e := range records {
+		record, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		record["name"] = props["name"]
+		record["managedZone"] = props["managedZone"]
+
+		// Record sets with more than one type get a label per type.
+		l := label(name)
+		if len(records) > 1 {
+			l = label(fmt.Sprintf("%s-%s", name, strings.ToLower(str(record["type"]))))
+		}
+
+		t := str(record["type"])
+		resources = append(resources, tfResource{
+			label: l,
+			props: record,
+			id: func(project string, p map[string]interface{}) string {
+				return fmt.Sprintf("projects/%s/managedZones/%s/rrsets/%s/%s",
+					project, str(p["managedZone"]), str(p["name"]), t)
+			},
+		})
+	}
+
+	return resources, nil
+}
+
+// rename renames the given field, if present.
+func rename(props map[string]interface{}, from, to string) {
+	if v, ok := props[from]; ok {
+		props[to] = v
+		delete(props, from)
+	}
+}
+
+// flatten moves the fields of the given nested object into its parent.
+func flatten(props map[string]interface{}, field string) {
+	if nested, ok := props[field].(map[string]interface{}); ok {
+		delete(props, field)
+
+		for k, v := range nested {
+			props[k] = v
+		}
+	}
+}
+
+// str returns the value as a string, or an empty string if it isn't one.
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
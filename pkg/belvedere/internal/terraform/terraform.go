@@ -0,0 +1,115 @@
+// Package terraform renders Deployment Manager resources as Terraform configuration for the google
+// provider.
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+)
+
+type UnsupportedTypeError struct {
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported resource type: %s", e.Type)
+}
+
+type MalformedPropertyError struct {
+	Property string
+	Value    interface{}
+}
+
+func (e *MalformedPropertyError) Error() string {
+	return fmt.Sprintf("malformed %s: %v", e.Property, e.Value)
+}
+
+// Render returns Terraform configuration equivalent to the given resources in the given project.
+// References between resources are translated into Terraform references. Resources which already
+// exist are passed in with their resolved properties, keyed by name, and an import block is emitted
+// for each of them.
+func Render(
+	project string, resources []deployments.Resource, existing map[string]map[string]interface{},
+) (string, error) {
+	// Map each resource name to its Terraform address, so references can be translated.
+	addrs := make(map[string]string, len(resources))
+
+	for i := range resources {
+		k, ok := kinds[resources[i].Type]
+		if !ok {
+			return "", &UnsupportedTypeError{Type: resources[i].Type}
+		}
+
+		addrs[resources[i].Name] = fmt.Sprintf("%s.%s", k.tfType, label(resources[i].Name))
+	}
+
+	var sb strings.Builder
+
+	for i := range resources {
+		r := &resources[i]
+		k := kinds[r.Type]
+
+		var props map[string]interface{}
+		if err := r.DecodeProperties(&props); err != nil {
+			return "", fmt.Errorf("error decoding %s: %w", r.Name, err)
+		}
+
+		if k.named && props["name"] == nil {
+			props["name"] = r.Name
+		}
+
+		converted, err := k.convert(r.Name, props)
+		if err != nil {
+			return "", fmt.Errorf("error converting %s: %w", r.Name, err)
+		}
+
+		for _, b := range converted {
+			if b.props["project"] == nil {
+				b.props["project"] = project
+			}
+
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+
+			_, _ = fmt.Fprintf(&sb, "resource %q %q {\n", k.tfType, b.label)
+			writeBody(&sb, b.props, addrs, 1)
+			sb.WriteString("}\n")
+
+			if resolved, ok := existing[r.Name]; ok {
+				if k.named && resolved["name"] == nil {
+					resolved["name"] = r.Name
+				}
+
+				_, _ = fmt.Fprintf(&sb, "\nimport {\n  to = %s.%s\n  id = %s\n}\n",
+					k.tfType, b.label, quote(b.id(project, resolved)))
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// A tfResource is a Terraform resource with its properties in the API's representation, plus a
+// function which returns its import ID given its resolved properties.
+type tfResource struct {
+	label string
+	props map[string]interface{}
+	id    func(project string, resolved map[string]interface{}) string
+}
+
+//nolint:gochecknoglobals // can't have const regexps
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// label returns a valid Terraform resource label for the given resource name.
+func label(name string) string {
+	l := invalidLabelChars.ReplaceAllString(name, "_")
+	if l == "" || (l[0] >= '0' && l[0] <= '9') {
+		l = "_" + l
+	}
+
+	return l
+}
@@ -0,0 +1,307 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	resources := []deployments.Resource{
+		{
+			Name: "my-app-ip",
+			Type: "compute.v1.globalAddress",
+			Properties: &compute.Address{
+				IpVersion: "IPV4",
+			},
+		},
+		{
+			Name: "my-app-fw",
+			Type: "compute.v1.firewall",
+			Properties: &compute.Firewall{
+				Allowed: []*compute.FirewallAllowed{
+					{IPProtocol: "TCP", Ports: []string{"8443"}},
+				},
+			},
+		},
+		{
+			Name: "my-app-sa",
+			Type: "iam.v1.serviceAccount",
+			Properties: &deployments.ServiceAccount{
+				AccountID:   "app-my-app",
+				DisplayName: "my-app \"${app}\"",
+			},
+		},
+		{
+			Name: "my-app-rrs",
+			Type: "gcp-types/dns-v1:resourceRecordSets",
+			Properties: &deployments.ResourceRecordSets{
+				Name:        "my-app.horse.club.",
+				ManagedZone: "belvedere",
+				Records: []*dns.ResourceRecordSet{
+					{
+						Type:    "A",
+						Rrdatas: []string{deployments.Ref("my-app-ip", "Address")},
+						Ttl:     50,
+					},
+				},
+			},
+		},
+		{
+			Name: "my-app-sa-roles/logging.logWriter",
+			Type: "gcp-types/cloudresourcemanager-v1:virtual.projects.iamMemberBinding",
+			Properties: &deployments.IAMMemberBinding{
+				Resource: "my-project",
+				Role:     "roles/logging.logWriter",
+				Member:   "serviceAccount:" + deployments.Ref("my-app-sa", "email"),
+			},
+		},
+	}
+
+	existing := map[string]map[string]interface{}{
+		"my-app-ip": {
+			"ipVersion": "IPV4",
+		},
+		"my-app-sa-roles/logging.logWriter": {
+			"resource": "my-project",
+			"role":     "roles/logging.logWriter",
+			"member":   "serviceAccount:app-my-app@my-project.iam.gserviceaccount.com",
+		},
+	}
+
+	got, err := Render("my-project", resources, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `resource "google_compute_global_address" "my-app-ip" {
+  ip_version = "IPV4"
+  name       = "my-app-ip"
+  project    = "my-project"
+}
+
+import {
+  to = google_compute_global_address.my-app-ip
+  id = "projects/my-project/global/addresses/my-app-ip"
+}
+
+resource "google_compute_firewall" "my-app-fw" {
+  name    = "my-app-fw"
+  network = "global/networks/default"
+  project = "my-project"
+  allow {
+    ports    = ["8443"]
+    protocol = "TCP"
+  }
+}
+
+resource "google_service_account" "my-app-sa" {
+  account_id   = "app-my-app"
+  display_name = "my-app \"$${app}\""
+  project      = "my-project"
+}
+
+resource "google_dns_record_set" "my-app-rrs" {
+  managed_zone = "belvedere"
+  name         = "my-app.horse.club."
+  project      = "my-project"
+  rrdatas      = [google_compute_global_address.my-app-ip.address]
+  ttl          = 50
+  type         = "A"
+}
+
+resource "google_project_iam_member" "my-app-sa-roles_logging_logWriter" {
+  member  = "serviceAccount:${google_service_account.my-app-sa.email}"
+  project = "my-project"
+  role    = "roles/logging.logWriter"
+}
+
+import {
+  to = google_project_iam_member.my-app-sa-roles_logging_logWriter
+  id = "my-project roles/logging.logWriter serviceAccount:app-my-app@my-project.iam.gserviceaccount.com"
+}
+`
+
+	assert.Equal(t, "Render()", want, got)
+}
+
+func TestRender_Release(t *testing.T) {
+	t.Parallel()
+
+	value := "true"
+	resources := []deployments.Resource{
+		{
+			Name: "my-app-v1-it",
+			Type: "compute.v1.instanceTemplate",
+			Properties: &compute.InstanceTemplate{
+				Properties: &compute.InstanceProperties{
+					Disks: []*compute.AttachedDisk{
+						{
+							Boot: true,
+							InitializeParams: &compute.AttachedDiskInitializeParams{
+								SourceImage: "cos",
+							},
+						},
+					},
+					MachineType: "n1-standard-1",
+					Metadata: &compute.Metadata{
+						Items: []*compute.MetadataItems{
+							{Key: "enable-os-login", Value: &value},
+						},
+					},
+					NetworkInterfaces: []*compute.NetworkInterface{
+						{
+							Network: "global/networks/default",
+							AccessConfigs: []*compute.AccessConfig{
+								{Name: "External NAT", Type: "ONE_TO_ONE_NAT"},
+							},
+						},
+					},
+					Tags: &compute.Tags{
+						Items: []string{"belvedere"},
+					},
+				},
+			},
+		},
+		{
+			Name: "my-app-v1-ig",
+			Type: "compute.v1.regionInstanceGroupManager",
+			Properties: &compute.InstanceGroupManager{
+				BaseInstanceName: "my-app-v1",
+				InstanceTemplate: deployments.SelfLink("my-app-v1-it"),
+				Region:           "us-central1",
+				TargetSize:       2,
+			},
+		},
+		{
+			Name: "my-app-v1-as",
+			Type: "compute.v1.regionAutoscaler",
+			Properties: &compute.Autoscaler{
+				Name: "my-app-v1",
+				AutoscalingPolicy: &compute.AutoscalingPolicy{
+					CoolDownPeriodSec: 60,
+					CpuUtilization: &compute.AutoscalingPolicyCpuUtilization{
+						UtilizationTarget: 0.6,
+					},
+					MaxNumReplicas: 10,
+					MinNumReplicas: 2,
+				},
+				Region: "us-central1",
+				Target: deployments.SelfLink("my-app-v1-ig"),
+			},
+		},
+	}
+
+	existing := map[string]map[string]interface{}{
+		"my-app-v1-as": {
+			"name":   "my-app-v1",
+			"region": "us-central1",
+		},
+	}
+
+	got, err := Render("my-project", resources, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `resource "google_compute_instance_template" "my-app-v1-it" {
+  machine_type = "n1-standard-1"
+  name         = "my-app-v1-it"
+  project      = "my-project"
+  tags         = ["belvedere"]
+  metadata = {
+    "enable-os-login" = "true"
+  }
+  disk {
+    boot         = true
+    source_image = "cos"
+  }
+  network_interface {
+    network = "global/networks/default"
+    access_config {}
+  }
+}
+
+resource "google_compute_region_instance_group_manager" "my-app-v1-ig" {
+  base_instance_name = "my-app-v1"
+  name               = "my-app-v1-ig"
+  project            = "my-project"
+  region             = "us-central1"
+  target_size        = 2
+  version {
+    instance_template = google_compute_instance_template.my-app-v1-it.self_link
+  }
+}
+
+resource "google_compute_region_autoscaler" "my-app-v1-as" {
+  name    = "my-app-v1"
+  project = "my-project"
+  region  = "us-central1"
+  target  = google_compute_region_instance_group_manager.my-app-v1-ig.self_link
+  autoscaling_policy {
+    cooldown_period = 60
+    max_replicas    = 10
+    min_replicas    = 2
+    cpu_utilization {
+      target = 0.6
+    }
+  }
+}
+
+import {
+  to = google_compute_region_autoscaler.my-app-v1-as
+  id = "projects/my-project/regions/us-central1/autoscalers/my-app-v1"
+}
+`
+
+	assert.Equal(t, "Render()", want, got)
+}
+
+func TestRender_UnsupportedType(t *testing.T) {
+	t.Parallel()
+
+	_, err := Render("my-project", []deployments.Resource{
+		{Name: "my-bucket", Type: "storage.v1.bucket", Properties: &compute.Address{}},
+	}, nil)
+
+	assert.Equal(t, "Render()", "unsupported resource type: storage.v1.bucket", err.Error())
+}
+
+func TestRender_MalformedAccessConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := Render("my-project", []deployments.Resource{
+		{
+			Name: "my-app-v1-it",
+			Type: "compute.v1.instanceTemplate",
+			Properties: json.RawMessage(
+				`{"properties": {"networkInterfaces": [{"accessConfigs": ["ONE_TO_ONE_NAT"]}]}}`),
+		},
+	}, nil)
+
+	assert.Equal(t, "Render()", "error converting my-app-v1-it: malformed accessConfigs[0]: ONE_TO_ONE_NAT",
+		err.Error())
+}
+
+func TestSnake(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"name":             "name",
+		"IPAddress":        "ip_address",
+		"IPProtocol":       "ip_protocol",
+		"enableCDN":        "enable_cdn",
+		"http2HealthCheck": "http2_health_check",
+		"oauth2ClientId":   "oauth2_client_id",
+		"selfLink":         "self_link",
+		"Address":          "address",
+	} {
+		assert.Equal(t, in, want, snake(in))
+	}
+}
@@ -0,0 +1,272 @@
+package belvedere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
+	"go.opencensus.io/trace"
+)
+
+// IPList is a named list of IP ranges which apps in the project can allow or deny.
+type IPList struct {
+	Name   string
+	Ranges []string
+}
+
+// IPListService manages the project's IP lists.
+type IPListService interface {
+	// List returns all of the project's IP lists.
+	List(ctx context.Context) ([]IPList, error)
+	// Create creates a new IP list with the given name and ranges.
+	Create(ctx context.Context, name string, ranges []string, dryRun bool) error
+	// Update replaces the ranges of the given IP list, then updates each app which refers to it with
+	// its last configuration so that its security policy has the new ranges. Each app is checked
+	// with the new ranges before the IP list is changed.
+	Update(ctx context.Context, name string, ranges []string, dryRun bool, interval time.Duration) error
+}
+
+type ipListService struct {
+	project string
+	configs configs.Store
+	apps    *appService
+}
+
+var _ IPListService = &ipListService{}
+
+type IPListExistsError struct {
+	Name string
+}
+
+func (e *IPListExistsError) Error() string {
+	return fmt.Sprintf("IP list %s already exists", e.Name)
+}
+
+type TooManyIPRulesError struct {
+	Rules int
+}
+
+func (e *TooManyIPRulesError) Error() string {
+	return fmt.Sprintf("allowed or denied IPs need %d WAF rules, more than the maximum of %d",
+		e.Rules, cfg.MaxIPRules)
+}
+
+func (s *ipListService) List(ctx context.Context) ([]IPList, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.iplists.List")
+	defer span.End()
+
+	names, err := s.configs.ListIPLists(ctx, s.project)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]IPList, len(names))
+
+	for i, name := range names {
+		ranges, err := s.configs.GetIPList(ctx, s.project, name)
+		if err != nil {
+			return nil, err
+		}
+
+		lists[i] = IPList{Name: name, Ranges: ranges}
+	}
+
+	return lists, nil
+}
+
+func (s *ipListService) Create(ctx context.Context, name string, ranges []string, dryRun bool) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.iplists.Create")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.StringAttribute("ranges", strings.Join(ranges, ",")),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	if err := validateIPList(name, ranges); err != nil {
+		return err
+	}
+
+	// Check that the IP list doesn't already exist.
+	_, err := s.configs.GetIPList(ctx, s.project, name)
+	if err == nil {
+		return &IPListExistsError{Name: name}
+	}
+
+	var notFound *configs.IPListNotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	return s.configs.PutIPList(ctx, s.project, name, ranges)
+}
+
+func (s *ipListService) Update(
+	ctx context.Context, name string, ranges []string, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.iplists.Update")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.StringAttribute("ranges", strings.Join(ranges, ",")),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	if err := validateIPList(name, ranges); err != nil {
+		return err
+	}
+
+	// Check that the IP list exists.
+	if _, err := s.configs.GetIPList(ctx, s.project, name); err != nil {
+		return err
+	}
+
+	// Find the apps which refer to the IP list.
+	apps, skipped, err := s.referringApps(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(apps))
+	for app := range apps {
+		names = append(names, app)
+	}
+
+	sort.Strings(names)
+
+	span.AddAttributes(
+		trace.StringAttribute("apps", strings.Join(names, ",")),
+	)
+
+	if len(skipped) > 0 {
+		span.Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("apps", strings.Join(skipped, ",")),
+			},
+			"Apps without a recorded configuration may refer to the IP list and weren't updated; update them by hand",
+		)
+	}
+
+	// Check that each app's configuration is still valid with the new ranges before making any changes.
+	for _, app := range names {
+		if _, err := s.apps.ipLists(ctx, &apps[app].AppConfig, map[string][]string{name: ranges}); err != nil {
+			return fmt.Errorf("error checking %s: %w", app, err)
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := s.configs.PutIPList(ctx, s.project, name, ranges); err != nil {
+		return err
+	}
+
+	// Re-render each app's security policy with the new ranges.
+	for _, app := range names {
+		if err := s.apps.Update(ctx, app, apps[app], false, false, interval); err != nil {
+			return fmt.Errorf("error updating %s: %w", app, err)
+		}
+	}
+
+	return nil
+}
+
+// referringApps returns the last configurations of the apps which refer to the given IP list, keyed
+// by app name, and the names of the apps without a recorded configuration, which can't be checked.
+func (s *ipListService) referringApps(
+	ctx context.Context, name string,
+) (map[string]*cfg.Config, []string, error) {
+	list, err := s.apps.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apps := map[string]*cfg.Config{}
+
+	var skipped []string
+
+	for _, app := range list {
+		config, err := s.configs.Get(ctx, s.project, resources.Name(app.Name))
+		if err != nil {
+			var notFound *configs.NotFoundError
+			if errors.As(err, &notFound) {
+				skipped = append(skipped, app.Name)
+				continue
+			}
+
+			return nil, nil, err
+		}
+
+		for _, l := range config.IPLists() {
+			if l == name {
+				apps[app.Name] = config
+			}
+		}
+	}
+
+	sort.Strings(skipped)
+
+	return apps, skipped, nil
+}
+
+// validateIPList returns an error if the given IP list name or any of its ranges are invalid.
+func validateIPList(name string, ranges []string) error {
+	if err := gcp.ValidateRFC1035(name); err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		if err := cfg.ValidateIPRange(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkIPLists returns an error if any of the project IP lists which the given configuration refers
+// to are missing from the given lists, or if the configuration's allowed or denied IPs need more WAF
+// rules than there are priorities reserved for them.
+func checkIPLists(config *cfg.AppConfig, ipLists map[string][]string) error {
+	for _, name := range config.IPLists() {
+		if _, ok := ipLists[name]; !ok {
+			return &configs.IPListNotFoundError{Name: name}
+		}
+	}
+
+	for _, ips := range []*cfg.IPs{config.AllowIPs, config.DenyIPs} {
+		if ips == nil {
+			continue
+		}
+
+		n := ipRuleCount(len(ips.Ranges))
+		for _, name := range ips.Lists {
+			n += ipRuleCount(len(ipLists[name]))
+		}
+
+		if n > cfg.MaxIPRules {
+			return &TooManyIPRulesError{Rules: n}
+		}
+	}
+
+	return nil
+}
+
+// ipRuleCount returns the number of WAF rules needed for the given number of IP ranges.
+func ipRuleCount(ranges int) int {
+	return (ranges + cfg.MaxRangesPerRule - 1) / cfg.MaxRangesPerRule
+}
@@ -0,0 +1,308 @@
+package belvedere
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/api/dns/v1"
+)
+
+func TestIPListService_List(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		ListIPLists(gomock.Any(), "my-project").
+		Return([]string{"bad-actors", "office"}, nil)
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "bad-actors").
+		Return([]string{"192.0.2.0/24"}, nil)
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return([]string{"203.0.113.0/24", "198.51.100.7"}, nil)
+
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+	}
+
+	got, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []IPList{
+		{Name: "bad-actors", Ranges: []string{"192.0.2.0/24"}},
+		{Name: "office", Ranges: []string{"203.0.113.0/24", "198.51.100.7"}},
+	}
+
+	assert.Equal(t, "List()", want, got)
+}
+
+func TestIPListService_Create(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return(nil, &configs.IPListNotFoundError{Name: "office"})
+	store.EXPECT().
+		PutIPList(gomock.Any(), "my-project", "office", []string{"203.0.113.0/24"})
+
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+	}
+
+	if err := s.Create(context.Background(), "office", []string{"203.0.113.0/24"}, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPListService_Create_Exists(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return([]string{"203.0.113.0/24"}, nil)
+
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+	}
+
+	err := s.Create(context.Background(), "office", []string{"203.0.113.0/24"}, false)
+
+	var exists *IPListExistsError
+	if !errors.As(err, &exists) {
+		t.Fatalf("Create() error = %v, want an IPListExistsError", err)
+	}
+}
+
+func TestIPListService_Create_InvalidRange(t *testing.T) {
+	t.Parallel()
+
+	s := &ipListService{
+		project: "my-project",
+	}
+
+	err := s.Create(context.Background(), "office", []string{"203.0.113.0/33"}, false)
+
+	var invalid *cfg.InvalidIPRangeError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Create() error = %v, want an InvalidIPRangeError", err)
+	}
+}
+
+func TestIPListService_Update(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resourceBuilder := NewResourceBuilder(ctrl)
+	dm := NewDeploymentsManager(ctrl)
+	setupService := NewSetupService(ctrl)
+	store := NewConfigStore(ctrl)
+	validator := NewMockConfigValidator(ctrl)
+
+	mz := &dns.ManagedZone{}
+	res := []deployments.Resource{
+		{
+			Name: "res",
+		},
+	}
+	config := &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			AllowIPs: &cfg.IPs{
+				Lists: []string{"office"},
+			},
+		},
+	}
+	ranges := []string{"203.0.113.0/24", "198.51.100.7"}
+
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return([]string{"203.0.113.0/24"}, nil)
+
+	// Only my-app refers to the IP list.
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "app"`).
+		Return([]deployments.Deployment{
+			{Labels: deployments.Labels{App: "my-app", Region: "us-west1"}},
+			{Labels: deployments.Labels{App: "other-app", Region: "us-west1"}},
+			{Labels: deployments.Labels{App: "old-app", Region: "us-west1"}},
+		}, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(config, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-other-app").
+		Return(&cfg.Config{}, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-old-app").
+		Return(nil, &configs.NotFoundError{Name: "belvedere-old-app"})
+
+	store.EXPECT().
+		PutIPList(gomock.Any(), "my-project", "office", ranges)
+
+	// my-app is updated with its last config and the new ranges.
+	dm.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&deployments.Deployment{
+			Labels: deployments.Labels{
+				Type:   "app",
+				App:    "my-app",
+				Region: "us-west1",
+			},
+		}, nil)
+	validator.EXPECT().
+		ValidateApp(gomock.Any(), "us-west1", config)
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "release" AND labels.belvedere-app eq "my-app"`)
+	setupService.EXPECT().
+		ManagedZone(gomock.Any(), "my-project").
+		Return(mz, nil)
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return(ranges, nil)
+	resourceBuilder.EXPECT().
+		App("my-project", "my-app", mz, &config.AppConfig, map[string][]string{"office": ranges}).
+		Return(res)
+	dm.EXPECT().
+		Update(gomock.Any(), "my-project", "belvedere-my-app", res, false, false, 10*time.Millisecond)
+	store.EXPECT().
+		Put(gomock.Any(), "my-project", "belvedere-my-app", config)
+
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+		apps: &appService{
+			project:   "my-project",
+			dm:        dm,
+			resources: resourceBuilder,
+			setup:     setupService,
+			validator: validator,
+			configs:   store,
+		},
+	}
+
+	if err := s.Update(context.Background(), "office", ranges, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPListService_Update_DryRun(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	store := NewConfigStore(ctrl)
+
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "office").
+		Return([]string{"203.0.113.0/24"}, nil)
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "app"`)
+
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+		apps: &appService{
+			project: "my-project",
+			dm:      dm,
+			configs: store,
+		},
+	}
+
+	if err := s.Update(context.Background(), "office", []string{"198.51.100.7"}, true, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPListService_Update_InvalidApp(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	store := NewConfigStore(ctrl)
+
+	ranges := make([]string, cfg.MaxIPRules*cfg.MaxRangesPerRule+1)
+	for i := range ranges {
+		ranges[i] = "192.0.2.1"
+	}
+
+	store.EXPECT().
+		GetIPList(gomock.Any(), "my-project", "bad-actors").
+		Return([]string{"203.0.113.0/24"}, nil)
+	dm.EXPECT().
+		List(gomock.Any(), "my-project", `labels.belvedere-type eq "app"`).
+		Return([]deployments.Deployment{
+			{Labels: deployments.Labels{App: "my-app", Region: "us-west1"}},
+		}, nil)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&cfg.Config{
+			AppConfig: cfg.AppConfig{
+				DenyIPs: &cfg.IPs{
+					Lists: []string{"bad-actors"},
+				},
+			},
+		}, nil)
+
+	// The IP list isn't stored, since my-app would be invalid with it.
+	s := &ipListService{
+		project: "my-project",
+		configs: store,
+		apps: &appService{
+			project: "my-project",
+			dm:      dm,
+			configs: store,
+		},
+	}
+
+	err := s.Update(context.Background(), "bad-actors", ranges, false, 10*time.Millisecond)
+
+	var tooMany *TooManyIPRulesError
+	if !errors.As(err, &tooMany) {
+		t.Fatalf("Update() error = %v, want a TooManyIPRulesError", err)
+	}
+}
+
+func TestCheckIPLists_TooManyRules(t *testing.T) {
+	t.Parallel()
+
+	ranges := make([]string, cfg.MaxIPRules*cfg.MaxRangesPerRule+1)
+	for i := range ranges {
+		ranges[i] = "192.0.2.1"
+	}
+
+	err := checkIPLists(&cfg.AppConfig{
+		DenyIPs: &cfg.IPs{
+			Lists: []string{"bad-actors"},
+		},
+	}, map[string][]string{"bad-actors": ranges})
+
+	assert.Equal(t, "checkIPLists()", &TooManyIPRulesError{Rules: cfg.MaxIPRules + 1}, err)
+}
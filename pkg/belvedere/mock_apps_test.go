@@ -36,18 +36,33 @@ func (m *MockAppService) EXPECT() *MockAppServiceMockRecorder {
 	return m.recorder
 }
 
+// Config mocks base method.
+func (m *MockAppService) Config(ctx context.Context, name string) (*cfg.Config, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Config", ctx, name)
+	ret0, _ := ret[0].(*cfg.Config)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Config indicates an expected call of Config.
+func (mr *MockAppServiceMockRecorder) Config(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Config", reflect.TypeOf((*MockAppService)(nil).Config), ctx, name)
+}
+
 // Create mocks base method.
-func (m *MockAppService) Create(ctx context.Context, region, name string, config *cfg.Config, dryRun bool, interval time.Duration) error {
+func (m *MockAppService) Create(ctx context.Context, region, name string, config *cfg.Config, dryRun, preview bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, region, name, config, dryRun, interval)
+	ret := m.ctrl.Call(m, "Create", ctx, region, name, config, dryRun, preview, interval)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockAppServiceMockRecorder) Create(ctx, region, name, config, dryRun, interval interface{}) *gomock.Call {
+func (mr *MockAppServiceMockRecorder) Create(ctx, region, name, config, dryRun, preview, interval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAppService)(nil).Create), ctx, region, name, config, dryRun, interval)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAppService)(nil).Create), ctx, region, name, config, dryRun, preview, interval)
 }
 
 // Delete mocks base method.
@@ -64,6 +79,36 @@ func (mr *MockAppServiceMockRecorder) Delete(ctx, name, dryRun, async, interval
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAppService)(nil).Delete), ctx, name, dryRun, async, interval)
 }
 
+// Diff mocks base method.
+func (m *MockAppService) Diff(ctx context.Context, name string, config *cfg.Config) ([]ResourceDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diff", ctx, name, config)
+	ret0, _ := ret[0].([]ResourceDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diff indicates an expected call of Diff.
+func (mr *MockAppServiceMockRecorder) Diff(ctx, name, config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diff", reflect.TypeOf((*MockAppService)(nil).Diff), ctx, name, config)
+}
+
+// Failures mocks base method.
+func (m *MockAppService) Failures(ctx context.Context, name string) ([]FailedResource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Failures", ctx, name)
+	ret0, _ := ret[0].([]FailedResource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Failures indicates an expected call of Failures.
+func (mr *MockAppServiceMockRecorder) Failures(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Failures", reflect.TypeOf((*MockAppService)(nil).Failures), ctx, name)
+}
+
 // Get mocks base method.
 func (m *MockAppService) Get(ctx context.Context, name string) (*App, error) {
 	m.ctrl.T.Helper()
@@ -94,16 +139,30 @@ func (mr *MockAppServiceMockRecorder) List(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAppService)(nil).List), ctx)
 }
 
+// Repair mocks base method.
+func (m *MockAppService) Repair(ctx context.Context, name string, abandon, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Repair", ctx, name, abandon, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Repair indicates an expected call of Repair.
+func (mr *MockAppServiceMockRecorder) Repair(ctx, name, abandon, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Repair", reflect.TypeOf((*MockAppService)(nil).Repair), ctx, name, abandon, dryRun, interval)
+}
+
 // Update mocks base method.
-func (m *MockAppService) Update(ctx context.Context, name string, config *cfg.Config, dryRun bool, interval time.Duration) error {
+func (m *MockAppService) Update(ctx context.Context, name string, config *cfg.Config, dryRun, preview bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, name, config, dryRun, interval)
+	ret := m.ctrl.Call(m, "Update", ctx, name, config, dryRun, preview, interval)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockAppServiceMockRecorder) Update(ctx, name, config, dryRun, interval interface{}) *gomock.Call {
+func (mr *MockAppServiceMockRecorder) Update(ctx, name, config, dryRun, preview, interval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockAppService)(nil).Update), ctx, name, config, dryRun, interval)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockAppService)(nil).Update), ctx, name, config, dryRun, preview, interval)
 }
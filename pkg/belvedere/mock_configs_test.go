@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/configs/configs.go
+
+// Package belvedere is a generated GoMock package.
+package belvedere
+
+import (
+	context "context"
+	reflect "reflect"
+
+	cfg "github.com/codahale/belvedere/pkg/belvedere/cfg"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// ConfigStore is a mock of Store interface.
+type ConfigStore struct {
+	ctrl     *gomock.Controller
+	recorder *ConfigStoreMockRecorder
+}
+
+// ConfigStoreMockRecorder is the mock recorder for ConfigStore.
+type ConfigStoreMockRecorder struct {
+	mock *ConfigStore
+}
+
+// NewConfigStore creates a new mock instance.
+func NewConfigStore(ctrl *gomock.Controller) *ConfigStore {
+	mock := &ConfigStore{ctrl: ctrl}
+	mock.recorder = &ConfigStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *ConfigStore) EXPECT() *ConfigStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *ConfigStore) Delete(ctx context.Context, project, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, project, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *ConfigStoreMockRecorder) Delete(ctx, project, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ConfigStore)(nil).Delete), ctx, project, name)
+}
+
+// Get mocks base method.
+func (m *ConfigStore) Get(ctx context.Context, project, name string) (*cfg.Config, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, project, name)
+	ret0, _ := ret[0].(*cfg.Config)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *ConfigStoreMockRecorder) Get(ctx, project, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*ConfigStore)(nil).Get), ctx, project, name)
+}
+
+// GetIPList mocks base method.
+func (m *ConfigStore) GetIPList(ctx context.Context, project, name string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIPList", ctx, project, name)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIPList indicates an expected call of GetIPList.
+func (mr *ConfigStoreMockRecorder) GetIPList(ctx, project, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIPList", reflect.TypeOf((*ConfigStore)(nil).GetIPList), ctx, project, name)
+}
+
+// ListIPLists mocks base method.
+func (m *ConfigStore) ListIPLists(ctx context.Context, project string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIPLists", ctx, project)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIPLists indicates an expected call of ListIPLists.
+func (mr *ConfigStoreMockRecorder) ListIPLists(ctx, project interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIPLists", reflect.TypeOf((*ConfigStore)(nil).ListIPLists), ctx, project)
+}
+
+// Put mocks base method.
+func (m *ConfigStore) Put(ctx context.Context, project, name string, config *cfg.Config) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, project, name, config)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *ConfigStoreMockRecorder) Put(ctx, project, name, config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*ConfigStore)(nil).Put), ctx, project, name, config)
+}
+
+// PutIPList mocks base method.
+func (m *ConfigStore) PutIPList(ctx context.Context, project, name string, ranges []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutIPList", ctx, project, name, ranges)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutIPList indicates an expected call of PutIPList.
+func (mr *ConfigStoreMockRecorder) PutIPList(ctx, project, name, ranges interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutIPList", reflect.TypeOf((*ConfigStore)(nil).PutIPList), ctx, project, name, ranges)
+}
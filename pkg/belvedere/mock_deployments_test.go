@@ -36,6 +36,34 @@ func (m *DeploymentsManager) EXPECT() *DeploymentsManagerMockRecorder {
 	return m.recorder
 }
 
+// Abandon mocks base method.
+func (m *DeploymentsManager) Abandon(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abandon", ctx, project, name, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abandon indicates an expected call of Abandon.
+func (mr *DeploymentsManagerMockRecorder) Abandon(ctx, project, name, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abandon", reflect.TypeOf((*DeploymentsManager)(nil).Abandon), ctx, project, name, dryRun, interval)
+}
+
+// CancelPreview mocks base method.
+func (m *DeploymentsManager) CancelPreview(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPreview", ctx, project, name, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelPreview indicates an expected call of CancelPreview.
+func (mr *DeploymentsManagerMockRecorder) CancelPreview(ctx, project, name, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPreview", reflect.TypeOf((*DeploymentsManager)(nil).CancelPreview), ctx, project, name, dryRun, interval)
+}
+
 // Delete mocks base method.
 func (m *DeploymentsManager) Delete(ctx context.Context, project, name string, dryRun, async bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
@@ -66,17 +94,17 @@ func (mr *DeploymentsManagerMockRecorder) Get(ctx, project, name interface{}) *g
 }
 
 // Insert mocks base method.
-func (m *DeploymentsManager) Insert(ctx context.Context, project, name string, resources []deployments.Resource, labels deployments.Labels, dryRun bool, interval time.Duration) error {
+func (m *DeploymentsManager) Insert(ctx context.Context, project, name string, resources []deployments.Resource, labels deployments.Labels, dryRun, preview bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Insert", ctx, project, name, resources, labels, dryRun, interval)
+	ret := m.ctrl.Call(m, "Insert", ctx, project, name, resources, labels, dryRun, preview, interval)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Insert indicates an expected call of Insert.
-func (mr *DeploymentsManagerMockRecorder) Insert(ctx, project, name, resources, labels, dryRun, interval interface{}) *gomock.Call {
+func (mr *DeploymentsManagerMockRecorder) Insert(ctx, project, name, resources, labels, dryRun, preview, interval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*DeploymentsManager)(nil).Insert), ctx, project, name, resources, labels, dryRun, interval)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*DeploymentsManager)(nil).Insert), ctx, project, name, resources, labels, dryRun, preview, interval)
 }
 
 // List mocks base method.
@@ -94,16 +122,74 @@ func (mr *DeploymentsManagerMockRecorder) List(ctx, project, filter interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*DeploymentsManager)(nil).List), ctx, project, filter)
 }
 
+// Resources mocks base method.
+func (m *DeploymentsManager) Resources(ctx context.Context, project, name string) ([]deployments.Resource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resources", ctx, project, name)
+	ret0, _ := ret[0].([]deployments.Resource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resources indicates an expected call of Resources.
+func (mr *DeploymentsManagerMockRecorder) Resources(ctx, project, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resources", reflect.TypeOf((*DeploymentsManager)(nil).Resources), ctx, project, name)
+}
+
+// Restore mocks base method.
+func (m *DeploymentsManager) Restore(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, project, name, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *DeploymentsManagerMockRecorder) Restore(ctx, project, name, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*DeploymentsManager)(nil).Restore), ctx, project, name, dryRun, interval)
+}
+
+// Status mocks base method.
+func (m *DeploymentsManager) Status(ctx context.Context, project, name string) (*deployments.Status, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", ctx, project, name)
+	ret0, _ := ret[0].(*deployments.Status)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *DeploymentsManagerMockRecorder) Status(ctx, project, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*DeploymentsManager)(nil).Status), ctx, project, name)
+}
+
+// Stop mocks base method.
+func (m *DeploymentsManager) Stop(ctx context.Context, project, name string, dryRun bool, interval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stop", ctx, project, name, dryRun, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *DeploymentsManagerMockRecorder) Stop(ctx, project, name, dryRun, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*DeploymentsManager)(nil).Stop), ctx, project, name, dryRun, interval)
+}
+
 // Update mocks base method.
-func (m *DeploymentsManager) Update(ctx context.Context, project, name string, resources []deployments.Resource, dryRun bool, interval time.Duration) error {
+func (m *DeploymentsManager) Update(ctx context.Context, project, name string, resources []deployments.Resource, dryRun, preview bool, interval time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, project, name, resources, dryRun, interval)
+	ret := m.ctrl.Call(m, "Update", ctx, project, name, resources, dryRun, preview, interval)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Update indicates an expected call of Update.
-func (mr *DeploymentsManagerMockRecorder) Update(ctx, project, name, resources, dryRun, interval interface{}) *gomock.Call {
+func (mr *DeploymentsManagerMockRecorder) Update(ctx, project, name, resources, dryRun, preview, interval interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*DeploymentsManager)(nil).Update), ctx, project, name, resources, dryRun, interval)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*DeploymentsManager)(nil).Update), ctx, project, name, resources, dryRun, preview, interval)
 }
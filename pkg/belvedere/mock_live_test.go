@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/live/live.go
+
+// Package belvedere is a generated GoMock package.
+package belvedere
+
+import (
+	context "context"
+	reflect "reflect"
+
+	deployments "github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// LiveFetcher is a mock of Fetcher interface.
+type LiveFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *LiveFetcherMockRecorder
+}
+
+// LiveFetcherMockRecorder is the mock recorder for LiveFetcher.
+type LiveFetcherMockRecorder struct {
+	mock *LiveFetcher
+}
+
+// NewLiveFetcher creates a new mock instance.
+func NewLiveFetcher(ctrl *gomock.Controller) *LiveFetcher {
+	mock := &LiveFetcher{ctrl: ctrl}
+	mock.recorder = &LiveFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *LiveFetcher) EXPECT() *LiveFetcherMockRecorder {
+	return m.recorder
+}
+
+// Fetch mocks base method.
+func (m *LiveFetcher) Fetch(ctx context.Context, project string, r *deployments.Resource) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fetch", ctx, project, r)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fetch indicates an expected call of Fetch.
+func (mr *LiveFetcherMockRecorder) Fetch(ctx, project, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*LiveFetcher)(nil).Fetch), ctx, project, r)
+}
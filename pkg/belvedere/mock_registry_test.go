@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/registry/registry.go
+
+// Package belvedere is a generated GoMock package.
+package belvedere
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// RegistryClient is a mock of Client interface.
+type RegistryClient struct {
+	ctrl     *gomock.Controller
+	recorder *RegistryClientMockRecorder
+}
+
+// RegistryClientMockRecorder is the mock recorder for RegistryClient.
+type RegistryClientMockRecorder struct {
+	mock *RegistryClient
+}
+
+// NewRegistryClient creates a new mock instance.
+func NewRegistryClient(ctrl *gomock.Controller) *RegistryClient {
+	mock := &RegistryClient{ctrl: ctrl}
+	mock.recorder = &RegistryClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *RegistryClient) EXPECT() *RegistryClientMockRecorder {
+	return m.recorder
+}
+
+// Digest mocks base method.
+func (m *RegistryClient) Digest(ctx context.Context, image string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Digest", ctx, image)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Digest indicates an expected call of Digest.
+func (mr *RegistryClientMockRecorder) Digest(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Digest", reflect.TypeOf((*RegistryClient)(nil).Digest), ctx, image)
+}
+
+// Exists mocks base method.
+func (m *RegistryClient) Exists(ctx context.Context, image string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, image)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *RegistryClientMockRecorder) Exists(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*RegistryClient)(nil).Exists), ctx, image)
+}
@@ -37,17 +37,17 @@ func (m *ResourceBuilder) EXPECT() *ResourceBuilderMockRecorder {
 }
 
 // App mocks base method.
-func (m *ResourceBuilder) App(project, app string, managedZone *dns.ManagedZone, config *cfg.Config) []deployments.Resource {
+func (m *ResourceBuilder) App(project, app string, managedZone *dns.ManagedZone, config *cfg.AppConfig, ipLists map[string][]string) []deployments.Resource {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "App", project, app, managedZone, config)
+	ret := m.ctrl.Call(m, "App", project, app, managedZone, config, ipLists)
 	ret0, _ := ret[0].([]deployments.Resource)
 	return ret0
 }
 
 // App indicates an expected call of App.
-func (mr *ResourceBuilderMockRecorder) App(project, app, managedZone, config interface{}) *gomock.Call {
+func (mr *ResourceBuilderMockRecorder) App(project, app, managedZone, config, ipLists interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "App", reflect.TypeOf((*ResourceBuilder)(nil).App), project, app, managedZone, config)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "App", reflect.TypeOf((*ResourceBuilder)(nil).App), project, app, managedZone, config, ipLists)
 }
 
 // Base mocks base method.
@@ -65,7 +65,7 @@ func (mr *ResourceBuilderMockRecorder) Base(dnsZone interface{}) *gomock.Call {
 }
 
 // Release mocks base method.
-func (m *ResourceBuilder) Release(project, region, app, release, imageSHA256 string, config *cfg.Config) []deployments.Resource {
+func (m *ResourceBuilder) Release(project, region, app, release, imageSHA256 string, config *cfg.ReleaseConfig) []deployments.Resource {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Release", project, region, app, release, imageSHA256, config)
 	ret0, _ := ret[0].([]deployments.Resource)
@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: validate.go
+
+// Package belvedere is a generated GoMock package.
+package belvedere
+
+import (
+	context "context"
+	reflect "reflect"
+
+	cfg "github.com/codahale/belvedere/pkg/belvedere/cfg"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockConfigValidator is a mock of ConfigValidator interface.
+type MockConfigValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockConfigValidatorMockRecorder
+}
+
+// MockConfigValidatorMockRecorder is the mock recorder for MockConfigValidator.
+type MockConfigValidatorMockRecorder struct {
+	mock *MockConfigValidator
+}
+
+// NewMockConfigValidator creates a new mock instance.
+func NewMockConfigValidator(ctrl *gomock.Controller) *MockConfigValidator {
+	mock := &MockConfigValidator{ctrl: ctrl}
+	mock.recorder = &MockConfigValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConfigValidator) EXPECT() *MockConfigValidatorMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockConfigValidator) Validate(ctx context.Context, region string, config *cfg.Config, imageSHA256 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", ctx, region, config, imageSHA256)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockConfigValidatorMockRecorder) Validate(ctx, region, config, imageSHA256 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockConfigValidator)(nil).Validate), ctx, region, config, imageSHA256)
+}
+
+// ValidateApp mocks base method.
+func (m *MockConfigValidator) ValidateApp(ctx context.Context, region string, config *cfg.Config) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateApp", ctx, region, config)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateApp indicates an expected call of ValidateApp.
+func (mr *MockConfigValidatorMockRecorder) ValidateApp(ctx, region, config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateApp", reflect.TypeOf((*MockConfigValidator)(nil).ValidateApp), ctx, region, config)
+}
+
+// ValidateRelease mocks base method.
+func (m *MockConfigValidator) ValidateRelease(ctx context.Context, region string, config *cfg.Config, imageSHA256 string, pin bool) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateRelease", ctx, region, config, imageSHA256, pin)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateRelease indicates an expected call of ValidateRelease.
+func (mr *MockConfigValidatorMockRecorder) ValidateRelease(ctx, region, config, imageSHA256, pin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRelease", reflect.TypeOf((*MockConfigValidator)(nil).ValidateRelease), ctx, region, config, imageSHA256, pin)
+}
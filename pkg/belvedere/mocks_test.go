@@ -7,3 +7,8 @@ package belvedere
 //go:generate mockgen -package belvedere -mock_names Service=BackendsService -destination mock_backends_test.go -source internal/backends/backends.go Service
 //go:generate mockgen -package belvedere -destination mock_health_test.go -source internal/check/health.go HealthChecker
 //go:generate mockgen -package belvedere -destination mock_apps_test.go -source apps.go AppService
+//go:generate mockgen -package belvedere -destination mock_images_test.go -source images.go ImageService
+//go:generate mockgen -package belvedere -mock_names Client=RegistryClient -destination mock_registry_test.go -source internal/registry/registry.go Client
+//go:generate mockgen -package belvedere -mock_names Fetcher=LiveFetcher -destination mock_live_test.go -source internal/live/live.go Fetcher
+//go:generate mockgen -package belvedere -destination mock_validate_test.go -source validate.go ConfigValidator
+//go:generate mockgen -package belvedere -mock_names Store=ConfigStore -destination mock_configs_test.go -source internal/configs/configs.go Store
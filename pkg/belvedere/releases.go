@@ -1,14 +1,19 @@
 package belvedere
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/backends"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/check"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
@@ -18,11 +23,28 @@ import (
 
 // A Release describes a specific release of an app.
 type Release struct {
-	Project string
-	Region  string
-	App     string
-	Release string
-	Hash    string
+	Project   string
+	Region    string
+	App       string
+	Release   string
+	Hash      string
+	BootImage string `table:"Boot Image"`
+}
+
+// ReleaseDetails describes a specific release of an app in full, including the effective
+// configuration it was created with.
+type ReleaseDetails struct {
+	Project     string
+	Region      string
+	App         string
+	Release     string
+	ImageSHA256 string `table:"Image SHA-256"`
+	BootImage   string `table:"Boot Image"`
+	Created     time.Time
+	Creator     string
+	// Images maps the names of the release's containers to their pinned image references.
+	Images map[string]string  `table:"-"`
+	Config *cfg.ReleaseConfig `table:"-"`
 }
 
 // ReleaseService provides methods for managing releases.
@@ -30,10 +52,17 @@ type ReleaseService interface {
 	// List returns a list of releases in the given project for the given app, if any is passed.
 	List(ctx context.Context, app string) ([]Release, error)
 
-	// Create creates a deployment containing release resources for the given app.
+	// Get returns the details of the given release, including the configuration it was created with.
+	Get(ctx context.Context, app, name string) (*ReleaseDetails, error)
+
+	// Diff returns the differences between the resources of two releases of the given app.
+	Diff(ctx context.Context, app, a, b string) ([]ResourceDiff, error)
+
+	// Create creates a deployment containing release resources for the given app. If preview is
+	// true, the planned changes are validated and printed by Deployment Manager but not made.
 	Create(
-		ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string, dryRun bool,
-		interval time.Duration,
+		ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string,
+		dryRun, preview bool, interval time.Duration,
 	) error
 
 	// Enable adds the release's instance group to the app's backend project and waits for the
@@ -43,8 +72,21 @@ type ReleaseService interface {
 	// Disable removes the release's instance group from the app's backend project.
 	Disable(ctx context.Context, app, name string, dryRun bool, interval time.Duration) error
 
+	// Config returns the configuration the given release was created with, with its boot image and
+	// sidecar images pinned to the versions which were deployed. Comments and formatting aren't kept.
+	Config(ctx context.Context, app, name string) (*cfg.Config, error)
+
 	// Delete deletes the release's deployment and waits for all underlying resources to be deleted.
 	Delete(ctx context.Context, app, name string, dryRun, async bool, interval time.Duration) error
+
+	// Failures returns the resources of the given release whose most recent changes failed or never
+	// finished.
+	Failures(ctx context.Context, app, name string) ([]FailedResource, error)
+
+	// Repair stops any running changes to the given release, cancels any outstanding preview, and
+	// restores its resources to the last configuration which was successfully deployed. If abandon
+	// is true, the release's deployment is deleted instead, leaving its resources in place.
+	Repair(ctx context.Context, app, name string, abandon, dryRun bool, interval time.Duration) error
 }
 
 type releaseService struct {
@@ -55,6 +97,8 @@ type releaseService struct {
 	resources resources.Builder
 	health    check.HealthChecker
 	apps      AppService
+	validator ConfigValidator
+	configs   configs.Store
 }
 
 func (r *releaseService) List(ctx context.Context, app string) ([]Release, error) {
@@ -65,28 +109,125 @@ func (r *releaseService) List(ctx context.Context, app string) ([]Release, error
 		span.AddAttributes(trace.StringAttribute("app", app))
 	}
 
+	list, err := r.dm.List(ctx, r.project, releaseFilter(app))
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, len(list))
+	for i, dep := range list {
+		releases[i] = Release{
+			Project:   r.project,
+			Region:    dep.Region,
+			App:       dep.App,
+			Release:   dep.Release,
+			Hash:      dep.Hash,
+			BootImage: dep.BootImage,
+		}
+	}
+
+	return releases, nil
+}
+
+// releaseFilter returns a deployment filter which matches the releases of the given app, or of all
+// apps if none is given.
+func releaseFilter(app string) string {
 	filter := `labels.belvedere-type eq "release"`
 	if app != "" {
 		filter = fmt.Sprintf("%s AND labels.belvedere-app eq %q", filter, app)
 	}
 
-	list, err := r.dm.List(ctx, r.project, filter)
+	return filter
+}
+
+func (r *releaseService) Get(ctx context.Context, app, name string) (*ReleaseDetails, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Get")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("name", name),
+	)
+
+	dep, err := r.dm.Get(ctx, r.project, resources.Name(app, name))
+	if err != nil {
+		return nil, fmt.Errorf("error getting release: %w", err)
+	}
+
+	res, err := r.dm.Resources(ctx, r.project, resources.Name(app, name))
+	if err != nil {
+		return nil, fmt.Errorf("error getting release: %w", err)
+	}
+
+	decoded, err := resources.DecodeRelease(app, res)
 	if err != nil {
 		return nil, err
 	}
 
-	releases := make([]Release, len(list))
-	for i, dep := range list {
-		releases[i] = Release{
-			Project: r.project,
-			Region:  dep.Region,
-			App:     dep.App,
-			Release: dep.Release,
-			Hash:    dep.Hash,
+	return &ReleaseDetails{
+		Project:     r.project,
+		Region:      dep.Region,
+		App:         app,
+		Release:     name,
+		ImageSHA256: decoded.ImageSHA256,
+		BootImage:   decoded.Config.BootImage,
+		Created:     dep.Created,
+		Creator:     dep.Creator,
+		Images:      decoded.Images,
+		Config:      decoded.Config,
+	}, nil
+}
+
+func (r *releaseService) Diff(ctx context.Context, app, a, b string) ([]ResourceDiff, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Diff")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("a", a),
+		trace.StringAttribute("b", b),
+	)
+
+	resA, err := r.dm.Resources(ctx, r.project, resources.Name(app, a))
+	if err != nil {
+		return nil, fmt.Errorf("error getting release %s: %w", a, err)
+	}
+
+	resB, err := r.dm.Resources(ctx, r.project, resources.Name(app, b))
+	if err != nil {
+		return nil, fmt.Errorf("error getting release %s: %w", b, err)
+	}
+
+	// Resource names include the release name, so compare A's resources as if they were B's.
+	renamed, err := renameRelease(resA, fmt.Sprintf("%s-%s-", app, a), fmt.Sprintf("%s-%s-", app, b))
+	if err != nil {
+		return nil, err
+	}
+
+	return diffResources(renamed, resB)
+}
+
+// renameRelease returns the given resources with the given prefix replaced in their names and in
+// any references between them.
+func renameRelease(res []deployments.Resource, from, to string) ([]deployments.Resource, error) {
+	renamed := make([]deployments.Resource, len(res))
+
+	for i, r := range res {
+		b, err := r.Properties.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		b = bytes.ReplaceAll(b, []byte("$(ref."+from), []byte("$(ref."+to))
+
+		renamed[i] = deployments.Resource{
+			Name:       strings.Replace(r.Name, from, to, 1),
+			Type:       r.Type,
+			Properties: json.RawMessage(b),
 		}
 	}
 
-	return releases, nil
+	return renamed, nil
 }
 
 var imageHashFormat = regexp.MustCompile(`^[a-f0-9]{64}$`)
@@ -100,8 +241,8 @@ func (e *InvalidSHA256DigestError) Error() string {
 }
 
 func (r *releaseService) Create(
-	ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string, dryRun bool,
-	interval time.Duration,
+	ctx context.Context, app, name string, config *cfg.Config, imageSHA256 string,
+	dryRun, preview bool, interval time.Duration,
 ) error {
 	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Create")
 	defer span.End()
@@ -111,6 +252,7 @@ func (r *releaseService) Create(
 		trace.StringAttribute("name", name),
 		trace.StringAttribute("image_sha256", imageSHA256),
 		trace.BoolAttribute("dry_run", dryRun),
+		trace.BoolAttribute("preview", preview),
 	)
 
 	if err := gcp.ValidateRFC1035(name); err != nil {
@@ -121,22 +263,159 @@ func (r *releaseService) Create(
 		return &InvalidSHA256DigestError{Digest: imageSHA256}
 	}
 
+	if err := config.ReleaseConfig.Validate(); err != nil {
+		return err
+	}
+
 	a, err := r.apps.Get(ctx, app)
 	if err != nil {
 		return err
 	}
 
-	return r.dm.Insert(ctx, r.project, resources.Name(app, name),
-		r.resources.Release(r.project, a.Region, app, name, imageSHA256, config),
+	// Pin the sidecar images so that all instances of the release run the same sidecar builds, unless
+	// the configuration opts out. Dry runs and previews don't create the release, so they show the
+	// images as configured.
+	pin := (config.PinSidecars == nil || *config.PinSidecars) && !dryRun && !preview
+
+	// Check the configuration against the project before making any changes. If the sidecar images
+	// are being pinned, this resolves their digests, too.
+	pinnedImages, err := r.validator.ValidateRelease(ctx, a.Region, config, imageSHA256, pin)
+	if err != nil {
+		return err
+	}
+
+	// Warn if the app-level settings differ from the app's, since they're ignored.
+	fields, err := r.ignoredChanges(ctx, app, config)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		span.Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("fields", strings.Join(fields, ",")),
+			},
+			"App-level settings differ from the app's and were ignored; update the app to change them",
+		)
+	}
+
+	// Pin the boot image so that all instances of the release run the same OS build.
+	bootImage, err := r.resolveBootImage(ctx, config.BootImage)
+	if err != nil {
+		return err
+	}
+
+	pinned := *config
+	pinned.BootImage = bootImage
+
+	if pin {
+		pinned.Sidecars = pinSidecars(config.Sidecars, pinnedImages)
+	}
+
+	if err := r.dm.Insert(ctx, r.project, resources.Name(app, name),
+		r.resources.Release(r.project, a.Region, app, name, imageSHA256, &pinned.ReleaseConfig),
 		deployments.Labels{
-			Type:    "release",
-			App:     app,
-			Release: name,
-			Region:  a.Region,
-			Hash:    imageSHA256[:32],
+			Type:      "release",
+			App:       app,
+			Release:   name,
+			Region:    a.Region,
+			Hash:      imageSHA256[:32],
+			BootImage: lastPathComponent(bootImage),
 		},
-		dryRun, interval,
-	)
+		dryRun, preview, interval,
+	); err != nil {
+		return err
+	}
+
+	if dryRun || preview {
+		return nil
+	}
+
+	// Record the configuration as it was pinned, so it matches what the release runs.
+	return r.configs.Put(ctx, r.project, resources.Name(app, name), &pinned)
+}
+
+// ignoredChanges returns the app-level settings, which creating a release ignores, which differ
+// between the given configuration and the given app's recorded configuration, if any.
+func (r *releaseService) ignoredChanges(ctx context.Context, app string, config *cfg.Config) ([]string, error) {
+	live, err := r.apps.Config(ctx, app)
+	if err != nil {
+		var notFound *configs.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return config.AppConfig.Changes(&live.AppConfig), nil
+}
+
+// pinSidecars returns a copy of the given sidecars with their images replaced by the given pinned
+// images.
+func pinSidecars(sidecars map[string]cfg.Container, images map[string]string) map[string]cfg.Container {
+	if sidecars == nil {
+		return nil
+	}
+
+	pinned := make(map[string]cfg.Container, len(sidecars))
+
+	for name, sidecar := range sidecars {
+		sidecar.Image = images[name]
+		pinned[name] = sidecar
+	}
+
+	return pinned
+}
+
+var bootImageFormat = regexp.MustCompile(
+	`^(?:https://[^/]+/compute/[^/]+/)?(?:projects/([^/]+)/)?global/images/(family/)?([a-z][-a-z0-9]*)$`,
+)
+
+type InvalidBootImageError struct {
+	Image string
+}
+
+func (e *InvalidBootImageError) Error() string {
+	return fmt.Sprintf("invalid boot image: %q", e.Image)
+}
+
+// resolveBootImage returns the URL of the specific image for the given boot image. If the boot image
+// is an image family, it is resolved to the family's current image.
+func (r *releaseService) resolveBootImage(ctx context.Context, image string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.resolveBootImage")
+	defer span.End()
+
+	if image == "" {
+		image = cfg.DefaultBootImage
+	}
+
+	span.AddAttributes(trace.StringAttribute("image", image))
+
+	m := bootImageFormat.FindStringSubmatch(image)
+	if m == nil {
+		return "", &InvalidBootImageError{Image: image}
+	}
+
+	// Specific images are already pinned.
+	if m[2] == "" {
+		return image, nil
+	}
+
+	// Images without a project are in the app's project.
+	project := m[1]
+	if project == "" {
+		project = r.project
+	}
+
+	i, err := r.gce.Images.GetFromFamily(project, m[3]).Context(ctx).Fields("selfLink").Do()
+	if err != nil {
+		return "", fmt.Errorf("error resolving image family %q: %w", image, err)
+	}
+
+	span.AddAttributes(trace.StringAttribute("resolved_image", i.SelfLink))
+
+	return i.SelfLink, nil
 }
 
 func (r *releaseService) Enable(ctx context.Context, app, name string, dryRun bool, interval time.Duration) error {
@@ -198,5 +477,60 @@ func (r *releaseService) Delete(
 		trace.BoolAttribute("async", async),
 	)
 
-	return r.dm.Delete(ctx, r.project, resources.Name(app, name), dryRun, async, interval)
+	if err := r.dm.Delete(ctx, r.project, resources.Name(app, name), dryRun, async, interval); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	// Only delete the release's recorded configuration once the release is gone.
+	if async {
+		span.Annotate(nil, "Kept the release's recorded configuration, since its deletion may still fail")
+
+		return nil
+	}
+
+	return r.configs.Delete(ctx, r.project, resources.Name(app, name))
+}
+
+func (r *releaseService) Config(ctx context.Context, app, name string) (*cfg.Config, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Config")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("name", name),
+	)
+
+	return r.configs.Get(ctx, r.project, resources.Name(app, name))
+}
+
+func (r *releaseService) Failures(ctx context.Context, app, name string) ([]FailedResource, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Failures")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("name", name),
+	)
+
+	return failures(ctx, r.dm, r.project, resources.Name(app, name))
+}
+
+func (r *releaseService) Repair(
+	ctx context.Context, app, name string, abandon, dryRun bool, interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.releases.Repair")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("app", app),
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("abandon", abandon),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	return repair(ctx, r.dm, r.project, resources.Name(app, name), abandon, dryRun, interval)
 }
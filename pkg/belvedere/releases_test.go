@@ -2,14 +2,22 @@ package belvedere
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
 	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
 	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
 	"github.com/golang/mock/gomock"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
 )
 
 func TestReleaseService_List(t *testing.T) {
@@ -25,11 +33,12 @@ func TestReleaseService_List(t *testing.T) {
 		Return([]deployments.Deployment{
 			{
 				Labels: deployments.Labels{
-					Type:    "release",
-					App:     "my-app",
-					Region:  "us-west1",
-					Release: "v1",
-					Hash:    "123456",
+					Type:      "release",
+					App:       "my-app",
+					Region:    "us-west1",
+					Release:   "v1",
+					Hash:      "123456",
+					BootImage: "cos-stable-89",
 				},
 			},
 		}, nil)
@@ -46,11 +55,12 @@ func TestReleaseService_List(t *testing.T) {
 
 	want := []Release{
 		{
-			Project: "my-project",
-			App:     "my-app",
-			Region:  "us-west1",
-			Release: "v1",
-			Hash:    "123456",
+			Project:   "my-project",
+			App:       "my-app",
+			Region:    "us-west1",
+			Release:   "v1",
+			Hash:      "123456",
+			BootImage: "cos-stable-89",
 		},
 	}
 
@@ -74,11 +84,12 @@ func TestReleaseService_List_withApp(t *testing.T) {
 		Return([]deployments.Deployment{
 			{
 				Labels: deployments.Labels{
-					Type:    "release",
-					App:     "my-app",
-					Region:  "us-west1",
-					Release: "v1",
-					Hash:    "123456",
+					Type:      "release",
+					App:       "my-app",
+					Region:    "us-west1",
+					Release:   "v1",
+					Hash:      "123456",
+					BootImage: "cos-stable-89",
 				},
 			},
 		}, nil)
@@ -95,20 +106,148 @@ func TestReleaseService_List_withApp(t *testing.T) {
 
 	want := []Release{
 		{
-			Project: "my-project",
-			App:     "my-app",
-			Region:  "us-west1",
-			Release: "v1",
-			Hash:    "123456",
+			Project:   "my-project",
+			App:       "my-app",
+			Region:    "us-west1",
+			Release:   "v1",
+			Hash:      "123456",
+			BootImage: "cos-stable-89",
 		},
 	}
 
 	assert.Equal(t, "List()", want, got)
 }
 
+func TestReleaseService_Get(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &cfg.ReleaseConfig{
+		NumReplicas: 2,
+		MachineType: "n1-standard-1",
+		BootImage:   "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89",
+		Container: cfg.Container{
+			Image: "gcr.io/my-project/my-app",
+		},
+		Sidecars: map[string]cfg.Container{
+			"nginx": {
+				Image: "docker.io/nginx@sha256:abcdef",
+			},
+		},
+	}
+	imageSHA256 := strings.Repeat("1", 64)
+	created := time.Date(2021, 4, 1, 12, 30, 0, 0, time.UTC)
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return(&deployments.Deployment{
+			Name:    "belvedere-my-app-v1",
+			Created: created,
+			Creator: "ops@example.com",
+			Labels: deployments.Labels{
+				Type:    "release",
+				App:     "my-app",
+				Region:  "us-west1",
+				Release: "v1",
+			},
+		}, nil)
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return(resources.NewBuilder().Release("my-project", "us-west1", "my-app", "v1", imageSHA256, config), nil)
+
+	service := &releaseService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	got, err := service.Get(context.Background(), "my-app", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ReleaseDetails{
+		Project:     "my-project",
+		Region:      "us-west1",
+		App:         "my-app",
+		Release:     "v1",
+		ImageSHA256: imageSHA256,
+		BootImage:   config.BootImage,
+		Created:     created,
+		Creator:     "ops@example.com",
+		Images: map[string]string{
+			"my-app": "gcr.io/my-project/my-app@sha256:" + imageSHA256,
+			"nginx":  "docker.io/nginx@sha256:abcdef",
+		},
+		Config: config,
+	}
+
+	assert.Equal(t, "Get()", want, got)
+}
+
+func TestReleaseService_Diff(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-v1-ig",
+				Type:       "compute.v1.regionInstanceGroupManager",
+				Properties: json.RawMessage(`{"instanceTemplate":"$(ref.my-app-v1-it.selfLink)","targetSize":2}`),
+			},
+		}, nil)
+	dm.EXPECT().
+		Resources(gomock.Any(), "my-project", "belvedere-my-app-v2").
+		Return([]deployments.Resource{
+			{
+				Name:       "my-app-v2-ig",
+				Type:       "compute.v1.regionInstanceGroupManager",
+				Properties: json.RawMessage(`{"instanceTemplate":"$(ref.my-app-v2-it.selfLink)","targetSize":3}`),
+			},
+		}, nil)
+
+	service := &releaseService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	got, err := service.Diff(context.Background(), "my-app", "v1", "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ResourceDiff{
+		{
+			Name:   "my-app-v2-ig",
+			Type:   "compute.v1.regionInstanceGroupManager",
+			Action: DiffChange,
+			Fields: []FieldDiff{
+				{Path: "targetSize", Action: DiffChange, Before: 2.0, After: 3.0},
+			},
+		},
+	}
+
+	assert.Equal(t, "Diff()", want, got)
+}
+
 func TestReleaseService_Create(t *testing.T) {
 	t.Parallel()
 
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/cos-cloud/global/images/family/cos-stable?alt=json&fields=selfLink&prettyPrint=false`,
+		httpmock.RespJSON(compute.Image{
+			SelfLink: "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89",
+		}))
+
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -118,19 +257,44 @@ func TestReleaseService_Create(t *testing.T) {
 		},
 	}
 
-	config := &cfg.Config{}
+	config := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+			Sidecars: map[string]cfg.Container{
+				"nginx": {
+					Image: "nginx:1.19",
+				},
+			},
+		},
+	}
+	pinned := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			BootImage: "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89",
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+			Sidecars: map[string]cfg.Container{
+				"nginx": {
+					Image: "docker.io/nginx@sha256:abcdef",
+				},
+			},
+		},
+	}
 	imageSHA256 := strings.Repeat("1", 64)
 
 	dm := NewDeploymentsManager(ctrl)
 	dm.EXPECT().
 		Insert(gomock.Any(), "my-project", "belvedere-my-app-v1",
 			res, deployments.Labels{
-				Type:    "release",
-				Region:  "us-west1",
-				App:     "my-app",
-				Release: "v1",
-				Hash:    strings.Repeat("1", 32),
-			}, false, 10*time.Millisecond)
+				Type:      "release",
+				Region:    "us-west1",
+				App:       "my-app",
+				Release:   "v1",
+				Hash:      strings.Repeat("1", 32),
+				BootImage: "cos-stable-89",
+			}, false, false, 10*time.Millisecond)
 
 	apps := NewMockAppService(ctrl)
 	apps.EXPECT().
@@ -138,26 +302,274 @@ func TestReleaseService_Create(t *testing.T) {
 		Return(&App{
 			Region: "us-west1",
 		}, nil)
+	apps.EXPECT().
+		Config(gomock.Any(), "my-app").
+		Return(nil, &configs.NotFoundError{Name: "belvedere-my-app"})
+
+	validator := NewMockConfigValidator(ctrl)
+	validator.EXPECT().
+		ValidateRelease(gomock.Any(), "us-west1", config, imageSHA256, true).
+		Return(map[string]string{"nginx": "docker.io/nginx@sha256:abcdef"}, nil)
 
 	resourceBuilder := NewResourceBuilder(ctrl)
 	resourceBuilder.EXPECT().
-		Release("my-project", "us-west1", "my-app", "v1", imageSHA256, config).
+		Release("my-project", "us-west1", "my-app", "v1", imageSHA256, &pinned.ReleaseConfig).
 		Return(res)
 
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Put(gomock.Any(), "my-project", "belvedere-my-app-v1", pinned)
+
+	gce, err := compute.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	service := &releaseService{
 		project:   "my-project",
 		dm:        dm,
+		gce:       gce,
 		resources: resourceBuilder,
 		apps:      apps,
+		validator: validator,
+		configs:   store,
 	}
 
 	if err := service.Create(
-		context.Background(), "my-app", "v1", config, imageSHA256, false, 10*time.Millisecond,
+		context.Background(), "my-app", "v1", config, imageSHA256, false, false, 10*time.Millisecond,
 	); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestReleaseService_Create_Unpinned(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		pinSidecars *bool
+		dryRun      bool
+	}{
+		{
+			name:        "opted out",
+			pinSidecars: new(bool),
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+		},
+	}
+	for _, testCase := range tests {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httpmock.NewServer(t)
+			defer srv.Finish()
+
+			srv.Expect(`/projects/cos-cloud/global/images/family/cos-stable?alt=json&fields=selfLink&prettyPrint=false`,
+				httpmock.RespJSON(compute.Image{
+					SelfLink: "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89",
+				}))
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := &cfg.Config{
+				ReleaseConfig: cfg.ReleaseConfig{
+					Container: cfg.Container{
+						Image: "gcr.io/my-project/my-app",
+					},
+					Sidecars: map[string]cfg.Container{
+						"nginx": {
+							Image: "nginx:1.19",
+						},
+					},
+					PinSidecars: testCase.pinSidecars,
+				},
+			}
+			pinned := *config
+			pinned.BootImage = "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-stable-89"
+			imageSHA256 := strings.Repeat("1", 64)
+
+			dm := NewDeploymentsManager(ctrl)
+			dm.EXPECT().
+				Insert(gomock.Any(), "my-project", "belvedere-my-app-v1", gomock.Any(), gomock.Any(),
+					testCase.dryRun, false, 10*time.Millisecond)
+
+			apps := NewMockAppService(ctrl)
+			apps.EXPECT().
+				Get(gomock.Any(), "my-app").
+				Return(&App{
+					Region: "us-west1",
+				}, nil)
+			apps.EXPECT().
+				Config(gomock.Any(), "my-app").
+				Return(nil, &configs.NotFoundError{Name: "belvedere-my-app"})
+
+			// The sidecar images are only checked, so a registry which denies access doesn't fail the release.
+			rc := NewRegistryClient(ctrl)
+			rc.EXPECT().
+				Exists(gomock.Any(), "gcr.io/my-project/my-app@sha256:"+imageSHA256).
+				Return(true, nil)
+			rc.EXPECT().
+				Exists(gomock.Any(), "nginx:1.19").
+				Return(false, &registry.ManifestError{
+					Image: "nginx:1.19", Status: "401 Unauthorized", StatusCode: http.StatusUnauthorized,
+				})
+
+			validator := &configValidator{
+				project:  "my-project",
+				registry: rc,
+			}
+
+			// The sidecars are left as-is.
+			resourceBuilder := NewResourceBuilder(ctrl)
+			resourceBuilder.EXPECT().
+				Release("my-project", "us-west1", "my-app", "v1", imageSHA256, &pinned.ReleaseConfig)
+
+			store := NewConfigStore(ctrl)
+			if !testCase.dryRun {
+				store.EXPECT().
+					Put(gomock.Any(), "my-project", "belvedere-my-app-v1", &pinned)
+			}
+
+			gce, err := compute.NewService(
+				context.Background(),
+				option.WithEndpoint(srv.URL()),
+				option.WithoutAuthentication(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := &releaseService{
+				project:   "my-project",
+				dm:        dm,
+				gce:       gce,
+				resources: resourceBuilder,
+				apps:      apps,
+				validator: validator,
+				configs:   store,
+			}
+
+			if err := service.Create(
+				context.Background(), "my-app", "v1", config, imageSHA256, testCase.dryRun, false, 10*time.Millisecond,
+			); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReleaseService_Create_NoImage(t *testing.T) {
+	t.Parallel()
+
+	service := &releaseService{project: "my-project"}
+
+	err := service.Create(context.Background(), "my-app", "v1", &cfg.Config{}, strings.Repeat("1", 64),
+		false, false, 10*time.Millisecond)
+
+	assert.Equal(t, "Create()", "invalid config:\n  container.image: required", err.Error())
+}
+
+func TestReleaseService_ignoredChanges(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	apps := NewMockAppService(ctrl)
+	apps.EXPECT().
+		Config(gomock.Any(), "my-app").
+		Return(&cfg.Config{
+			AppConfig: cfg.AppConfig{
+				IAMRoles:        []string{"roles/logging.logWriter"},
+				SessionAffinity: cfg.SessionAffinityIP,
+			},
+			ReleaseConfig: cfg.ReleaseConfig{
+				NumReplicas: 10,
+			},
+		}, nil)
+
+	service := &releaseService{
+		project: "my-project",
+		apps:    apps,
+	}
+
+	fields, err := service.ignoredChanges(context.Background(), "my-app", &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			IAMRoles: []string{"roles/logging.logWriter"},
+			WAFRules: []*compute.SecurityPolicyRule{
+				{Action: "deny(403)", Priority: 100},
+			},
+		},
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "ignoredChanges()", []string{"sessionAffinity", "wafRules"}, fields)
+}
+
+func TestReleaseService_resolveBootImage(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/global/images/family/my-os?alt=json&fields=selfLink&prettyPrint=false`,
+		httpmock.RespJSON(compute.Image{
+			SelfLink: "https://www.googleapis.com/compute/v1/projects/my-project/global/images/my-os-v2",
+		}))
+
+	gce, err := compute.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &releaseService{
+		project: "my-project",
+		gce:     gce,
+	}
+
+	tests := []struct {
+		in, out string
+	}{
+		{
+			in:  "projects/cos-cloud/global/images/cos-stable-89",
+			out: "projects/cos-cloud/global/images/cos-stable-89",
+		},
+		{
+			in:  "global/images/family/my-os",
+			out: "https://www.googleapis.com/compute/v1/projects/my-project/global/images/my-os-v2",
+		},
+	}
+
+	for _, test := range tests {
+		got, err := service.resolveBootImage(context.Background(), test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, "resolveBootImage()", test.out, got)
+	}
+
+	_, err = service.resolveBootImage(context.Background(), "cos-stable")
+	assert.Equal(t, "resolveBootImage() error", &InvalidBootImageError{Image: "cos-stable"}, err)
+}
+
 func TestReleaseService_Enable(t *testing.T) {
 	t.Parallel()
 
@@ -234,9 +646,14 @@ func TestReleaseService_Delete(t *testing.T) {
 	dm.EXPECT().
 		Delete(gomock.Any(), "my-project", "belvedere-my-app-v1", false, false, 10*time.Millisecond)
 
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Delete(gomock.Any(), "my-project", "belvedere-my-app-v1")
+
 	service := &releaseService{
 		project: "my-project",
 		dm:      dm,
+		configs: store,
 	}
 
 	if err := service.Delete(
@@ -245,3 +662,54 @@ func TestReleaseService_Delete(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReleaseService_Delete_Async(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+
+	dm.EXPECT().
+		Delete(gomock.Any(), "my-project", "belvedere-my-app-v1", false, true, 10*time.Millisecond)
+
+	// The recorded configuration is kept, since the deletion may still fail.
+	service := &releaseService{
+		project: "my-project",
+		dm:      dm,
+		configs: NewConfigStore(ctrl),
+	}
+
+	if err := service.Delete(
+		context.Background(), "my-app", "v1", false, true, 10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleaseService_Config(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &cfg.Config{ReleaseConfig: cfg.ReleaseConfig{NumReplicas: 2}}
+
+	store := NewConfigStore(ctrl)
+	store.EXPECT().
+		Get(gomock.Any(), "my-project", "belvedere-my-app-v1").
+		Return(config, nil)
+
+	service := &releaseService{
+		project: "my-project",
+		configs: store,
+	}
+
+	got, err := service.Config(context.Background(), "my-app", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Config()", config, got)
+}
@@ -0,0 +1,90 @@
+package belvedere
+
+import (
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/gcp"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/dns/v1"
+)
+
+// RenderApp returns the Deployment Manager configuration which would be used to create the given
+// app in the given project, with the given DNS name (e.g. example.com.) as its managed zone. Unlike
+// Apps().Create, it doesn't make any API calls, so neither the project nor the managed zone are
+// checked to exist. The ranges of the project IP lists which the configuration refers to are given by
+// name.
+func RenderApp(project, name, dnsName string, config *cfg.Config, ipLists map[string][]string) (string, error) {
+	for _, s := range []string{project, name} {
+		if err := gcp.ValidateRFC1035(s); err != nil {
+			return "", err
+		}
+	}
+
+	managedZone := &dns.ManagedZone{
+		Name:    "belvedere",
+		DnsName: dnsName,
+	}
+
+	if err := checkIPLists(&config.AppConfig, ipLists); err != nil {
+		return "", err
+	}
+
+	return deployments.Config(resources.NewBuilder().App(project, name, managedZone, &config.AppConfig, ipLists))
+}
+
+// RenderRelease returns the Deployment Manager configuration which would be used to create the
+// given release of an app in the given project and region. Unlike Releases().Create, it doesn't make
+// any API calls, so the boot image and sidecar images are used as-is instead of being pinned.
+func RenderRelease(project, region, app, name, imageSHA256 string, config *cfg.Config) (string, error) {
+	res, err := renderRelease(project, region, app, name, imageSHA256, config)
+	if err != nil {
+		return "", err
+	}
+
+	return deployments.Config(res)
+}
+
+// RenderUserData returns the cloud-config user data of the instances of the given release, as
+// rendered by RenderRelease.
+func RenderUserData(project, region, app, name, imageSHA256 string, config *cfg.Config) (string, error) {
+	res, err := renderRelease(project, region, app, name, imageSHA256, config)
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range res {
+		it, ok := r.Properties.(*compute.InstanceTemplate)
+		if !ok || it.Properties == nil || it.Properties.Metadata == nil {
+			continue
+		}
+
+		for _, item := range it.Properties.Metadata.Items {
+			if item.Key == resources.UserDataMetadataKey && item.Value != nil {
+				return *item.Value, nil
+			}
+		}
+	}
+
+	return "", &resources.MalformedReleaseError{Reason: "no user data"}
+}
+
+func renderRelease(
+	project, region, app, name, imageSHA256 string, config *cfg.Config,
+) ([]deployments.Resource, error) {
+	for _, s := range []string{project, app, name} {
+		if err := gcp.ValidateRFC1035(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if !imageHashFormat.MatchString(imageSHA256) {
+		return nil, &InvalidSHA256DigestError{Digest: imageSHA256}
+	}
+
+	if err := config.ReleaseConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	return resources.NewBuilder().Release(project, region, app, name, imageSHA256, &config.ReleaseConfig), nil
+}
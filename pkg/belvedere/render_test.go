@@ -0,0 +1,129 @@
+package belvedere
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/configs"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/resources"
+	"github.com/codahale/gubbins/assert"
+	"google.golang.org/api/dns/v1"
+)
+
+func TestRenderApp(t *testing.T) {
+	t.Parallel()
+
+	config := &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			IAMRoles: []string{"roles/dog.wrangler"},
+			DenyIPs: &cfg.IPs{
+				Lists: []string{"bad-actors"},
+			},
+		},
+	}
+	ipLists := map[string][]string{"bad-actors": {"192.0.2.0/24"}}
+
+	got, err := RenderApp("my-project", "my-app", "horse.club.", config, ipLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := deployments.Config(resources.NewBuilder().App("my-project", "my-app",
+		&dns.ManagedZone{Name: "belvedere", DnsName: "horse.club."}, &config.AppConfig, ipLists))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "RenderApp()", want, got)
+}
+
+func TestRenderApp_UnknownIPList(t *testing.T) {
+	t.Parallel()
+
+	_, err := RenderApp("my-project", "my-app", "horse.club.", &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			AllowIPs: &cfg.IPs{
+				Lists: []string{"office"},
+			},
+		},
+	}, nil)
+
+	var notFound *configs.IPListNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("RenderApp() error = %v, want an IPListNotFoundError", err)
+	}
+}
+
+func TestRenderApp_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	_, err := RenderApp("my-project", "My_App", "horse.club.", &cfg.Config{}, nil)
+	if err == nil {
+		t.Fatal("should have returned an error")
+	}
+}
+
+func TestRenderRelease(t *testing.T) {
+	t.Parallel()
+
+	imageSHA256 := strings.Repeat("1", 64)
+	config := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			NumReplicas: 2,
+			MachineType: "n1-standard-1",
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	got, err := RenderRelease("my-project", "us-central1", "my-app", "v43", imageSHA256, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := deployments.Config(resources.NewBuilder().Release("my-project", "us-central1",
+		"my-app", "v43", imageSHA256, &config.ReleaseConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "RenderRelease()", want, got)
+}
+
+func TestRenderRelease_InvalidDigest(t *testing.T) {
+	t.Parallel()
+
+	_, err := RenderRelease("my-project", "us-central1", "my-app", "v43", "woo", &cfg.Config{})
+
+	assert.Equal(t, "RenderRelease()", `invalid SHA-256 digest: "woo"`, err.Error())
+}
+
+func TestRenderUserData(t *testing.T) {
+	t.Parallel()
+
+	config := &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+		},
+	}
+
+	got, err := RenderUserData("my-project", "us-central1", "my-app", "v43", strings.Repeat("1", 64),
+		config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(got, "#cloud-config\n") {
+		t.Errorf("RenderUserData() = %q, want a cloud-config", got)
+	}
+
+	if !strings.Contains(got, "gcr.io/my-project/my-app@sha256:"+strings.Repeat("1", 64)) {
+		t.Errorf("RenderUserData() = %q, want the app's pinned image", got)
+	}
+}
@@ -0,0 +1,97 @@
+package belvedere
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"go.opencensus.io/trace"
+)
+
+// A FailedResource is a deployment resource whose most recent change failed or never finished. The
+// deployment itself is included, with a type of "deployment", if its most recent operation failed or
+// never finished.
+type FailedResource struct {
+	Name  string
+	Type  string
+	State string
+	Error string
+}
+
+// failures returns the failed resources of the given deployment, if any.
+func failures(ctx context.Context, dm deployments.Manager, project, name string) ([]FailedResource, error) {
+	status, err := dm.Status(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []FailedResource
+
+	if status.State != "DONE" || len(status.Errors) > 0 {
+		failed = append(failed, FailedResource{
+			Name:  name,
+			Type:  "deployment",
+			State: status.State,
+			Error: strings.Join(status.Errors, "; "),
+		})
+	}
+
+	for _, r := range status.Resources {
+		failed = append(failed, FailedResource{
+			Name:  r.Name,
+			Type:  r.Type,
+			State: r.State,
+			Error: strings.Join(r.Errors, "; "),
+		})
+	}
+
+	return failed, nil
+}
+
+// repair walks the given deployment back to a consistent state. Any running operation is stopped and
+// any outstanding preview is cancelled. Then, the deployment is either updated with the last manifest
+// which was successfully deployed or, if abandon is true, deleted while leaving its resources in
+// place. Healthy deployments are left alone unless they're being abandoned.
+func repair(
+	ctx context.Context, dm deployments.Manager, project, name string, abandon, dryRun bool,
+	interval time.Duration,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.repair")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.BoolAttribute("abandon", abandon),
+		trace.BoolAttribute("dry_run", dryRun),
+	)
+
+	status, err := dm.Status(ctx, project, name)
+	if err != nil {
+		return err
+	}
+
+	if status.Healthy() && !abandon {
+		return nil
+	}
+
+	// Stop any running operation, so nothing changes out from under us.
+	if status.State != "DONE" {
+		if err := dm.Stop(ctx, project, name, dryRun, interval); err != nil {
+			return err
+		}
+	}
+
+	// Cancel any outstanding preview, since it blocks further updates.
+	if status.Previewing {
+		if err := dm.CancelPreview(ctx, project, name, dryRun, interval); err != nil {
+			return err
+		}
+	}
+
+	if abandon {
+		return dm.Abandon(ctx, project, name, dryRun, interval)
+	}
+
+	return dm.Restore(ctx, project, name, dryRun, interval)
+}
@@ -0,0 +1,150 @@
+package belvedere
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codahale/belvedere/pkg/belvedere/internal/deployments"
+	"github.com/codahale/gubbins/assert"
+	"github.com/golang/mock/gomock"
+)
+
+func TestAppService_Failures(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Status(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&deployments.Status{
+			Operation: "update",
+			State:     "DONE",
+			Errors:    []string{"resource errors"},
+			Manifest:  "manifest-1",
+			Resources: []deployments.ResourceStatus{
+				{
+					Name:   "my-app-ig",
+					Type:   "compute.v1.instanceGroupManager",
+					State:  "FAILED",
+					Errors: []string{"quota exceeded", "try again"},
+				},
+			},
+		}, nil)
+
+	apps := &appService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	got, err := apps.Failures(context.Background(), "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FailedResource{
+		{
+			Name:  "belvedere-my-app",
+			Type:  "deployment",
+			State: "DONE",
+			Error: "resource errors",
+		},
+		{
+			Name:  "my-app-ig",
+			Type:  "compute.v1.instanceGroupManager",
+			State: "FAILED",
+			Error: "quota exceeded; try again",
+		},
+	}
+
+	assert.Equal(t, "Failures()", want, got)
+}
+
+func TestAppService_Repair(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Status(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&deployments.Status{
+			Operation:  "update",
+			State:      "RUNNING",
+			Previewing: true,
+			Manifest:   "manifest-1",
+		}, nil)
+
+	gomock.InOrder(
+		dm.EXPECT().Stop(gomock.Any(), "my-project", "belvedere-my-app", false, 10*time.Millisecond),
+		dm.EXPECT().CancelPreview(gomock.Any(), "my-project", "belvedere-my-app", false, 10*time.Millisecond),
+		dm.EXPECT().Restore(gomock.Any(), "my-project", "belvedere-my-app", false, 10*time.Millisecond),
+	)
+
+	apps := &appService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	if err := apps.Repair(context.Background(), "my-app", false, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppService_Repair_Healthy(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Status(gomock.Any(), "my-project", "belvedere-my-app").
+		Return(&deployments.Status{
+			Operation: "update",
+			State:     "DONE",
+			Manifest:  "manifest-1",
+		}, nil)
+
+	apps := &appService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	if err := apps.Repair(context.Background(), "my-app", false, false, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleaseService_Repair_Abandon(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dm := NewDeploymentsManager(ctrl)
+	dm.EXPECT().
+		Status(gomock.Any(), "my-project", "belvedere-my-app-v43").
+		Return(&deployments.Status{
+			Operation: "insert",
+			State:     "DONE",
+			Errors:    []string{"resource errors"},
+		}, nil)
+
+	dm.EXPECT().
+		Abandon(gomock.Any(), "my-project", "belvedere-my-app-v43", true, 10*time.Millisecond)
+
+	releases := &releaseService{
+		project: "my-project",
+		dm:      dm,
+	}
+
+	if err := releases.Repair(
+		context.Background(), "my-app", "v43", true, true, 10*time.Millisecond,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -40,7 +40,7 @@ func (p *project) Setup(ctx context.Context, dnsZone string, dryRun bool, interv
 		deployments.Labels{
 			Type: "base",
 		},
-		dryRun, interval,
+		dryRun, false, interval,
 	)
 }
 
@@ -32,7 +32,7 @@ func TestProject_Setup(t *testing.T) {
 		Base("dns.").
 		Return(res)
 	dm.EXPECT().
-		Insert(gomock.Any(), "my-project", "belvedere", res, deployments.Labels{Type: "base"}, false, 10*time.Millisecond)
+		Insert(gomock.Any(), "my-project", "belvedere", res, deployments.Labels{Type: "base"}, false, false, 10*time.Millisecond)
 
 	p := &project{
 		name:      "my-project",
@@ -0,0 +1,381 @@
+package belvedere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+)
+
+// ConfigValidator checks app configurations against the resources which are available in a project.
+type ConfigValidator interface {
+	// Validate checks both the app-level and the release-level settings of the configuration, as
+	// ValidateApp and ValidateRelease do. All problems are returned at once as a
+	// *cfg.ValidationError.
+	Validate(ctx context.Context, region string, config *cfg.Config, imageSHA256 string) error
+
+	// ValidateApp checks that the configuration's IAM roles exist. All problems are returned at once
+	// as a *cfg.ValidationError.
+	ValidateApp(ctx context.Context, region string, config *cfg.Config) error
+
+	// ValidateRelease checks that the configuration's machine type is offered in the given region,
+	// that its network and subnetwork exist, and that its sidecar images exist in their registries.
+	// If an image digest is given, the app's image must exist with that digest. All problems are
+	// returned at once as a *cfg.ValidationError. If pin is true, the sidecar images are resolved to
+	// their digests as they're checked, and returned pinned to them by sidecar name. Otherwise, sidecar
+	// images which the registry doesn't allow access to are assumed to exist, with a warning.
+	ValidateRelease(
+		ctx context.Context, region string, config *cfg.Config, imageSHA256 string, pin bool,
+	) (map[string]string, error)
+}
+
+type configValidator struct {
+	project      string
+	gce          *compute.Service
+	iam          *iam.Service
+	registry     registry.Client
+	machineTypes func(ctx context.Context, region string) ([]MachineType, error)
+}
+
+var _ ConfigValidator = &configValidator{}
+
+func (v *configValidator) Validate(
+	ctx context.Context, region string, config *cfg.Config, imageSHA256 string,
+) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.config.Validate")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("image_sha256", imageSHA256),
+	)
+
+	appProblems, err := v.checkIAMRoles(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	releaseProblems, _, err := v.checkRelease(ctx, region, config, imageSHA256, false)
+	if err != nil {
+		return err
+	}
+
+	return validationError(append(appProblems, releaseProblems...))
+}
+
+func (v *configValidator) ValidateApp(ctx context.Context, region string, config *cfg.Config) error {
+	ctx, span := trace.StartSpan(ctx, "belvedere.config.ValidateApp")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	problems, err := v.checkIAMRoles(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	return validationError(problems)
+}
+
+func (v *configValidator) ValidateRelease(
+	ctx context.Context, region string, config *cfg.Config, imageSHA256 string, pin bool,
+) (map[string]string, error) {
+	ctx, span := trace.StartSpan(ctx, "belvedere.config.ValidateRelease")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("image_sha256", imageSHA256),
+		trace.BoolAttribute("pin", pin),
+	)
+
+	problems, pinned, err := v.checkRelease(ctx, region, config, imageSHA256, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validationError(problems); err != nil {
+		return nil, err
+	}
+
+	return pinned, nil
+}
+
+// checkRelease checks the release-level settings, returning any problems and, if pin is true, the
+// sidecars' pinned images.
+func (v *configValidator) checkRelease(
+	ctx context.Context, region string, config *cfg.Config, imageSHA256 string, pin bool,
+) ([]*cfg.FieldError, map[string]string, error) {
+	var problems []*cfg.FieldError
+
+	for _, check := range []func(context.Context, string, *cfg.Config) ([]*cfg.FieldError, error){
+		v.checkMachineType,
+		v.checkNetwork,
+	} {
+		p, err := check(ctx, region, config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		problems = append(problems, p...)
+	}
+
+	p, pinned, err := v.checkImages(ctx, config, imageSHA256, pin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return append(problems, p...), pinned, nil
+}
+
+// validationError returns the given problems as a *cfg.ValidationError, or nil if there are none.
+func validationError(problems []*cfg.FieldError) error {
+	if len(problems) > 0 {
+		return &cfg.ValidationError{Errors: problems}
+	}
+
+	return nil
+}
+
+// checkMachineType checks that the machine type is offered in the region.
+func (v *configValidator) checkMachineType(
+	ctx context.Context, region string, config *cfg.Config,
+) ([]*cfg.FieldError, error) {
+	if config.MachineType == "" {
+		return nil, nil
+	}
+
+	machineTypes, err := v.machineTypes(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mt := range machineTypes {
+		if mt.Name == config.MachineType {
+			return nil, nil
+		}
+	}
+
+	return []*cfg.FieldError{
+		problem("machineType", config.MachineType, fmt.Sprintf("not offered in %s", region)),
+	}, nil
+}
+
+// checkNetwork checks that the network and subnetwork exist.
+func (v *configValidator) checkNetwork(
+	ctx context.Context, region string, config *cfg.Config,
+) ([]*cfg.FieldError, error) {
+	var problems []*cfg.FieldError
+
+	if config.Network != "" {
+		project, _, name := parseLink(config.Network, v.project, "")
+
+		_, err := v.gce.Networks.Get(project, name).Context(ctx).Fields("name").Do()
+		switch {
+		case isNotFound(err):
+			problems = append(problems, problem("network", config.Network, "network not found"))
+		case err != nil:
+			return nil, fmt.Errorf("error getting network %s: %w", config.Network, err)
+		}
+	}
+
+	if config.Subnetwork != "" {
+		project, subnetRegion, name := parseLink(config.Subnetwork, v.project, region)
+
+		_, err := v.gce.Subnetworks.Get(project, subnetRegion, name).Context(ctx).Fields("name").Do()
+		switch {
+		case isNotFound(err):
+			problems = append(problems, problem("subnetwork", config.Subnetwork,
+				fmt.Sprintf("subnetwork not found in %s", subnetRegion)))
+		case err != nil:
+			return nil, fmt.Errorf("error getting subnetwork %s: %w", config.Subnetwork, err)
+		}
+	}
+
+	return problems, nil
+}
+
+// checkIAMRoles checks that all of the IAM roles exist.
+func (v *configValidator) checkIAMRoles(ctx context.Context, config *cfg.Config) ([]*cfg.FieldError, error) {
+	var problems []*cfg.FieldError
+
+	for i, role := range config.IAMRoles {
+		var err error
+
+		switch {
+		case strings.HasPrefix(role, "projects/"):
+			_, err = v.iam.Projects.Roles.Get(role).Context(ctx).Fields("name").Do()
+		case strings.HasPrefix(role, "organizations/"):
+			_, err = v.iam.Organizations.Roles.Get(role).Context(ctx).Fields("name").Do()
+		default:
+			_, err = v.iam.Roles.Get(role).Context(ctx).Fields("name").Do()
+		}
+
+		switch {
+		case isNotFound(err):
+			problems = append(problems, problem(fmt.Sprintf("iamRoles[%d]", i), role, "role not found"))
+		case err != nil:
+			return nil, fmt.Errorf("error getting role %s: %w", role, err)
+		}
+	}
+
+	return problems, nil
+}
+
+// checkImages checks that all of the sidecar images exist, and that the app's image exists with the
+// given digest, if any. If pin is true, sidecar images are resolved to their digests as they're
+// checked, and returned pinned to them by sidecar name.
+func (v *configValidator) checkImages(
+	ctx context.Context, config *cfg.Config, imageSHA256 string, pin bool,
+) ([]*cfg.FieldError, map[string]string, error) {
+	var problems []*cfg.FieldError
+
+	if imageSHA256 != "" {
+		image := fmt.Sprintf("%s@sha256:%s", config.Container.Image, imageSHA256)
+
+		exists, err := v.registry.Exists(ctx, image)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !exists {
+			problems = append(problems, problem("container.image", image, "image not found"))
+		}
+	}
+
+	names := make([]string, 0, len(config.Sidecars))
+	for name := range config.Sidecars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if !pin {
+		p, err := v.checkSidecarsExist(ctx, config, names)
+		return append(problems, p...), nil, err
+	}
+
+	pinned := make(map[string]string, len(names))
+
+	for _, name := range names {
+		image := config.Sidecars[name].Image
+
+		digest, err := v.digest(ctx, image)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if digest == "" {
+			problems = append(problems, problem(fmt.Sprintf("sidecars.%s.image", name), image, "image not found"))
+			continue
+		}
+
+		pinned[name] = pinImage(image, digest)
+	}
+
+	return problems, pinned, nil
+}
+
+// checkSidecarsExist checks that the images of the sidecars with the given names exist. Unlike pinning,
+// which needs their digests, it only warns about images which the registry doesn't allow access to
+// (e.g. private images outside Google's registries), since they may well exist.
+func (v *configValidator) checkSidecarsExist(
+	ctx context.Context, config *cfg.Config, names []string,
+) ([]*cfg.FieldError, error) {
+	var (
+		problems  []*cfg.FieldError
+		unchecked []string
+	)
+
+	for _, name := range names {
+		image := config.Sidecars[name].Image
+
+		exists, err := v.registry.Exists(ctx, image)
+
+		var manifestErr *registry.ManifestError
+
+		switch {
+		case errors.As(err, &manifestErr) &&
+			(manifestErr.StatusCode == http.StatusUnauthorized || manifestErr.StatusCode == http.StatusForbidden):
+			unchecked = append(unchecked, name)
+		case err != nil:
+			return nil, err
+		case !exists:
+			problems = append(problems, problem(fmt.Sprintf("sidecars.%s.image", name), image, "image not found"))
+		}
+	}
+
+	if len(unchecked) > 0 {
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("sidecars", strings.Join(unchecked, ",")),
+			},
+			"Couldn't check that the sidecars' images exist, since their registries denied access",
+		)
+	}
+
+	return problems, nil
+}
+
+// digest returns the digest of the given image, or an empty string if the image doesn't exist.
+// Images which are already pinned to a digest are looked up, too.
+func (v *configValidator) digest(ctx context.Context, image string) (string, error) {
+	if _, _, reference := registry.Parse(image); strings.HasPrefix(reference, "sha256:") {
+		exists, err := v.registry.Exists(ctx, image)
+		if err != nil || !exists {
+			return "", err
+		}
+
+		return strings.TrimPrefix(reference, "sha256:"), nil
+	}
+
+	digest, err := v.registry.Digest(ctx, image)
+
+	var manifestErr *registry.ManifestError
+	if errors.As(err, &manifestErr) && manifestErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	return digest, err
+}
+
+// problem returns a problem with the given field's value.
+func problem(path string, value interface{}, reason string) *cfg.FieldError {
+	return &cfg.FieldError{
+		Path: path,
+		Err:  &cfg.InvalidValueError{Value: value, Reason: reason},
+	}
+}
+
+// isNotFound returns true if the given error is a 404 Not Found response from a GCP API.
+func isNotFound(err error) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == http.StatusNotFound
+}
+
+// parseLink returns the project, region, and name of the given partial or full URL of a GCE
+// resource (e.g. projects/my-project/regions/us-west1/subnetworks/my-subnet), using the given project
+// and region if they aren't part of the URL.
+func parseLink(link, project, region string) (string, string, string) {
+	parts := strings.Split(link, "/")
+
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "projects":
+			project = parts[i+1]
+		case "regions":
+			region = parts[i+1]
+		}
+	}
+
+	return project, region, parts[len(parts)-1]
+}
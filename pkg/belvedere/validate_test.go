@@ -0,0 +1,218 @@
+package belvedere
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/codahale/belvedere/pkg/belvedere/cfg"
+	"github.com/codahale/belvedere/pkg/belvedere/internal/registry"
+	"github.com/codahale/gubbins/assert"
+	"github.com/codahale/gubbins/httpmock"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+)
+
+func TestConfigValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	srv := httpmock.NewServer(t)
+	defer srv.Finish()
+
+	srv.Expect(`/projects/my-project/global/networks/my-network?alt=json&fields=name&prettyPrint=false`,
+		httpmock.RespJSON(compute.Network{Name: "my-network"}))
+	srv.Expect(`/projects/my-project/regions/us-west1/subnetworks/my-subnet?alt=json&fields=name&prettyPrint=false`,
+		httpmock.Status(http.StatusNotFound))
+	srv.Expect(`/v1/roles/logging.logWriter?alt=json&fields=name&prettyPrint=false`,
+		httpmock.RespJSON(iam.Role{Name: "roles/logging.logWriter"}))
+	srv.Expect(`/v1/projects/my-project/roles/dogWrangler?alt=json&fields=name&prettyPrint=false`,
+		httpmock.Status(http.StatusNotFound))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rc := NewRegistryClient(ctrl)
+	rc.EXPECT().
+		Exists(gomock.Any(), "gcr.io/my-project/my-app@sha256:abcdef").
+		Return(true, nil)
+	rc.EXPECT().
+		Exists(gomock.Any(), "nginx:1.19").
+		Return(false, nil)
+
+	gce, err := compute.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	is, err := iam.NewService(
+		context.Background(),
+		option.WithEndpoint(srv.URL()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &configValidator{
+		project:  "my-project",
+		gce:      gce,
+		iam:      is,
+		registry: rc,
+		machineTypes: func(ctx context.Context, region string) ([]MachineType, error) {
+			assert.Equal(t, "machineTypes() region", "us-west1", region)
+
+			return []MachineType{{Name: "n1-standard-1"}}, nil
+		},
+	}
+
+	err = v.Validate(context.Background(), "us-west1", &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			IAMRoles: []string{"roles/logging.logWriter", "projects/my-project/roles/dogWrangler"},
+		},
+		ReleaseConfig: cfg.ReleaseConfig{
+			MachineType: "n2-standard-1",
+			Network:     "projects/my-project/global/networks/my-network",
+			Subnetwork:  "regions/us-west1/subnetworks/my-subnet",
+			Container: cfg.Container{
+				Image: "gcr.io/my-project/my-app",
+			},
+			Sidecars: map[string]cfg.Container{
+				"nginx": {
+					Image: "nginx:1.19",
+				},
+			},
+		},
+	}, "abcdef")
+
+	want := `invalid config:
+  iamRoles[1]: role not found: "projects/my-project/roles/dogWrangler"
+  machineType: not offered in us-west1: "n2-standard-1"
+  subnetwork: subnetwork not found in us-west1: "regions/us-west1/subnetworks/my-subnet"
+  sidecars.nginx.image: image not found: "nginx:1.19"`
+
+	if err == nil {
+		t.Fatal("should have returned an error")
+	}
+
+	assert.Equal(t, "Validate()", want, err.Error())
+}
+
+func TestConfigValidator_ValidateRelease(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The sidecar images are each looked up once, and the app-level IAM roles aren't checked.
+	rc := NewRegistryClient(ctrl)
+	rc.EXPECT().
+		Digest(gomock.Any(), "nginx:1.19").
+		Return("abcdef", nil)
+	rc.EXPECT().
+		Exists(gomock.Any(), "gcr.io/my-project/proxy@sha256:123456").
+		Return(true, nil)
+
+	v := &configValidator{
+		project:  "my-project",
+		registry: rc,
+	}
+
+	got, err := v.ValidateRelease(context.Background(), "us-west1", &cfg.Config{
+		AppConfig: cfg.AppConfig{
+			IAMRoles: []string{"projects/my-project/roles/dogWrangler"},
+		},
+		ReleaseConfig: cfg.ReleaseConfig{
+			Sidecars: map[string]cfg.Container{
+				"nginx": {
+					Image: "nginx:1.19",
+				},
+				"proxy": {
+					Image: "gcr.io/my-project/proxy@sha256:123456",
+				},
+			},
+		},
+	}, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"nginx": "docker.io/nginx@sha256:abcdef",
+		"proxy": "gcr.io/my-project/proxy@sha256:123456",
+	}
+
+	assert.Equal(t, "ValidateRelease()", want, got)
+}
+
+func TestConfigValidator_ValidateRelease_Unpinned(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Without pinning, images are only checked for existence, and private images are assumed to exist.
+	rc := NewRegistryClient(ctrl)
+	rc.EXPECT().
+		Exists(gomock.Any(), "nginx:1.19").
+		Return(false, nil)
+	rc.EXPECT().
+		Exists(gomock.Any(), "registry.example.com/proxy:v1").
+		Return(false, &registry.ManifestError{
+			Image: "registry.example.com/proxy:v1", Status: "403 Forbidden", StatusCode: http.StatusForbidden,
+		})
+
+	v := &configValidator{
+		project:  "my-project",
+		registry: rc,
+	}
+
+	got, err := v.ValidateRelease(context.Background(), "us-west1", &cfg.Config{
+		ReleaseConfig: cfg.ReleaseConfig{
+			Sidecars: map[string]cfg.Container{
+				"nginx": {
+					Image: "nginx:1.19",
+				},
+				"proxy": {
+					Image: "registry.example.com/proxy:v1",
+				},
+			},
+		},
+	}, "", false)
+	if err == nil {
+		t.Fatalf("ValidateRelease() = %v, want an error", got)
+	}
+
+	want := `invalid config:
+  sidecars.nginx.image: image not found: "nginx:1.19"`
+
+	assert.Equal(t, "ValidateRelease()", want, err.Error())
+}
+
+func TestParseLink(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		link, project, region, name string
+	}{
+		{"my-subnet", "my-project", "us-west1", "my-subnet"},
+		{"regions/us-east1/subnetworks/my-subnet", "my-project", "us-east1", "my-subnet"},
+		{
+			"https://www.googleapis.com/compute/v1/projects/other/regions/us-east1/subnetworks/my-subnet",
+			"other", "us-east1", "my-subnet",
+		},
+	}
+
+	for _, test := range tests {
+		project, region, name := parseLink(test.link, "my-project", "us-west1")
+
+		assert.Equal(t, "parseLink() project", test.project, project)
+		assert.Equal(t, "parseLink() region", test.region, region)
+		assert.Equal(t, "parseLink() name", test.name, name)
+	}
+}